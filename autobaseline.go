@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// createTableRex matches the creation of a table in a migration, capturing its possibly quoted name.
+var createTableRex = regexp.MustCompile(
+	"(?i)\\bCREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?([\\w.\"`\\[\\]]+)")
+
+// identifierQuotes removes the quotes of identifiers of the supported dialects.
+var identifierQuotes = strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "")
+
+// quotedIdentifierRex matches identifiers enclosed in the quotes of the supported dialects.
+var quotedIdentifierRex = regexp.MustCompile("^(?:\"[^\"]*\"|`[^`]*`|\\[[^\\]]*\\])$")
+
+// WithAutoBaselineExisting registers the leading migrations as applied without executing them, if the migration
+// table did not exist before and the tables they create already exist. This eases adopting DMorph for databases
+// populated otherwise. Only migrations consisting of SQL are considered, their tables are determined from their
+// `CREATE TABLE` statements. The first migration not creating tables, or whose tables are missing, and all
+// following ones are applied as usual. Migrations whose tables only partly exist are rejected with
+// ErrAutoBaselineInconsistent. As this is a heuristic, every decision is logged. The dialect has to implement
+// the TableProber interface, otherwise ErrAutoBaselineUnsupported is returned.
+func WithAutoBaselineExisting(auto bool) MorphOption {
+	return func(m *Morpher) error {
+		m.AutoBase = auto
+
+		return nil
+	}
+}
+
+// autoBaselineProber returns the prober to determine whether the migration table is created by this run and to
+// probe the tables of the migrations, if auto-baselining is configured and the migration table does not exist.
+func (m *Morpher) autoBaselineProber(ctx context.Context, db *sql.DB) (TableProber, error) {
+	if !m.AutoBase {
+		return nil, nil
+	}
+
+	prober, isProber := m.Dialect.(TableProber)
+
+	if !isProber {
+		return nil, ErrAutoBaselineUnsupported
+	}
+
+	exists, err := prober.MigrationTableExists(ctx, db, m.TableName)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAutoBaselineUnsupported, err)
+	}
+
+	if exists {
+		m.logger().InfoContext(ctx, "auto baseline skipped, migration table exists", slog.String("table", m.TableName))
+
+		return nil, nil
+	}
+
+	return prober, nil
+}
+
+// autoBaseline registers the leading migrations whose tables exist as applied, returning their keys.
+func (m *Morpher) autoBaseline(ctx context.Context, db *sql.DB, prober TableProber) ([]string, error) {
+	var result []string
+
+	log := m.logger()
+
+	sorted := slices.Clone(m.Migrations)
+	slices.SortFunc(sorted, m.KeyProp.MigrationOrder)
+
+	for _, migration := range sorted {
+		tables, err := createdTables(migration, m.Dialect)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not read migration %s: %w", migration.Key(), err)
+		}
+
+		if len(tables) == 0 {
+			log.InfoContext(ctx, "auto baseline stopped, migration creates no tables", slog.String("file", migration.Key()))
+
+			break
+		}
+
+		var missing []string
+
+		for _, table := range tables {
+			exists, existsErr := prober.MigrationTableExists(ctx, db, table)
+
+			if existsErr != nil {
+				return nil, fmt.Errorf("could not probe table %s: %w", table, existsErr)
+			}
+
+			if !exists {
+				missing = append(missing, table)
+			}
+		}
+
+		if len(missing) == len(tables) {
+			log.InfoContext(ctx, "auto baseline stopped, tables missing", slog.String("file", migration.Key()))
+
+			break
+		}
+
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("%w: %s misses %s", ErrAutoBaselineInconsistent,
+				migration.Key(), strings.Join(missing, ", "))
+		}
+
+		err = inTransaction(ctx, db, m.TxOptions, func(tx *sql.Tx) error {
+			return m.registerMigration(ctx, tx, migration.Key())
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("could not register migration %s: %w", migration.Key(), err)
+		}
+
+		log.WarnContext(ctx, "migration baselined, tables exist",
+			slog.String("file", migration.Key()),
+			slog.String("tables", strings.Join(tables, ", ")),
+		)
+
+		result = append(result, migration.Key())
+	}
+
+	return result, nil
+}
+
+// createdTables returns the names of the tables created by the given migration, without quotes. Unquoted names
+// are folded like the database does, if the dialect implements IdentifierFolder, while quoted ones are kept as
+// written. Migrations not consisting of SQL create no tables as far as can be determined.
+func createdTables(migration Migration, dialect Dialect) ([]string, error) {
+	source, isSource := migration.(sqlSource)
+
+	if !isSource {
+		return nil, nil
+	}
+
+	r, err := source.open()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = r.Close() }()
+
+	content, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, wrapIfError("could not read migration", err)
+	}
+
+	folder, isFolder := dialect.(IdentifierFolder)
+
+	var result []string
+
+	for _, match := range createTableRex.FindAllStringSubmatch(string(content), -1) {
+		parts := strings.Split(match[1], ".")
+
+		for i, part := range parts {
+			if quotedIdentifierRex.MatchString(part) {
+				parts[i] = identifierQuotes.Replace(part)
+			} else if isFolder {
+				parts[i] = folder.FoldIdentifier(part)
+			}
+		}
+
+		result = append(result, strings.Join(parts, "."))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// testAutoBaselineMigrations are the migrations used for the auto-baseline tests. The data migration does not
+// create tables and therefore stops the auto-baselining.
+var testAutoBaselineMigrations = fstest.MapFS{
+	"01_base.sql":  {Data: []byte("CREATE TABLE t0 (id INTEGER)\n;\nCREATE TABLE IF NOT EXISTS \"t1\" (id INTEGER)")},
+	"02_addon.sql": {Data: []byte("CREATE TABLE t2 (id INTEGER)")},
+	"03_data.sql":  {Data: []byte("INSERT INTO t0 (id) VALUES (1)")},
+}
+
+// TestWithAutoBaselineExisting verifies that the migrations whose tables already exist are registered without
+// being executed, if the migration table is created by the run.
+func TestWithAutoBaselineExisting(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		existing []string
+		want     []string
+		wantErr  error
+	}{
+		{ // fresh database
+			want: []string{"01_base.sql", "02_addon.sql", "03_data.sql"},
+		},
+		{ // pre-populated database
+			existing: []string{"CREATE TABLE t0 (id INTEGER)", "CREATE TABLE t1 (id INTEGER)"},
+			want:     []string{"01_base.sql", "02_addon.sql", "03_data.sql"},
+		},
+		{ // pre-populated database, up to the data migration
+			existing: []string{
+				"CREATE TABLE t0 (id INTEGER)", "CREATE TABLE t1 (id INTEGER)", "CREATE TABLE t2 (id INTEGER)",
+			},
+			want: []string{"01_base.sql", "02_addon.sql", "03_data.sql"},
+		},
+		{ // partly populated database
+			existing: []string{"CREATE TABLE t1 (id INTEGER)"},
+			wantErr:  dmorph.ErrAutoBaselineInconsistent,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestWithAutoBaselineExisting-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			for _, statement := range test.existing {
+				_, err := db.ExecContext(t.Context(), statement)
+				require.NoError(t, err, "could not prepare table")
+			}
+
+			runErr := dmorph.Run(t.Context(),
+				db,
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithAutoBaselineExisting(true),
+				dmorph.WithMigrationsFromFS(testAutoBaselineMigrations))
+
+			if test.wantErr != nil {
+				require.ErrorIs(t, runErr, test.wantErr, "expected auto baseline error")
+
+				return
+			}
+
+			require.NoError(t, runErr, "migrations could not be run")
+			assert.Equal(t, test.want, appliedSQLite(t, db), "wrong migrations registered")
+
+			var rows int
+
+			require.NoError(t, db.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM t0").Scan(&rows),
+				"could not count rows")
+			assert.Equal(t, 1, rows, "data migration not applied exactly once")
+		})
+	}
+}
+
+// TestWithAutoBaselineExistingTable verifies that existing migration tables are not auto-baselined, even if they
+// are empty.
+func TestWithAutoBaselineExistingTable(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	require.NoError(t, dmorph.DialectSQLite().EnsureMigrationTableExists(t.Context(), db,
+		dmorph.MigrationTableName), "could not create migration table")
+
+	_, err := db.ExecContext(t.Context(), "CREATE TABLE t0 (id INTEGER)")
+	require.NoError(t, err, "could not prepare table")
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithAutoBaselineExisting(true),
+		dmorph.WithMigrationsFromFS(testAutoBaselineMigrations))
+
+	assert.ErrorContains(t, runErr, "already exists", "expected the migration to be applied")
+}
+
+// TestWithAutoBaselineExistingUnsupported verifies that dialects that cannot probe tables are rejected.
+func TestWithAutoBaselineExistingUnsupported(t *testing.T) {
+	t.Parallel()
+
+	dialect := dmorph.DialectSQLite()
+	dialect.IntegrityTemplate = ""
+
+	runErr := dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dialect),
+		dmorph.WithAutoBaselineExisting(true),
+		dmorph.WithMigrationsFromFS(testAutoBaselineMigrations))
+
+	assert.ErrorIs(t, runErr, dmorph.ErrAutoBaselineUnsupported, "expected unsupported error")
+}
+
+// TestWithAutoBaselineExistingFolded verifies that unquoted table names are folded like the database does, while
+// quoted ones are probed as written. The probe of the dialect compares the names case-sensitively, like databases
+// folding unquoted names do.
+func TestWithAutoBaselineExistingFolded(t *testing.T) {
+	t.Parallel()
+
+	dialect := dmorph.DialectSQLite()
+	dialect.CaseFolding = dmorph.CaseFoldingUpper
+	dialect.IntegrityTemplate = `
+		SELECT name
+		FROM   pragma_table_info('%s')
+		WHERE  EXISTS (SELECT 1 FROM sqlite_master WHERE name = '%[1]s')`
+
+	db := openTempSQLite(t)
+
+	for _, statement := range []string{`CREATE TABLE "T0" (id INTEGER)`, `CREATE TABLE "t1" (id INTEGER)`} {
+		_, err := db.ExecContext(t.Context(), statement)
+		require.NoError(t, err, "could not prepare table")
+	}
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dialect),
+		dmorph.WithAutoBaselineExisting(true),
+		dmorph.WithMigrationsFromFS(testAutoBaselineMigrations)),
+		"migrations could not be run")
+
+	assert.Equal(t, []string{"01_base.sql", "02_addon.sql", "03_data.sql"}, appliedSQLite(t, db),
+		"wrong migrations registered")
+}
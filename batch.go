@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// Execer executes statements, as done by *sql.DB, *sql.Conn and *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// BatchUpdate backfills data in bounded batches, e.g. for a new column of a huge table, where a single UPDATE
+// would lock the whole table and exceed the transaction size. The query is executed with the batch size as its
+// only parameter, that it has to use to limit the affected rows, until it affects fewer rows than the batch size:
+//
+//	UPDATE t SET c = 0 WHERE id IN (SELECT id FROM t WHERE c IS NULL LIMIT ?)
+//
+// Given the transaction of a Go migration, all batches are part of it. Given a *sql.DB instead, e.g. held by the
+// migration, each batch is committed separately. The backfill is then not rolled back if the migration fails, so
+// the query has to skip the rows already done, resuming the backfill on the next run. The progress is logged to
+// the given logger after each batch, a nil logger discards it. The total number of affected rows is returned. If
+// the batch size is less than 1, ErrBatchSizeInvalid is returned.
+func BatchUpdate(ctx context.Context, db Execer, query string, batchSize int, log *slog.Logger) (int64, error) {
+	if batchSize < 1 {
+		return 0, fmt.Errorf("%w: %d", ErrBatchSizeInvalid, batchSize)
+	}
+
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+
+	var total int64
+
+	for batch := 0; ; batch++ {
+		result, execErr := db.ExecContext(ctx, query, batchSize)
+
+		if execErr != nil {
+			return total, fmt.Errorf("could not apply batch %d: %w", batch, execErr)
+		}
+
+		affected, affectedErr := result.RowsAffected()
+
+		if affectedErr != nil {
+			return total, fmt.Errorf("could not get affected rows of batch %d: %w", batch, affectedErr)
+		}
+
+		total += affected
+
+		log.InfoContext(ctx, "batch applied",
+			slog.Int("batch", batch),
+			slog.Int64("rows", affected),
+			slog.Int64("total", total),
+		)
+
+		if affected < int64(batchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// backfillMigration backfills the batch column of the table t0 in batches of the given size.
+type backfillMigration struct {
+	db        *sql.DB // database to commit each batch separately, the migration transaction if nil
+	batchSize int
+	log       *slog.Logger // logger of the progress
+}
+
+func (m backfillMigration) Key() string {
+	return "02_backfill"
+}
+
+func (m backfillMigration) Migrate(ctx context.Context, tx *sql.Tx) error {
+	var db dmorph.Execer = tx
+
+	if m.db != nil {
+		db = m.db
+	}
+
+	// the batch number is the number of batches done so far plus one, marking the rows of each batch
+	_, err := dmorph.BatchUpdate(ctx, db, `
+		UPDATE t0
+		SET    batch = (SELECT COUNT(DISTINCT batch) FROM t0) + 1
+		WHERE  id IN (SELECT id FROM t0 WHERE batch IS NULL ORDER BY id LIMIT ?)`,
+		m.batchSize,
+		m.log)
+
+	return err //nolint:wrapcheck // test migration
+}
+
+// TestBatchUpdate verifies that the rows are backfilled in batches of the given size, within the migration
+// transaction or committed separately.
+func TestBatchUpdate(t *testing.T) {
+	t.Parallel()
+
+	for k, separate := range []bool{false, true} {
+		t.Run(fmt.Sprintf("TestBatchUpdate-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLiteFile(t, "backfill.db")
+
+			_, createErr := db.ExecContext(t.Context(), `
+				CREATE TABLE t0 (id INTEGER PRIMARY KEY, batch INTEGER);
+				WITH RECURSIVE n(id) AS (SELECT 1 UNION ALL SELECT id + 1 FROM n WHERE id < 25)
+				INSERT INTO t0 (id) SELECT id FROM n`)
+
+			require.NoError(t, createErr, "table could not be created")
+
+			buf := bytes.Buffer{}
+			migration := backfillMigration{batchSize: 10, log: slog.New(slog.NewTextHandler(&buf, nil))}
+
+			if separate {
+				// a further connection, as the one of the database is used by the migration transaction
+				var dbName string
+
+				require.NoError(t, db.QueryRowContext(t.Context(), "SELECT file FROM pragma_database_list").
+					Scan(&dbName), "database file could not be read")
+
+				separateDB, openErr := sql.Open("sqlite3", dbName)
+
+				require.NoError(t, openErr, "database could not be opened")
+
+				t.Cleanup(func() { _ = separateDB.Close() })
+
+				migration.db = separateDB
+			}
+
+			runErr := dmorph.Run(t.Context(),
+				db,
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrations(oneMigration{key: "01_base"}, migration))
+
+			require.NoError(t, runErr, "migrations could not be run")
+
+			rows, rowsErr := db.QueryContext(t.Context(),
+				"SELECT batch, COUNT(*) FROM t0 GROUP BY batch ORDER BY batch")
+
+			require.NoError(t, rowsErr, "batches could not be read")
+
+			defer func() { _ = rows.Close() }()
+
+			batches := map[int]int{}
+
+			for rows.Next() {
+				var batch, count int
+
+				require.NoError(t, rows.Scan(&batch, &count), "batch could not be scanned")
+
+				batches[batch] = count
+			}
+
+			require.NoError(t, rows.Err(), "batches could not be read")
+			assert.Equal(t, map[int]int{1: 10, 2: 10, 3: 5}, batches, "rows not backfilled in batches")
+			assert.Contains(t, buf.String(), `msg="batch applied" batch=2 rows=5 total=25`, "progress not logged")
+		})
+	}
+
+	_, invalidErr := dmorph.BatchUpdate(t.Context(), openTempSQLite(t), "UPDATE t0 SET batch = 1", 0, nil)
+
+	assert.ErrorIs(t, invalidErr, dmorph.ErrBatchSizeInvalid, "expected invalid batch size")
+}
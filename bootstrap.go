@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// Bootstrap only creates the migration table, including its comment and the AfterEnsureTable callback, without
+// applying any migrations. It is intended to be run once by a privileged job, so that the application itself
+// never issues DDL for the migration table, see RunBootstrapped. The setup statements are executed before, as for
+// Run. Bootstrapping an existing migration table does nothing.
+func (m *Morpher) Bootstrap(ctx context.Context, db *sql.DB) error {
+	if db == nil {
+		return ErrNilDB
+	}
+
+	if validErr := m.IsValid(); validErr != nil {
+		return validErr
+	}
+
+	if err := m.runSetup(ctx, db); err != nil {
+		return err
+	}
+
+	if err := m.createMigrationTable(ctx, db); err != nil {
+		return err
+	}
+
+	m.logger().InfoContext(ctx, "migration table bootstrapped", slog.String("table", m.TableName))
+
+	return nil
+}
+
+// RunBootstrapped runs the configured Morpher like Run, but never creates the migration table, as if configured
+// using WithAssumeTableExists. The table has to be created using Bootstrap before. If the dialect can determine
+// that the migration table does not exist, ErrMigrationTableMissing is returned.
+func (m *Morpher) RunBootstrapped(ctx context.Context, db *sql.DB) error {
+	if db == nil {
+		return ErrNilDB
+	}
+
+	missing, err := m.migrationTableMissing(ctx, db)
+
+	if err != nil {
+		return err
+	}
+
+	if missing {
+		return fmt.Errorf("%w: %s", ErrMigrationTableMissing, m.TableName)
+	}
+
+	bootstrapped := *m
+	bootstrapped.AssumeTable = true
+
+	return bootstrapped.Run(ctx, db)
+}
+
+// runSetup executes the setup statements, see WithSetupStatements.
+func (m *Morpher) runSetup(ctx context.Context, db *sql.DB) error {
+	for _, statement := range m.Setup {
+		m.logger().DebugContext(ctx, "setup statement", slog.String("statement", statement))
+
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("could not execute setup statement %q: %w", statement, err)
+		}
+	}
+
+	return nil
+}
+
+// beginTx begins the transaction of a migration. If setup statements are configured, the transaction is begun on
+// a connection of its own, after executing the setup statements on it, so that settings specific to a connection,
+// like `PRAGMA foreign_keys=ON` of SQLite, take effect for the migration, whatever connection of the pool it
+// gets. The returned function releases the connection, after the transaction ended.
+func (m *Morpher) beginTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*sql.Tx, func(), error) {
+	if len(m.Setup) == 0 {
+		tx, err := db.BeginTx(ctx, opts)
+
+		return tx, func() {}, wrapIfError("begin tx", err)
+	}
+
+	conn, connErr := db.Conn(ctx)
+
+	if connErr != nil {
+		return nil, nil, fmt.Errorf("could not get connection: %w", connErr)
+	}
+
+	for _, statement := range m.Setup {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			_ = conn.Close()
+
+			return nil, nil, fmt.Errorf("could not execute setup statement %q: %w", statement, err)
+		}
+	}
+
+	tx, err := conn.BeginTx(ctx, opts)
+
+	if err != nil {
+		_ = conn.Close()
+
+		return nil, nil, fmt.Errorf("begin tx: %w", err)
+	}
+
+	return tx, func() { _ = conn.Close() }, nil
+}
+
+// inMigrationTx calls the given function in a transaction of a migration, see beginTx, that is committed if the
+// function succeeds.
+func (m *Morpher) inMigrationTx(
+	ctx context.Context,
+	db *sql.DB,
+	opts *sql.TxOptions,
+	fn func(tx *sql.Tx) error) error {
+
+	tx, release, err := m.beginTx(ctx, db, opts)
+
+	if err != nil {
+		return err
+	}
+
+	defer release()
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return wrapIfError("commit tx", tx.Commit())
+}
@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// TestBootstrap verifies the two-phase deployment, creating the migration table by a privileged job and applying
+// the migrations by an application unable to create tables.
+func TestBootstrap(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	// the application, like a user without DDL privileges for the migration table
+	unprivileged := dmorph.DialectSQLite()
+	unprivileged.CreateTemplate = "CREATE TABLE %s INVALID"
+
+	application, applicationErr := dmorph.NewMorpher(
+		dmorph.WithDialect(unprivileged),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, applicationErr, "application morpher could not be created")
+	require.ErrorIs(t, application.RunBootstrapped(t.Context(), db), dmorph.ErrMigrationTableMissing,
+		"missing migration table not reported")
+
+	// the privileged job
+	privileged, privilegedErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, privilegedErr, "privileged morpher could not be created")
+	require.NoError(t, privileged.Bootstrap(t.Context(), db), "migration table could not be bootstrapped")
+	require.NoError(t, privileged.Bootstrap(t.Context(), db), "existing migration table not accepted")
+	assert.Empty(t, appliedSQLite(t, db), "bootstrapping applied migrations")
+
+	require.NoError(t, application.RunBootstrapped(t.Context(), db), "migrations could not be run")
+	assert.Equal(t, []string{"01_test"}, appliedSQLite(t, db), "migration not applied")
+
+	assert.ErrorContains(t, application.Bootstrap(t.Context(), db), "could not create migration table",
+		"unprivileged bootstrap expected to fail")
+}
@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// WithCategories restricts the applied CategorizedMigration instances to the given categories, e.g. `schema`
+// to apply the schema migrations during a deployment and the `data` ones later. Migrations without category are
+// always applied. The migrations of other categories are deferred: they are neither applied nor reported as
+// applied, and a later run selecting their category applies them, even if newer migrations were applied in the
+// meantime.
+func WithCategories(categories ...string) MorphOption {
+	return func(m *Morpher) error {
+		m.Categories = append(m.Categories, categories...)
+
+		return nil
+	}
+}
+
+// readCategories reads the categories of the CategorizedMigration instances.
+func (m *Morpher) readCategories() error {
+	m.categories = nil
+
+	for _, mi := range m.Migrations {
+		categorized, isCategorized := mi.(CategorizedMigration)
+
+		if !isCategorized {
+			continue
+		}
+
+		category, categoryErr := categorized.Category()
+
+		if categoryErr != nil {
+			return fmt.Errorf("could not get category of migration %s: %w", mi.Key(), categoryErr)
+		}
+
+		if category == "" {
+			continue
+		}
+
+		if m.categories == nil {
+			m.categories = make(map[string]string)
+		}
+
+		m.categories[mi.Key()] = category
+	}
+
+	return nil
+}
+
+// categorySelected checks if the migration with the given key is selected by the configured categories.
+func (m *Morpher) categorySelected(key string) bool {
+	category := m.categories[key]
+
+	return category == "" || len(m.Categories) == 0 || slices.Contains(m.Categories, category)
+}
+
+// categoryGap checks if the migration with the given key is a categorized one that is not applied yet, even if
+// newer migrations are.
+func (m *Morpher) categoryGap(key string) bool {
+	_, isCategorized := m.categories[key]
+	_, isApplied := m.appliedKeys[key]
+
+	return isCategorized && !isApplied
+}
+
+// deferredMigrations returns the migrations that are not applied yet, but deferred as their category is not
+// selected.
+func (m *Morpher) deferredMigrations(lastMigration string) []Migration {
+	var result []Migration
+
+	for _, migration := range m.Migrations {
+		if m.notApplied(lastMigration, migration.Key()) && !m.categorySelected(migration.Key()) {
+			result = append(result, migration)
+		}
+	}
+
+	return result
+}
+
+// notApplied checks if the migration with the given key is not applied yet.
+func (m *Morpher) notApplied(lastMigration string, key string) bool {
+	return lastMigration == "" || m.KeyProp.MigrationKeyOrder(lastMigration, key) < 0 || m.categoryGap(key)
+}
+
+// lastCategorized checks the applied migrations for consistency if some migrations are categorized. The
+// uncategorized ones have to be applied in order, while the categorized ones may be applied out of order,
+// after their category was selected. It returns a copy of the Morpher knowing the applied migrations and the
+// newest applied migration.
+func (m *Morpher) lastCategorized(ctx context.Context, appliedMigrations []string) (*Morpher, string, error) {
+	for _, mi := range appliedMigrations {
+		if !m.KeyProp.MigrationKeyValid(mi) {
+			return nil, "", &KeyFormatError{Key: mi, Applied: true}
+		}
+	}
+
+	uncategorized := slices.DeleteFunc(slices.Clone(appliedMigrations), func(key string) bool {
+		_, isCategorized := m.categories[key]
+
+		return isCategorized
+	})
+
+	if !slices.IsSortedFunc(uncategorized, m.KeyProp.MigrationKeyOrder) {
+		m.logger().ErrorContext(ctx, "migrations not applied in order")
+
+		return nil, "", &UnsortedError{Applied: appliedMigrations}
+	}
+
+	sorted := slices.Clone(appliedMigrations)
+	slices.SortFunc(sorted, m.KeyProp.MigrationKeyOrder)
+
+	applied := keySet(appliedMigrations)
+
+	// deferred migrations are missing in the database, so they are skipped for the consistency check
+	checked := *m
+	checked.Migrations = slices.DeleteFunc(slices.Clone(m.Migrations), func(mi Migration) bool {
+		_, isCategorized := m.categories[mi.Key()]
+		_, isApplied := applied[mi.Key()]
+
+		return isCategorized && !isApplied
+	})
+
+	if len(checked.Migrations) == 0 {
+		return nil, "", &UnrelatedError{Position: 0, Applied: sorted[0]}
+	}
+
+	if err := checked.checkAppliedMigrations(ctx, sorted); err != nil {
+		return nil, "", err
+	}
+
+	m.logger().DebugContext(ctx, "last migration", slog.String("file", sorted[len(sorted)-1]))
+
+	result := *m
+	result.appliedKeys = applied
+
+	return &result, sorted[len(sorted)-1], nil
+}
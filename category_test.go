@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// TestCategories verifies that migrations of unselected categories are deferred and applied by a later run
+// selecting them, even after newer migrations were applied.
+func TestCategories(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	migrations := fstest.MapFS{
+		"01_schema.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+		"02_backfill.sql": {Data: []byte("-- dmorph:category data\n" +
+			"INSERT INTO t0 (id) VALUES (1)")},
+		"03_schema.sql": {Data: []byte("-- dmorph:category schema\n" +
+			"CREATE TABLE t1 (id INTEGER)")},
+	}
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations),
+		dmorph.WithCategories("schema")),
+		"schema migrations could not be run")
+
+	assert.Equal(t, []string{"01_schema.sql", "03_schema.sql"}, appliedSQLite(t, db), "wrong applied migrations")
+
+	schemaMorpher, schemaErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations),
+		dmorph.WithCategories("schema"))
+
+	require.NoError(t, schemaErr, "morpher could not be created")
+
+	pending, pendingErr := schemaMorpher.Pending(t.Context(), db)
+
+	require.NoError(t, pendingErr, "could not get pending migrations")
+	assert.Empty(t, pending, "deferred migration reported as pending")
+
+	status, statusErr := schemaMorpher.Status(t.Context(), db)
+
+	require.NoError(t, statusErr, "could not get status")
+	require.Len(t, status, 3, "wrong number of migrations")
+	assert.Equal(t, dmorph.MigrationStateDeferred, status[1].State, "data migration not deferred")
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	pending, pendingErr = morpher.Pending(t.Context(), db)
+
+	require.NoError(t, pendingErr, "could not get pending migrations")
+	assert.Equal(t, []string{"02_backfill.sql"}, pending, "data migration not pending")
+
+	require.NoError(t, morpher.Run(t.Context(), db), "data migration could not be run")
+	require.NoError(t, morpher.Run(t.Context(), db), "repeated run failed")
+
+	assert.ElementsMatch(t, []string{"01_schema.sql", "02_backfill.sql", "03_schema.sql"}, appliedSQLite(t, db),
+		"wrong applied migrations")
+
+	var rows int
+
+	require.NoError(t, db.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM t0").Scan(&rows),
+		"could not count rows")
+	assert.Equal(t, 1, rows, "data migration not applied exactly once")
+}
+
+// TestCategoriesUncategorizedGap verifies that missing migrations without category are still reported as
+// inconsistent.
+func TestCategoriesUncategorizedGap(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{
+			"01_schema.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+			"03_schema.sql": {Data: []byte("CREATE TABLE t1 (id INTEGER)")},
+		})),
+		"migrations could not be run")
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{
+			"01_schema.sql":   {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+			"02_missing.sql":  {Data: []byte("CREATE TABLE t2 (id INTEGER)")},
+			"03_schema.sql":   {Data: []byte("CREATE TABLE t1 (id INTEGER)")},
+			"04_backfill.sql": {Data: []byte("-- dmorph:category data\nINSERT INTO t0 (id) VALUES (1)")},
+		}))
+
+	var unrelated *dmorph.UnrelatedError
+
+	assert.ErrorAs(t, runErr, &unrelated, "expected unrelated migrations error")
+}
+
+// TestCategoryInvalid verifies that a migration declaring more than one category is rejected.
+func TestCategoryInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{
+			"01_base.sql": {Data: []byte("-- dmorph:category schema data\nCREATE TABLE t0 (id INTEGER)")},
+		}))
+
+	assert.ErrorIs(t, morpherErr, dmorph.ErrCategoryInvalid, "expected invalid category error")
+}
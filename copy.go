@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// copyUnsupportedRex matches the errors of databases rejecting the syntax of the COPY statement and of drivers not
+// supporting it.
+var copyUnsupportedRex = regexp.MustCompile(
+	`(?i)syntax|not supported|unsupported|not implemented|invalid SQL statement|ORA-00900`)
+
+// CopyFrom loads the given rows into the columns of the table inside the migration transaction, using the COPY
+// protocol of PostgreSQL. This is considerably faster than individual INSERT statements for large amounts of
+// data, e.g. in seed migrations. It requires a driver supporting `COPY ... FROM STDIN` as prepared statement,
+// like github.com/lib/pq: every row is passed to one execution of the statement, a final execution without
+// arguments flushes the data. The table name may be qualified by a schema. For other databases or drivers,
+// ErrCopyUnsupported is returned, while other errors of preparing the statement, e.g. of a missing table, are
+// returned as they are.
+func CopyFrom(ctx context.Context, tx *sql.Tx, table string, cols []string, rows [][]any) error {
+	quoted := make([]string, 0, len(cols))
+
+	for _, c := range cols {
+		quoted = append(quoted, QuoteStyleDouble.Quote(c))
+	}
+
+	stmt, prepareErr := tx.PrepareContext(ctx,
+		fmt.Sprintf("COPY %s (%s) FROM STDIN", QuoteStyleDouble.QuoteQualified(table), strings.Join(quoted, ", ")))
+
+	if prepareErr != nil && copyUnsupportedRex.MatchString(prepareErr.Error()) {
+		return fmt.Errorf("%w: %w", ErrCopyUnsupported, prepareErr)
+	}
+
+	if prepareErr != nil {
+		return fmt.Errorf("could not prepare copy into %s: %w", table, prepareErr)
+	}
+
+	defer func() { _ = stmt.Close() }()
+
+	for i, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return fmt.Errorf("could not copy row %d into %s: %w", i, table, err)
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("could not finish copy into %s: %w", table, err)
+	}
+
+	return wrapIfError("could not close copy statement", stmt.Close())
+}
@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// TestCopyFromUnsupported verifies that CopyFrom reports databases not supporting the COPY protocol.
+func TestCopyFromUnsupported(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	tx, txErr := db.BeginTx(t.Context(), nil)
+
+	require.NoError(t, txErr, "expected no tx error")
+
+	defer func() { _ = tx.Rollback() }()
+
+	_, createErr := tx.ExecContext(t.Context(), "CREATE TABLE seed (id INTEGER, name VARCHAR(255))")
+
+	require.NoError(t, createErr, "table could not be created")
+
+	copyErr := dmorph.CopyFrom(t.Context(), tx, "seed", []string{"id", "name"}, [][]any{{1, "a"}})
+
+	assert.ErrorIs(t, copyErr, dmorph.ErrCopyUnsupported, "unsupported database not reported")
+}
+
+// TestCopyFromPrepareError verifies that errors of preparing the statement not caused by the COPY protocol are
+// not reported as unsupported.
+func TestCopyFromPrepareError(t *testing.T) {
+	t.Parallel()
+
+	tx, txErr := openTempSQLite(t).BeginTx(t.Context(), nil)
+
+	require.NoError(t, txErr, "expected no tx error")
+	require.NoError(t, tx.Commit(), "transaction could not be committed")
+
+	copyErr := dmorph.CopyFrom(t.Context(), tx, "main.seed", []string{"id"}, [][]any{{1}})
+
+	require.ErrorIs(t, copyErr, sql.ErrTxDone, "expected finished transaction error")
+	assert.NotErrorIs(t, copyErr, dmorph.ErrCopyUnsupported, "finished transaction reported as unsupported")
+}
@@ -20,5 +20,8 @@ func DialectCSVQ() NamedParamsDialect {
 		RegisterTemplate: `
 			INSERT INTO %s (id, mgroup)
 	        VALUES(:id, :mgroup)`,
+		VersionTemplate: `SELECT @#VERSION`,
+		QuoteStyle:      QuoteStyleNone,
+		DialectName:     "csvq",
 	}
 }
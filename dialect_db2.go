@@ -14,7 +14,7 @@ func DialectDB2() NamedParamsDialect {
                     WHERE NAME = '%s' AND TYPE = 'T'
                 )
                 THEN
-                    CREATE TABLE "%s" (
+                    CREATE TABLE "%[1]s" (
                         id        VARCHAR(255) NOT NULL,
                         mgroup    VARCHAR(255) NOT NULL,
                         create_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -26,9 +26,49 @@ func DialectDB2() NamedParamsDialect {
             SELECT id
             FROM   "%s"
             WHERE  mgroup = :mgroup
-            ORDER BY create_ts ASC`,
+            ORDER BY CASE WHEN create_ts IS NULL THEN 0 ELSE 1 END,
+                     create_ts ASC,
+                     CASE WHEN create_ts IS NULL THEN id END ASC`,
+		HistoryPageTemplate: `
+            SELECT id, create_ts
+            FROM   "%s"
+            WHERE  mgroup = :mgroup
+            ORDER BY CASE WHEN create_ts IS NULL THEN 0 ELSE 1 END,
+                     create_ts ASC,
+                     CASE WHEN create_ts IS NULL THEN id END ASC
+            OFFSET :offset ROWS FETCH NEXT :limit ROWS ONLY`,
+		LatestTemplate: `
+            SELECT id
+            FROM   "%s"
+            WHERE  mgroup = :mgroup
+            AND    create_ts = (
+                SELECT MAX(create_ts)
+                FROM   "%[1]s"
+                WHERE  mgroup = :mgroup)`,
 		RegisterTemplate: `
             INSERT INTO "%s" (id, mgroup)
             VALUES (:id, :mgroup)`,
+		IdempotentRegisterTemplate: `
+            MERGE INTO "%s" AS t
+            USING (VALUES (CAST(:id AS VARCHAR(255)), CAST(:mgroup AS VARCHAR(255)))) AS s (id, mgroup)
+            ON t.id = s.id AND t.mgroup = s.mgroup
+            WHEN NOT MATCHED THEN
+                INSERT (id, mgroup) VALUES (s.id, s.mgroup)`,
+		SavepointTemplate:         `SAVEPOINT %s ON ROLLBACK RETAIN CURSORS`,
+		RollbackSavepointTemplate: `ROLLBACK TO SAVEPOINT %s`,
+		ReleaseSavepointTemplate:  `RELEASE SAVEPOINT %s`,
+		IntegrityTemplate: `
+            SELECT LOWER(NAME)
+            FROM   SYSIBM.SYSCOLUMNS
+            WHERE  TBNAME = '%s'`,
+		CommentTemplate:    `COMMENT ON TABLE "%s" IS '%s'`,
+		VersionTemplate:    `SELECT service_level FROM TABLE(sysproc.env_get_inst_info())`,
+		QuoteStyle:         QuoteStyleDouble,
+		DialectName:        "db2",
+		IDLength:           255,
+		MaxTableNameLength: 128,
+		CaseFolding:        CaseFoldingUpper,
+		DrainResults:       true,
+		RollbackDDL:        true,
 	}
 }
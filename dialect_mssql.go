@@ -3,7 +3,8 @@
 
 package dmorph
 
-// DialectMSSQL returns a Dialect configured for Microsoft SQL Server databases.
+// DialectMSSQL returns a Dialect configured for Microsoft SQL Server databases. In file migrations, lines only
+// containing the batch separator GO separate the steps, like `;` does.
 func DialectMSSQL() NamedParamsDialect {
 	return NamedParamsDialect{
 		CreateTemplate: `
@@ -12,7 +13,7 @@ func DialectMSSQL() NamedParamsDialect {
                 FROM sys.tables
                 WHERE name = '%s'
             )
-            CREATE TABLE [%s] (
+            CREATE TABLE [%[1]s] (
                 id        NVARCHAR(255) NOT NULL,
                 mgroup    NVARCHAR(255) NOT NULL,
                 create_ts DATETIME DEFAULT GETDATE(),
@@ -22,9 +23,44 @@ func DialectMSSQL() NamedParamsDialect {
             SELECT id
             FROM   [%s]
             WHERE  mgroup = @mgroup
-            ORDER BY create_ts ASC`,
+            ORDER BY CASE WHEN create_ts IS NULL THEN 0 ELSE 1 END,
+                     create_ts ASC,
+                     CASE WHEN create_ts IS NULL THEN id END ASC`,
+		HistoryPageTemplate: `
+            SELECT id, create_ts
+            FROM   [%s]
+            WHERE  mgroup = @mgroup
+            ORDER BY CASE WHEN create_ts IS NULL THEN 0 ELSE 1 END,
+                     create_ts ASC,
+                     CASE WHEN create_ts IS NULL THEN id END ASC
+            OFFSET @offset ROWS FETCH NEXT @limit ROWS ONLY`,
+		LatestTemplate: `
+            SELECT id
+            FROM   [%s]
+            WHERE  mgroup = @mgroup
+            AND    create_ts = (
+                SELECT MAX(create_ts)
+                FROM   [%[1]s]
+                WHERE  mgroup = @mgroup)`,
 		RegisterTemplate: `
             INSERT INTO [%s] (id, mgroup)
             VALUES (@id, @mgroup)`,
+		IdempotentRegisterTemplate: `
+            MERGE INTO [%s] AS t
+            USING (SELECT @id AS id, @mgroup AS mgroup) AS s
+            ON t.id = s.id AND t.mgroup = s.mgroup
+            WHEN NOT MATCHED THEN
+                INSERT (id, mgroup) VALUES (s.id, s.mgroup);`,
+		IntegrityTemplate: `
+            SELECT name
+            FROM   sys.columns
+            WHERE  object_id = OBJECT_ID('%s')`,
+		VersionTemplate:    `SELECT CAST(SERVERPROPERTY('ProductVersion') AS NVARCHAR(128))`,
+		QuoteStyle:         QuoteStyleBrackets,
+		DialectName:        "mssql",
+		IDLength:           255,
+		MaxTableNameLength: 128,
+		RollbackDDL:        true,
+		BatchSeparator:     "GO",
 	}
 }
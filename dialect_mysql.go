@@ -3,7 +3,8 @@
 
 package dmorph
 
-// DialectMySQL returns a Dialect configured for MySQL databases.
+// DialectMySQL returns a Dialect configured for MySQL databases. With utf8mb4, the primary key of the migration
+// table limits the length of the id column to 512 characters.
 func DialectMySQL() NumberedParamsDialect {
 	return NumberedParamsDialect{
 		NamedParamsDialect: NamedParamsDialect{
@@ -13,8 +14,27 @@ func DialectMySQL() NumberedParamsDialect {
 				create_ts TIMESTAMP DEFAULT current_timestamp,
 				PRIMARY KEY (id, mgroup)
 			)`,
-			AppliedTemplate:  "SELECT id FROM `%s` WHERE mgroup = ? ORDER BY create_ts ASC",
-			RegisterTemplate: "INSERT INTO `%s` (id, mgroup) VALUES(?, ?)",
+			AppliedTemplate: "SELECT id FROM `%s` WHERE mgroup = ? " +
+				"ORDER BY CASE WHEN create_ts IS NULL THEN 0 ELSE 1 END, create_ts ASC, " +
+				"CASE WHEN create_ts IS NULL THEN id END ASC",
+			HistoryPageTemplate: "SELECT id, create_ts FROM `%s` WHERE mgroup = ? " +
+				"ORDER BY CASE WHEN create_ts IS NULL THEN 0 ELSE 1 END, create_ts ASC, " +
+				"CASE WHEN create_ts IS NULL THEN id END ASC LIMIT ? OFFSET ?",
+			LatestTemplate: "SELECT id FROM `%s` WHERE mgroup = ? " +
+				"AND create_ts = (SELECT MAX(create_ts) FROM `%[1]s` WHERE mgroup = ?)",
+			RegisterTemplate:           "INSERT INTO `%s` (id, mgroup) VALUES(?, ?)",
+			IdempotentRegisterTemplate: "INSERT IGNORE INTO `%s` (id, mgroup) VALUES(?, ?)",
+			ApplicationTemplate:        "SET @dmorph_application = ?",
+			IntegrityTemplate: "SELECT column_name FROM information_schema.columns " +
+				"WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?",
+			CommentTemplate:    "ALTER TABLE `%s` COMMENT = '%s'",
+			VersionTemplate:    "SELECT VERSION()",
+			QuoteStyle:         QuoteStyleBacktick,
+			DialectName:        "mysql",
+			IDLength:           255,
+			MaxIDLength:        512,
+			MaxTableNameLength: 64,
+			BindIntegrityNames: true,
 		},
 		AppliedMigrationsParamsOrder: []ParamName{
 			ParamNameMGroup,
@@ -24,5 +44,16 @@ func DialectMySQL() NumberedParamsDialect {
 			ParamNameID,
 			ParamNameMGroup,
 		},
+
+		HistoryPageParamsOrder: []ParamName{
+			ParamNameMGroup,
+			ParamNameLimit,
+			ParamNameOffset,
+		},
+
+		LatestParamsOrder: []ParamName{
+			ParamNameMGroup,
+			ParamNameMGroup,
+		},
 	}
 }
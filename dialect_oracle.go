@@ -3,7 +3,9 @@
 
 package dmorph
 
-// DialectOracle returns a Dialect configured for Oracle Database.
+// DialectOracle returns a Dialect configured for Oracle Database. The creation of the migration table ignores
+// ORA-00955 (name is already used by an existing object), so that concurrent creators do not fail. As Oracle
+// commits DDL statements implicitly, the creation is done without a transaction.
 func DialectOracle() NamedParamsDialect {
 	return NamedParamsDialect{
 		CreateTemplate: `
@@ -26,9 +28,48 @@ func DialectOracle() NamedParamsDialect {
             SELECT id
             FROM   "%s"
             WHERE  mgroup = :mgroup
-            ORDER BY create_ts ASC`,
+            ORDER BY CASE WHEN create_ts IS NULL THEN 0 ELSE 1 END,
+                     create_ts ASC,
+                     CASE WHEN create_ts IS NULL THEN id END ASC`,
+		HistoryPageTemplate: `
+            SELECT id, create_ts
+            FROM   "%s"
+            WHERE  mgroup = :mgroup
+            ORDER BY CASE WHEN create_ts IS NULL THEN 0 ELSE 1 END,
+                     create_ts ASC,
+                     CASE WHEN create_ts IS NULL THEN id END ASC
+            OFFSET :offset ROWS FETCH NEXT :limit ROWS ONLY`,
+		LatestTemplate: `
+            SELECT id
+            FROM   "%s"
+            WHERE  mgroup = :mgroup
+            AND    create_ts = (
+                SELECT MAX(create_ts)
+                FROM   "%[1]s"
+                WHERE  mgroup = :mgroup)`,
 		RegisterTemplate: `
             INSERT INTO "%s" (id, mgroup)
             VALUES (:id, :mgroup)`,
+		IdempotentRegisterTemplate: `
+            MERGE INTO "%s" t
+            USING (SELECT :id AS id, :mgroup AS mgroup FROM dual) s
+            ON (t.id = s.id AND t.mgroup = s.mgroup)
+            WHEN NOT MATCHED THEN
+                INSERT (id, mgroup) VALUES (s.id, s.mgroup)`,
+		IntegrityTemplate: `
+            SELECT LOWER(column_name)
+            FROM   user_tab_columns
+            WHERE  table_name = '%s'`,
+		CommentTemplate:    `COMMENT ON TABLE "%s" IS '%s'`,
+		VersionTemplate:    `SELECT MAX(version) FROM product_component_version WHERE product LIKE 'Oracle%'`,
+		QuoteStyle:         QuoteStyleDouble,
+		DialectName:        "oracle",
+		IDLength:           255,
+		MaxTableNameLength: 128,
+		CaseFolding:        CaseFoldingUpper,
+		DrainResults:       true,
+
+		// Oracle implicitly commits DDL statements, so there is no use in a transaction
+		NonTransactionalDDL: true,
 	}
 }
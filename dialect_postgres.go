@@ -19,9 +19,77 @@ func DialectPostgres() NamedParamsDialect {
 			SELECT id
 			FROM   "%s"
 			WHERE  mgroup = :mgroup
-	        ORDER BY create_ts ASC`,
+	        ORDER BY CASE WHEN create_ts IS NULL THEN 0 ELSE 1 END,
+	                 create_ts ASC,
+	                 CASE WHEN create_ts IS NULL THEN id END ASC`,
+		HistoryPageTemplate: `
+			SELECT id, create_ts
+			FROM   "%s"
+			WHERE  mgroup = :mgroup
+			ORDER BY CASE WHEN create_ts IS NULL THEN 0 ELSE 1 END,
+			         create_ts ASC,
+			         CASE WHEN create_ts IS NULL THEN id END ASC
+			LIMIT :limit OFFSET :offset`,
+		LatestTemplate: `
+			SELECT id
+			FROM   "%s"
+			WHERE  mgroup = :mgroup
+			AND    create_ts = (
+			    SELECT MAX(create_ts)
+			    FROM   "%[1]s"
+			    WHERE  mgroup = :mgroup)`,
 		RegisterTemplate: `
 			INSERT INTO "%s" (id, mgroup)
 	        VALUES(:id, :mgroup)`,
+		IdempotentRegisterTemplate: `
+			INSERT INTO "%s" (id, mgroup)
+	        VALUES(:id, :mgroup)
+	        ON CONFLICT (id, mgroup) DO NOTHING`,
+		IntegrityTemplate: `
+			SELECT column_name
+			FROM   information_schema.columns
+			WHERE  table_schema = COALESCE(NULLIF($1, ''), current_schema())
+			AND    table_name = $2`,
+		CommentTemplate: `COMMENT ON TABLE "%s" IS '%s'`,
+		ListTablesTemplate: `
+			SELECT   table_name
+			FROM     information_schema.columns
+			WHERE    table_schema = current_schema()
+			AND      column_name IN ('id', 'mgroup', 'create_ts')
+			GROUP BY table_name
+			HAVING   COUNT(DISTINCT column_name) = 3
+			ORDER BY table_name`,
+		SavepointTemplate:         `SAVEPOINT %s`,
+		RollbackSavepointTemplate: `ROLLBACK TO SAVEPOINT %s`,
+		ReleaseSavepointTemplate:  `RELEASE SAVEPOINT %s`,
+		ApplicationTemplate:       `SELECT set_config('application_name', $1, true)`,
+		SchemaTemplate: `
+			SELECT ddl
+			FROM  (SELECT 0 AS kind,
+			              c.table_name AS name,
+			              'CREATE TABLE ' || quote_ident(c.table_name) || ' (' ||
+			              string_agg(quote_ident(c.column_name) || ' ' || c.data_type ||
+			                         CASE WHEN c.is_nullable = 'NO' THEN ' NOT NULL' ELSE '' END ||
+			                         COALESCE(' DEFAULT ' || c.column_default, ''),
+			                         ', ' ORDER BY c.ordinal_position) || ')' AS ddl
+			       FROM   information_schema.columns c
+			       JOIN   information_schema.tables t
+			       ON     t.table_schema = c.table_schema AND t.table_name = c.table_name
+			       WHERE  c.table_schema = current_schema()
+			       AND    t.table_type = 'BASE TABLE'
+			       GROUP BY c.table_name
+			       UNION ALL
+			       SELECT 1, indexname, indexdef
+			       FROM   pg_indexes
+			       WHERE  schemaname = current_schema()) s
+			ORDER BY kind, name`,
+		VersionTemplate:    `SHOW server_version`,
+		QuoteStyle:         QuoteStyleDouble,
+		DialectName:        "postgres",
+		IDLength:           255,
+		MaxTableNameLength: 63,
+		CaseFolding:        CaseFoldingLower,
+		RollbackDDL:        true,
+		BindIntegrityNames: true,
 	}
 }
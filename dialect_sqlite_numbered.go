@@ -21,12 +21,61 @@ func DialectSQLiteNumbered() NumberedParamsDialect {
 			SELECT id
 			FROM   "%s"
 			WHERE  mgroup = ?
-	        ORDER BY create_ts ASC`,
+	        ORDER BY CASE WHEN create_ts IS NULL THEN 0 ELSE 1 END,
+	                 create_ts ASC,
+	                 CASE WHEN create_ts IS NULL THEN id END ASC`,
+			HistoryPageTemplate: `
+			SELECT id, create_ts
+			FROM   "%s"
+			WHERE  mgroup = ?
+			ORDER BY CASE WHEN create_ts IS NULL THEN 0 ELSE 1 END,
+			         create_ts ASC,
+			         CASE WHEN create_ts IS NULL THEN id END ASC
+			LIMIT ? OFFSET ?`,
+			LatestTemplate: `
+			SELECT id
+			FROM   "%s"
+			WHERE  mgroup = ?
+			AND    create_ts = (
+			    SELECT MAX(create_ts)
+			    FROM   "%[1]s"
+			    WHERE  mgroup = ?)`,
 			RegisterTemplate: `
 			INSERT INTO "%s" (id, mgroup)
 	        VALUES(?, ?)`,
+			IdempotentRegisterTemplate: `
+			INSERT INTO "%s" (id, mgroup)
+	        VALUES(?, ?)
+	        ON CONFLICT (id, mgroup) DO NOTHING`,
+			IntegrityTemplate: `
+			SELECT name
+			FROM   pragma_table_info('%s')`,
+			ListTablesTemplate: `
+			SELECT m.name
+			FROM   sqlite_master m
+			WHERE  m.type = 'table'
+			AND    (SELECT COUNT(*)
+			        FROM   pragma_table_info(m.name)
+			        WHERE  name IN ('id', 'mgroup', 'create_ts')) = 3
+			ORDER BY m.name`,
+			SavepointTemplate:         `SAVEPOINT %s`,
+			RollbackSavepointTemplate: `ROLLBACK TO SAVEPOINT %s`,
+			ReleaseSavepointTemplate:  `RELEASE SAVEPOINT %s`,
+			SchemaTemplate: `
+			SELECT sql
+			FROM   sqlite_master
+			WHERE  sql IS NOT NULL
+			AND    name NOT LIKE 'sqlite_%'
+			ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 ELSE 2 END, name`,
+			VersionTemplate: `SELECT sqlite_version()`,
+			QuoteStyle:      QuoteStyleDouble,
+			DialectName:     "sqlite_numbered",
+			IDLength:        255,
+			RollbackDDL:     true,
 		},
 		AppliedMigrationsParamsOrder: []ParamName{ParamNameMGroup},
 		RegisterMigrationParamsOrder: []ParamName{ParamNameID, ParamNameMGroup},
+		HistoryPageParamsOrder:       []ParamName{ParamNameMGroup, ParamNameLimit, ParamNameOffset},
+		LatestParamsOrder:            []ParamName{ParamNameMGroup, ParamNameMGroup},
 	}
 }
@@ -8,19 +8,553 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 // NamedParamsDialect is a convenience type for databases that manage the necessary operations solely using
 // queries. Defining the CreateTemplate, AppliedTemplate and RegisterTemplate enables the NamedParamsDialect to
 // perform all the necessary operations to fulfill the Dialect interface.
 type NamedParamsDialect struct {
-	CreateTemplate   string // statement ensuring the existence of the migration table
-	AppliedTemplate  string // statement getting applied migrations ordered by application date
-	RegisterTemplate string // statement registering a migration
+	CreateTemplate             string     // statement ensuring the existence of the migration table
+	AppliedTemplate            string     // statement getting applied migrations ordered by application date
+	RegisterTemplate           string     // statement registering a migration
+	IdempotentRegisterTemplate string     // statement registering a migration unless registered already, optional
+	IntegrityTemplate          string     // statement getting the column names of the migration table, optional
+	CommentTemplate            string     // statement setting the comment of the migration table, optional
+	ListTablesTemplate         string     // statement listing all tables shaped like migration tables, optional
+	HistoryPageTemplate        string     // statement getting a page of applied migrations with timestamps, optional
+	LatestTemplate             string     // statement getting the migrations with the latest timestamp, optional
+	VersionTemplate            string     // statement getting the version of the database server, optional
+	OrdinalTemplate            string     // statement getting the next ordinal of the migration group, optional
+	SchemaTemplate             string     // statement getting the DDL of all tables and indexes in order, optional
+	SavepointTemplate          string     // statement creating the savepoint of the given name, optional
+	RollbackSavepointTemplate  string     // statement rolling back to the savepoint of the given name, optional
+	ReleaseSavepointTemplate   string     // statement releasing the savepoint of the given name, optional
+	ApplicationTemplate        string     // statement annotating a transaction with an application name, optional
+	QuoteStyle                 QuoteStyle // style used to enclose identifiers
+	DialectName                string     // name of the dialect, e.g. used in log messages
+	BatchSeparator             string     // line separating steps in addition to `;`, e.g. GO for MSSQL, optional
+	IDLength                   int        // declared length of the id column, keys are checked against it, optional
+	MaxIDLength                int        // maximum length of the id column supported by the database, optional
+	OrderColumn                string     // column ordering the applied migrations, create_ts if empty
+	MaxTableNameLength         int        // maximum length of table names supported by the database, optional
+
+	// QuoteTableName quotes the table name using the QuoteStyle wherever it is used as identifier, e.g. for
+	// schema-qualified names like `schema.migrations`, whose parts are quoted separately. The templates then have
+	// to use the bare placeholder instead of quoting it themselves, see WithQuotedTableName.
+	QuoteTableName bool
+
+	// BindIntegrityNames marks an IntegrityTemplate binding the schema and the name of the migration table as
+	// positional parameters, in this order, instead of containing the unqualified name. The schema is empty for
+	// unqualified names, so that the template can fall back to the current schema.
+	BindIntegrityNames bool
+
+	// CaseFolding is the case of unquoted identifiers in the database, used to fold table names, see
+	// FoldIdentifier.
+	CaseFolding CaseFolding
+
+	// NonTransactionalDDL marks databases that implicitly commit DDL statements, like Oracle. For those the
+	// migration table is created without a surrounding transaction.
+	NonTransactionalDDL bool
+
+	// DrainResults marks databases whose drivers may return result sets for the procedural blocks of the
+	// CreateTemplate, like Oracle and DB2. For those the statements are queried and all results are drained, so
+	// the connection is left in a clean state.
+	DrainResults bool
+
+	// RollbackDDL marks databases that can roll back DDL statements, like PostgreSQL. For those migrations can be
+	// validated by executing them in a transaction that is rolled back, see WithValidateFirst.
+	RollbackDDL bool
+}
+
+// Name returns the name of the dialect.
+func (b NamedParamsDialect) Name() string {
+	return b.DialectName
+}
+
+// StepSeparator returns the line separating the steps of file migrations in addition to `;`.
+func (b NamedParamsDialect) StepSeparator() string {
+	return b.BatchSeparator
+}
+
+// DryRunSupported returns if the database can roll back DDL statements.
+func (b NamedParamsDialect) DryRunSupported() bool {
+	return b.RollbackDDL
+}
+
+// AnnotateTx annotates the given transaction with the given application name using the ApplicationTemplate,
+// e.g. to correlate long-running statements of migrations to them in `pg_stat_activity`. The name is bound as
+// the only parameter of the ApplicationTemplate. If the ApplicationTemplate is not set, the transaction is not
+// annotated.
+func (b NamedParamsDialect) AnnotateTx(ctx context.Context, tx *sql.Tx, name string) error {
+	if b.ApplicationTemplate == "" {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, b.ApplicationTemplate, name)
+
+	return wrapIfError("could not annotate transaction", err)
+}
+
+// SavepointsSupported returns if the dialect has templates to create, roll back to and release savepoints.
+func (b NamedParamsDialect) SavepointsSupported() bool {
+	return b.SavepointTemplate != "" && b.RollbackSavepointTemplate != "" && b.ReleaseSavepointTemplate != ""
+}
+
+// Savepoint creates the savepoint of the given name within the given transaction.
+func (b NamedParamsDialect) Savepoint(ctx context.Context, tx *sql.Tx, name string) error {
+	return b.savepointStatement(ctx, tx, b.SavepointTemplate, name)
+}
+
+// RollbackSavepoint rolls the given transaction back to the savepoint of the given name.
+func (b NamedParamsDialect) RollbackSavepoint(ctx context.Context, tx *sql.Tx, name string) error {
+	return b.savepointStatement(ctx, tx, b.RollbackSavepointTemplate, name)
+}
+
+// ReleaseSavepoint releases the savepoint of the given name within the given transaction.
+func (b NamedParamsDialect) ReleaseSavepoint(ctx context.Context, tx *sql.Tx, name string) error {
+	return b.savepointStatement(ctx, tx, b.ReleaseSavepointTemplate, name)
+}
+
+// savepointStatement executes the given savepoint template for the savepoint of the given name. If the template
+// is not set, ErrSavepointUnsupported is returned.
+func (b NamedParamsDialect) savepointStatement(ctx context.Context, tx *sql.Tx, template string, name string) error {
+	if template == "" {
+		return ErrSavepointUnsupported
+	}
+
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(template, name))
+
+	return wrapIfError("could not execute savepoint statement", err)
+}
+
+// KeyLength returns the maximum length of the migration keys that fit into the id column.
+func (b NamedParamsDialect) KeyLength() int {
+	return b.IDLength
+}
+
+// idTypeRex matches the type of the id column in the CreateTemplate, and its casts, of the included dialects.
+var idTypeRex = regexp.MustCompile(`(?m)((?:^\s*id\s+|:id AS )N?VARCHAR2?)\(\d+\)`)
+
+// WithIDLength returns a copy of the dialect declaring the id column of the migration table with the given
+// length, e.g. for keys encoding full paths. Only types like `VARCHAR(255)` following the column name id in the
+// templates are adapted, so custom templates might need to be adapted manually. If the length exceeds the
+// MaxIDLength of the dialect, e.g. due to index size limits of the primary key, ErrIDLengthInvalid is returned.
+// Existing migration tables are not altered.
+func (b NamedParamsDialect) WithIDLength(length int) (NamedParamsDialect, error) {
+	if length < 1 || (b.MaxIDLength > 0 && length > b.MaxIDLength) {
+		return b, fmt.Errorf("%w: %d", ErrIDLengthInvalid, length)
+	}
+
+	replacement := fmt.Sprintf("${1}(%d)", length)
+
+	b.CreateTemplate = idTypeRex.ReplaceAllString(b.CreateTemplate, replacement)
+	b.IdempotentRegisterTemplate = idTypeRex.ReplaceAllString(b.IdempotentRegisterTemplate, replacement)
+	b.IDLength = length
+
+	return b, nil
+}
+
+// reservedWords contains the words reserved by the SQL standard and all included database management systems.
+// Though the dialects quote the table name, such names break any SQL written by hand.
+var reservedWords = []string{
+	"ALL", "AND", "AS", "BETWEEN", "BY", "CASE", "CHECK", "COLUMN", "CONSTRAINT", "CREATE", "DEFAULT", "DELETE",
+	"DISTINCT", "DROP", "ELSE", "FROM", "GROUP", "HAVING", "IN", "INSERT", "INTO", "IS", "JOIN", "NOT", "NULL",
+	"ON", "OR", "ORDER", "PRIMARY", "REFERENCES", "SELECT", "SET", "TABLE", "THEN", "UNION", "UNIQUE", "UPDATE",
+	"VALUES", "WHEN", "WHERE",
+}
+
+// ValidateTableName checks the given table name against the constraints of the database, i.e. its
+// MaxTableNameLength and the words reserved by all included database management systems, surfacing problems
+// before running instead of failing in the database. Violations are reported as ErrMigrationTableNameInvalid.
+func (b NamedParamsDialect) ValidateTableName(name string) error {
+	// the constraints apply to the table itself, not to its schema
+	name = name[strings.LastIndex(name, ".")+1:]
+
+	if b.MaxTableNameLength > 0 && len(name) > b.MaxTableNameLength {
+		return fmt.Errorf("%w: %s exceeds %d characters supported by %s",
+			ErrMigrationTableNameInvalid, name, b.MaxTableNameLength, b.DialectName)
+	}
+
+	for _, word := range reservedWords {
+		if strings.EqualFold(name, word) {
+			return fmt.Errorf("%w: %s is a reserved word", ErrMigrationTableNameInvalid, name)
+		}
+	}
+
+	return nil
+}
+
+// orderColumnRex matches the default order column of the migration table in the templates.
+var orderColumnRex = regexp.MustCompile(`\bcreate_ts\b`)
+
+// WithOrderColumn returns a copy of the dialect ordering the applied migrations by the given column instead of
+// create_ts, e.g. to adopt existing migration tables with an `applied_at` column. The column is replaced in all
+// templates, so that creating, ordering and checking the migration table stay consistent. Custom templates not
+// using create_ts might need to be adapted manually. If the column is not a plain identifier,
+// ErrOrderColumnInvalid is returned. Existing migration tables are not altered.
+func (b NamedParamsDialect) WithOrderColumn(column string) (NamedParamsDialect, error) {
+	if !ValidTableNameRex.MatchString(column) {
+		return b, fmt.Errorf("%w: %q", ErrOrderColumnInvalid, column)
+	}
+
+	for _, template := range []*string{
+		&b.CreateTemplate,
+		&b.AppliedTemplate,
+		&b.RegisterTemplate,
+		&b.IdempotentRegisterTemplate,
+		&b.IntegrityTemplate,
+		&b.ListTablesTemplate,
+		&b.HistoryPageTemplate,
+		&b.LatestTemplate,
+		&b.OrdinalTemplate,
+	} {
+		*template = orderColumnRex.ReplaceAllLiteralString(*template, column)
+	}
+
+	b.OrderColumn = column
+
+	return b, nil
+}
+
+// orderColumn returns the column ordering the applied migrations.
+func (b NamedParamsDialect) orderColumn() string {
+	if b.OrderColumn == "" {
+		return "create_ts"
+	}
+
+	return b.OrderColumn
+}
+
+// CaseFolding represents the way a database normalizes the case of unquoted identifiers. Quoted identifiers,
+// like the migration table names in the included dialects, keep their case, so that `Migrations` and
+// `migrations` are different tables, but only one of them is found by SQL not quoting the name.
+type CaseFolding int
+
+const (
+	// CaseFoldingNone keeps the case of unquoted identifiers or compares them case-insensitively, e.g. SQLite.
+	CaseFoldingNone CaseFolding = iota
+
+	// CaseFoldingLower folds unquoted identifiers to lower case, e.g. PostgreSQL.
+	CaseFoldingLower
+
+	// CaseFoldingUpper folds unquoted identifiers to upper case, as the SQL standard suggests, e.g. Oracle.
+	CaseFoldingUpper
+)
+
+// Fold normalizes the case of the given identifier as the database does for unquoted identifiers.
+func (c CaseFolding) Fold(name string) string {
+	switch c {
+	case CaseFoldingLower:
+		return strings.ToLower(name)
+	case CaseFoldingUpper:
+		return strings.ToUpper(name)
+	default:
+		return name
+	}
+}
+
+// QuoteStyle represents the way a database encloses identifiers, e.g. table names.
+type QuoteStyle int
+
+const (
+	// QuoteStyleNone leaves identifiers as they are.
+	QuoteStyleNone QuoteStyle = iota
+
+	// QuoteStyleDouble encloses identifiers in double quotes, e.g. "name", as the SQL standard suggests.
+	QuoteStyleDouble
+
+	// QuoteStyleBrackets encloses identifiers in square brackets, e.g. [name], as used by Microsoft SQL Server.
+	QuoteStyleBrackets
+
+	// QuoteStyleBacktick encloses identifiers in backticks, e.g. `name`, as used by MySQL.
+	QuoteStyleBacktick
+)
+
+// Quote encloses the given identifier according to the quote style. Occurrences of the closing quote
+// character inside the identifier are doubled, so that they do not terminate the identifier prematurely.
+func (q QuoteStyle) Quote(name string) string {
+	switch q {
+	case QuoteStyleDouble:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	case QuoteStyleBrackets:
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	case QuoteStyleBacktick:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	default:
+		return name
+	}
+}
+
+// WithQuotedTableName returns a copy of the dialect quoting the table name itself, instead of the templates
+// enclosing the placeholder in quotes. This allows schema-qualified table names, e.g. `schema.migrations`,
+// whose parts are quoted separately. Only placeholders enclosed in the quotes of the QuoteStyle are adapted, so
+// custom templates might need to be adapted manually.
+func (b NamedParamsDialect) WithQuotedTableName() NamedParamsDialect {
+	unquote := strings.NewReplacer(b.QuoteStyle.Quote("%s"), "%s", b.QuoteStyle.Quote("%[1]s"), "%[1]s")
+
+	for _, template := range []*string{
+		&b.CreateTemplate,
+		&b.AppliedTemplate,
+		&b.RegisterTemplate,
+		&b.IdempotentRegisterTemplate,
+		&b.CommentTemplate,
+		&b.HistoryPageTemplate,
+		&b.LatestTemplate,
+	} {
+		*template = unquote.Replace(*template)
+	}
+
+	b.QuoteTableName = true
+
+	return b
+}
+
+// QuoteQualified encloses each part of the given, possibly schema-qualified, name according to the quote style,
+// e.g. "schema"."table" for schema.table.
+func (q QuoteStyle) QuoteQualified(name string) string {
+	parts := strings.Split(name, ".")
+
+	for i, part := range parts {
+		parts[i] = q.Quote(part)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// tableSQL fills the table name, quoted if configured, and the given further arguments into the given template.
+// All statements using the table name as identifier are built using it, so that they never diverge.
+// If the table name is quoted, placeholders of the table name enclosed in single quotes, e.g. the existence
+// probes of Microsoft SQL Server and DB2, are filled with the unqualified name as string literal instead.
+func (b NamedParamsDialect) tableSQL(template string, tableName string, args ...any) string {
+	if b.QuoteTableName {
+		template = literalTableTemplate(template, tableName)
+		tableName = b.QuoteStyle.QuoteQualified(tableName)
+	}
+
+	return fmt.Sprintf(template, append([]any{tableName}, args...)...)
+}
+
+// verbRex matches the formatting verbs of the templates, with their optional explicit argument index.
+var verbRex = regexp.MustCompile(`%(?:\[(\d+)\])?([a-zA-Z%])`)
+
+// literalTableTemplate replaces the placeholders of the table name, i.e. the first argument, that are enclosed in
+// single quotes by the table name as string content, see tableLiteral. All other verbs are given explicit
+// argument indexes, so that they keep referring to their arguments.
+func literalTableTemplate(template string, tableName string) string {
+	var result strings.Builder
+
+	next := 1
+	last := 0
+
+	for _, match := range verbRex.FindAllStringSubmatchIndex(template, -1) {
+		start, end := match[0], match[1]
+		verb := template[match[4]:match[5]]
+
+		result.WriteString(template[last:start])
+		last = end
+
+		if verb == "%" {
+			result.WriteString("%%")
+
+			continue
+		}
+
+		arg := next
+
+		if match[2] >= 0 {
+			arg, _ = strconv.Atoi(template[match[2]:match[3]])
+		}
+
+		next = arg + 1
+
+		if arg == 1 && start > 0 && template[start-1] == '\'' && end < len(template) && template[end] == '\'' {
+			result.WriteString(strings.ReplaceAll(tableLiteral(tableName), "%", "%%"))
+
+			continue
+		}
+
+		result.WriteString("%[" + strconv.Itoa(arg) + "]" + verb)
+	}
+
+	result.WriteString(template[last:])
+
+	return result.String()
+}
+
+// tableLiteral returns the unqualified table name as SQL string content, as used by the IntegrityTemplate to look
+// up the columns of the table.
+func tableLiteral(tableName string) string {
+	_, table := splitTableName(tableName)
+
+	return strings.ReplaceAll(table, "'", "''")
+}
+
+// splitTableName splits the given, possibly schema-qualified, table name into its schema and its unqualified
+// name. The schema is empty for unqualified names.
+func splitTableName(tableName string) (string, string) {
+	i := strings.LastIndex(tableName, ".")
+
+	if i < 0 {
+		return "", tableName
+	}
+
+	return tableName[:i], tableName[i+1:]
+}
+
+// integrityQuery returns the IntegrityTemplate for the given table name and its parameters, see
+// BindIntegrityNames.
+func (b NamedParamsDialect) integrityQuery(tableName string) (string, []any) {
+	if !b.BindIntegrityNames {
+		return fmt.Sprintf(b.IntegrityTemplate, tableLiteral(tableName)), nil
+	}
+
+	schema, table := splitTableName(tableName)
+
+	return b.IntegrityTemplate, []any{schema, table}
+}
+
+// QualifiesTableName reports if the dialect quotes the table name itself, so that it may be qualified by a
+// schema, see WithQuotedTableName.
+func (b NamedParamsDialect) QualifiesTableName() bool {
+	return b.QuoteTableName
+}
+
+// FoldIdentifier normalizes the case of the given identifier according to the CaseFolding of the dialect, so
+// that the quoted identifier denotes the same object as the unquoted one, see WithFoldTableName.
+func (b NamedParamsDialect) FoldIdentifier(name string) string {
+	return b.CaseFolding.Fold(name)
+}
+
+// QuoteQualified encloses each part of the given, possibly schema-qualified, identifier in the quote characters
+// of the dialect, as the table name is quoted in the statements.
+func (b NamedParamsDialect) QuoteQualified(name string) string {
+	return b.QuoteStyle.QuoteQualified(name)
+}
+
+// QuoteIdentifier encloses the given identifier in the quote characters of the dialect. It is intended for
+// users building their own SQL, keeping it consistent with the quoting of the migration table.
+func (b NamedParamsDialect) QuoteIdentifier(name string) string {
+	return b.QuoteStyle.Quote(name)
 }
 
 // EnsureMigrationTableExists ensures that the migration table, saving the applied migrations ids, exists.
 func (b NamedParamsDialect) EnsureMigrationTableExists(ctx context.Context, db *sql.DB, tableName string) error {
+	return b.EnsureMigrationTableExistsWithComment(ctx, db, tableName, "")
+}
+
+// EnsureMigrationTableExistsWithComment ensures that the migration table exists and sets its comment in the
+// same transaction. If the comment or the CommentTemplate is empty, no comment is set. For dialects with
+// NonTransactionalDDL, the statements are executed without a transaction.
+func (b NamedParamsDialect) EnsureMigrationTableExistsWithComment(
+	ctx context.Context,
+	db *sql.DB,
+	tableName string,
+	comment string) error {
+
+	statements := []string{b.tableSQL(b.CreateTemplate, tableName)}
+
+	if comment != "" && b.CommentTemplate != "" {
+		// the comment is a string literal, so enclosed single quotes are doubled
+		statements = append(statements,
+			b.tableSQL(b.CommentTemplate, tableName, strings.ReplaceAll(comment, "'", "''")))
+	}
+
+	if err := b.execDDL(ctx, db, statements); err != nil {
+		return err
+	}
+
+	return b.verifyMigrationTable(ctx, db, tableName)
+}
+
+// MigrationTableExists checks if the migration table exists, by probing its columns using the
+// IntegrityTemplate. If the IntegrityTemplate is not set, ErrIntegrityCheckUnsupported is returned.
+func (b NamedParamsDialect) MigrationTableExists(ctx context.Context, db *sql.DB, tableName string) (bool, error) {
+	if b.IntegrityTemplate == "" {
+		return false, ErrIntegrityCheckUnsupported
+	}
+
+	query, params := b.integrityQuery(tableName)
+	names, err := queryStrings(ctx, db, query, params...)
+
+	if err != nil {
+		return false, wrapIfError("could not get migration table columns", err)
+	}
+
+	return len(names) > 0, nil
+}
+
+// verifyMigrationTable verifies that the migration table exists after its creation, e.g. to detect a
+// CreateTemplate ignoring the requested table name. Without IntegrityTemplate, the verification is skipped.
+func (b NamedParamsDialect) verifyMigrationTable(ctx context.Context, db *sql.DB, tableName string) error {
+	if b.IntegrityTemplate == "" {
+		return nil
+	}
+
+	exists, err := b.MigrationTableExists(ctx, db, tableName)
+
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("%w: %s not created, check the CreateTemplate", ErrNoMigrationTable, tableName)
+	}
+
+	return nil
+}
+
+// statementRunner is implemented by sql.DB and sql.Tx, executing statements with or without results.
+type statementRunner interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// execStatement executes the given statement, draining all its result sets for dialects with DrainResults.
+func (b NamedParamsDialect) execStatement(ctx context.Context, runner statementRunner, statement string) error {
+	if !b.DrainResults {
+		_, err := runner.ExecContext(ctx, statement)
+
+		return err //nolint:wrapcheck
+	}
+
+	rows, err := runner.QueryContext(ctx, statement)
+
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	for {
+		for rows.Next() { //nolint:revive // the results are only drained
+		}
+
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+
+	return rows.Err() //nolint:wrapcheck
+}
+
+// execDDL executes the given statements in a transaction, or without one for dialects with
+// NonTransactionalDDL.
+func (b NamedParamsDialect) execDDL(ctx context.Context, db *sql.DB, statements []string) error {
+	if b.NonTransactionalDDL {
+		for _, statement := range statements {
+			if execErr := b.execStatement(ctx, db, statement); execErr != nil {
+				return wrapIfError("could not execute statement", execErr)
+			}
+		}
+
+		return nil
+	}
+
 	tx, err := db.BeginTx(ctx, nil)
 
 	if err != nil {
@@ -31,10 +565,12 @@ func (b NamedParamsDialect) EnsureMigrationTableExists(ctx context.Context, db *
 	// as it does semantically nothing in case of a previous successful commit
 	defer func() { _ = tx.Rollback() }()
 
-	if _, execErr := tx.ExecContext(ctx, fmt.Sprintf(b.CreateTemplate, tableName)); execErr != nil {
-		rollbackErr := tx.Rollback()
+	for _, statement := range statements {
+		if execErr := b.execStatement(ctx, tx, statement); execErr != nil {
+			rollbackErr := tx.Rollback()
 
-		return errors.Join(execErr, rollbackErr)
+			return errors.Join(execErr, rollbackErr)
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -53,7 +589,39 @@ func (b NamedParamsDialect) AppliedMigrations(
 	tableName string,
 	groupName string) ([]string, error) {
 
-	rows, rowsErr := db.QueryContext(ctx, fmt.Sprintf(b.AppliedTemplate, tableName),
+	return b.appliedMigrations(ctx, db, tableName, groupName)
+}
+
+// EnsureMigrationTableExistsTx ensures that the migration table exists within the given transaction. Only
+// dialects with RollbackDDL support this, others return ErrRunTxUnsupported.
+func (b NamedParamsDialect) EnsureMigrationTableExistsTx(ctx context.Context, tx *sql.Tx, tableName string) error {
+	if !b.RollbackDDL {
+		return ErrRunTxUnsupported
+	}
+
+	return wrapIfError("could not create migration table",
+		b.execStatement(ctx, tx, b.tableSQL(b.CreateTemplate, tableName)))
+}
+
+// AppliedMigrationsTx gets the already applied migrations within the given transaction, ordered by application
+// date.
+func (b NamedParamsDialect) AppliedMigrationsTx(
+	ctx context.Context,
+	tx *sql.Tx,
+	tableName string,
+	groupName string) ([]string, error) {
+
+	return b.appliedMigrations(ctx, tx, tableName, groupName)
+}
+
+// appliedMigrations gets the already applied migrations using the given runner.
+func (b NamedParamsDialect) appliedMigrations(
+	ctx context.Context,
+	runner statementRunner,
+	tableName string,
+	groupName string) ([]string, error) {
+
+	rows, rowsErr := runner.QueryContext(ctx, b.tableSQL(b.AppliedTemplate, tableName),
 		sql.Named("mgroup", groupName))
 
 	if rowsErr != nil {
@@ -72,7 +640,80 @@ func (b NamedParamsDialect) AppliedMigrations(
 		}
 	}
 
-	return result, errors.Join(rows.Err(), scanErr)
+	// partial results are never returned, so that callers cannot mistake them for complete ones
+	if err := errors.Join(rows.Err(), scanErr); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// HistoryPage gets a page of the applied migrations, including the time of their application, ordered by
+// application date.
+func (b NamedParamsDialect) HistoryPage(
+	ctx context.Context,
+	db *sql.DB,
+	tableName string,
+	groupName string,
+	limit int,
+	offset int) ([]AppliedMigration, error) {
+
+	if b.HistoryPageTemplate == "" {
+		return nil, ErrHistoryUnsupported
+	}
+
+	return queryHistory(ctx, db, b.tableSQL(b.HistoryPageTemplate, tableName),
+		sql.Named("mgroup", groupName),
+		sql.Named("limit", limit),
+		sql.Named("offset", offset))
+}
+
+// LatestMigrations gets the migrations applied last, i.e. sharing the latest timestamp. If the LatestTemplate is
+// not set, all applied migrations are returned.
+func (b NamedParamsDialect) LatestMigrations(
+	ctx context.Context,
+	db *sql.DB,
+	tableName string,
+	groupName string) ([]string, error) {
+
+	if b.LatestTemplate == "" {
+		return b.AppliedMigrations(ctx, db, tableName, groupName)
+	}
+
+	result, err := queryStrings(ctx, db, b.tableSQL(b.LatestTemplate, tableName), sql.Named("mgroup", groupName))
+
+	return result, wrapIfError("could not get latest migrations", err)
+}
+
+// queryHistory executes the given query, returning the applied migrations including the time of their
+// application.
+func queryHistory(ctx context.Context, db *sql.DB, query string, args ...any) ([]AppliedMigration, error) {
+	rows, rowsErr := db.QueryContext(ctx, query, args...)
+
+	if rowsErr != nil {
+		return nil, wrapIfError("could not get migration history", rowsErr)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	var result []AppliedMigration
+	var id string
+	var applied sql.NullTime
+	var scanErr error
+
+	for rows.Next() && scanErr == nil {
+		// the timestamp may be missing for manually inserted migrations, resulting in the zero time
+		if scanErr = rows.Scan(&id, &applied); scanErr == nil {
+			result = append(result, AppliedMigration{ID: id, Applied: applied.Time})
+		}
+	}
+
+	// partial results are never returned, so that callers cannot mistake them for complete ones
+	if err := errors.Join(rows.Err(), scanErr); err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
 // RegisterMigration registers a migration in the migration table.
@@ -83,11 +724,277 @@ func (b NamedParamsDialect) RegisterMigration(
 	tableName string,
 	groupName string) error {
 
-	_, err := tx.ExecContext(ctx, fmt.Sprintf(b.RegisterTemplate, tableName),
-		sql.Named("id", id),
-		sql.Named("mgroup", groupName))
+	return b.register(ctx, tx, nil, b.RegisterTemplate, id, tableName, groupName, nil)
+}
+
+// RegisterMigrationWithValues registers a migration in the migration table, passing the given values as
+// additional named parameters to the RegisterTemplate.
+func (b NamedParamsDialect) RegisterMigrationWithValues(
+	ctx context.Context,
+	tx *sql.Tx,
+	id string,
+	tableName string,
+	groupName string,
+	values map[string]any) error {
+
+	return b.register(ctx, tx, nil, b.RegisterTemplate, id, tableName, groupName, values)
+}
 
-	return wrapIfError("could not register migration", err)
+// RegisterMigrationIdempotent registers a migration in the migration table, unless it is registered already.
+func (b NamedParamsDialect) RegisterMigrationIdempotent(
+	ctx context.Context,
+	tx *sql.Tx,
+	id string,
+	tableName string,
+	groupName string) error {
+
+	if b.IdempotentRegisterTemplate == "" {
+		return ErrIdempotentRegisterUnsupported
+	}
+
+	return b.register(ctx, tx, nil, b.IdempotentRegisterTemplate, id, tableName, groupName, nil)
+}
+
+// register registers a migration in the migration table using the given template, or the given statement
+// prepared from it if not nil, and additional values.
+func (b NamedParamsDialect) register(
+	ctx context.Context,
+	tx *sql.Tx,
+	stmt *sql.Stmt,
+	template string,
+	id string,
+	tableName string,
+	groupName string,
+	values map[string]any) error {
+
+	params := []any{sql.Named("id", id), sql.Named("mgroup", groupName)}
+
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		params = append(params, sql.Named(name, values[name]))
+	}
+
+	if b.OrdinalTemplate != "" {
+		ordinal, err := nextOrdinal(ctx, tx, b.tableSQL(b.OrdinalTemplate, tableName), sql.Named("mgroup", groupName))
+
+		if err != nil {
+			return err
+		}
+
+		params = append(params, sql.Named(string(ParamNameOrdinal), ordinal))
+	}
+
+	return registerError(id, execRegister(ctx, tx, stmt, b.tableSQL(template, tableName), params))
+}
+
+// PrepareRegister prepares the statement registering migrations in the given table once, e.g. for a whole run,
+// instead of for every migration, see PreparedRegisterer.
+func (b NamedParamsDialect) PrepareRegister(ctx context.Context, db *sql.DB, tableName string) (*sql.Stmt, error) {
+	stmt, err := db.PrepareContext(ctx, b.tableSQL(b.RegisterTemplate, tableName))
+
+	return stmt, wrapIfError("could not prepare register statement", err)
+}
+
+// RegisterMigrationStmt registers a migration in the migration table like RegisterMigration, using the statement
+// prepared by PrepareRegister, that is bound to the given transaction.
+func (b NamedParamsDialect) RegisterMigrationStmt(
+	ctx context.Context,
+	tx *sql.Tx,
+	stmt *sql.Stmt,
+	id string,
+	tableName string,
+	groupName string) error {
+
+	return b.register(ctx, tx, stmt, b.RegisterTemplate, id, tableName, groupName, nil)
+}
+
+// execRegister executes the given register query, or the given statement prepared from it if not nil, in the
+// transaction.
+func execRegister(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, query string, params []any) error {
+	var err error
+
+	if stmt != nil {
+		_, err = tx.StmtContext(ctx, stmt).ExecContext(ctx, params...)
+	} else {
+		_, err = tx.ExecContext(ctx, query, params...)
+	}
+
+	return err //nolint:wrapcheck // wrapped by registerError
+}
+
+// nextOrdinal executes the given query returning the next ordinal of a migration group, within the transaction
+// registering the migration. So the ordinals follow the order of the registrations, independent of the precision
+// of the clock of the database.
+func nextOrdinal(ctx context.Context, tx *sql.Tx, query string, args ...any) (int64, error) {
+	var ordinal int64
+
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&ordinal); err != nil {
+		return 0, wrapIfError("could not get next ordinal", err)
+	}
+
+	return ordinal, nil
+}
+
+// uniqueViolationRex matches the error messages of the supported database management systems on the violation
+// of a unique or primary key constraint.
+var uniqueViolationRex = regexp.MustCompile(
+	`(?i)unique constraint|duplicate key|duplicate entry|violation of primary key|ORA-00001|SQLSTATE=23505`)
+
+// registerError wraps the error of registering a migration, naming the migration. If the error indicates that
+// the migration is already registered, ErrMigrationRegistered is wrapped additionally.
+func registerError(id string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if uniqueViolationRex.MatchString(err.Error()) {
+		return fmt.Errorf("could not register migration %q, it may already be applied: %w: %w",
+			id, ErrMigrationRegistered, err)
+	}
+
+	return fmt.Errorf("could not register migration %q: %w", id, err)
+}
+
+// RenderCreate returns the statement ensuring the existence of the migration table with the given table name,
+// without executing it.
+func (b NamedParamsDialect) RenderCreate(tableName string) (string, error) {
+	return b.renderTemplate(b.CreateTemplate, tableName)
+}
+
+// RenderApplied returns the statement getting the applied migrations from the given table, without executing it.
+func (b NamedParamsDialect) RenderApplied(tableName string) (string, error) {
+	return b.renderTemplate(b.AppliedTemplate, tableName)
+}
+
+// RenderRegister returns the statement registering a migration in the given table, without executing it.
+func (b NamedParamsDialect) RenderRegister(tableName string) (string, error) {
+	return b.renderTemplate(b.RegisterTemplate, tableName)
+}
+
+// namedRegisterParamRex matches the named parameters of the RegisterTemplate.
+var namedRegisterParamRex = regexp.MustCompile(`[:@](id|mgroup)\b`)
+
+// RenderRegisterMigration returns the statement registering the given migration in the given table, with the
+// parameters replaced by literals, without executing it.
+func (b NamedParamsDialect) RenderRegisterMigration(tableName string, id string, groupName string) (string, error) {
+	statement, err := b.renderTemplate(b.RegisterTemplate, tableName)
+
+	if err != nil {
+		return "", err
+	}
+
+	return namedRegisterParamRex.ReplaceAllStringFunc(statement, func(param string) string {
+		if param[1:] == string(ParamNameID) {
+			return quoteLiteral(id)
+		}
+
+		return quoteLiteral(groupName)
+	}), nil
+}
+
+// quoteLiteral encloses the given value in single quotes as SQL string literal, doubling enclosed single quotes.
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// renderTemplate fills the table name into the given statement template, after checking it against
+// ValidTableNameRex, or ValidQualifiedTableNameRex if the table name is quoted.
+func (b NamedParamsDialect) renderTemplate(template string, tableName string) (string, error) {
+	if !validTableName(tableName, b.QuoteTableName) {
+		return "", ErrMigrationTableNameInvalid
+	}
+
+	return b.tableSQL(template, tableName), nil
+}
+
+// IntegrityCheck verifies that the migration table contains all the columns required by DMorph. The types of
+// the columns are not checked, as they differ too much between the database management systems.
+func (b NamedParamsDialect) IntegrityCheck(ctx context.Context, db *sql.DB, tableName string) error {
+	if b.IntegrityTemplate == "" {
+		return ErrIntegrityCheckUnsupported
+	}
+
+	query, params := b.integrityQuery(tableName)
+	names, err := queryStrings(ctx, db, query, params...)
+
+	if err != nil {
+		return wrapIfError("could not get migration table columns", err)
+	}
+
+	columns := make(map[string]bool)
+
+	for _, n := range names {
+		columns[strings.ToLower(n)] = true
+	}
+
+	var missing []string
+
+	for _, c := range []string{"id", "mgroup", strings.ToLower(b.orderColumn())} {
+		if !columns[c] {
+			missing = append(missing, c)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing columns %s", ErrMigrationTableCorrupt, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// ListMigrationTables lists the tables in the database having the columns of a migration table. This is a best
+// effort to discover the migration tables of multiple programs sharing one database.
+func (b NamedParamsDialect) ListMigrationTables(ctx context.Context, db *sql.DB) ([]string, error) {
+	if b.ListTablesTemplate == "" {
+		return nil, ErrTableListingUnsupported
+	}
+
+	result, err := queryStrings(ctx, db, b.ListTablesTemplate)
+
+	return result, wrapIfError("could not list migration tables", err)
+}
+
+// ServerVersion gets the version of the database server using the VersionTemplate. If the VersionTemplate is not
+// set, ErrVersionUnsupported is returned.
+func (b NamedParamsDialect) ServerVersion(ctx context.Context, db *sql.DB) (string, error) {
+	if b.VersionTemplate == "" {
+		return "", ErrVersionUnsupported
+	}
+
+	var version string
+
+	if err := db.QueryRowContext(ctx, b.VersionTemplate).Scan(&version); err != nil {
+		return "", wrapIfError("could not get server version", err)
+	}
+
+	return version, nil
+}
+
+// queryStrings executes the given query and returns the first column of all result rows.
+func queryStrings(ctx context.Context, db *sql.DB, query string, args ...any) ([]string, error) {
+	rows, rowsErr := db.QueryContext(ctx, query, args...)
+
+	if rowsErr != nil {
+		return nil, rowsErr //nolint:wrapcheck // wrapped by the callers
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	var result []string
+	var tmp string
+	var scanErr error
+
+	for rows.Next() && scanErr == nil {
+		if scanErr = rows.Scan(&tmp); scanErr == nil {
+			result = append(result, tmp)
+		}
+	}
+
+	// partial results are never returned, so that callers cannot mistake them for complete ones
+	if err := errors.Join(rows.Err(), scanErr); err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
 // ParamName represents a named parameter for use in SQL queries or migrations.
@@ -100,6 +1007,15 @@ const (
 
 	// ParamNameMGroup represents the "mgroup" parameter used in SQL queries or migration operations.
 	ParamNameMGroup ParamName = "mgroup"
+
+	// ParamNameLimit represents the "limit" parameter, the maximum number of rows of a page.
+	ParamNameLimit ParamName = "limit"
+
+	// ParamNameOffset represents the "offset" parameter, the number of rows preceding a page.
+	ParamNameOffset ParamName = "offset"
+
+	// ParamNameOrdinal represents the "ordinal" parameter, the position of a migration in the order of application.
+	ParamNameOrdinal ParamName = "ordinal"
 )
 
 // NumberedParamsDialect extends NamedParamsDialect to support positional parameterized SQL queries.
@@ -108,6 +1024,37 @@ type NumberedParamsDialect struct {
 
 	AppliedMigrationsParamsOrder []ParamName // defines the order of parameters for retrieving applied migrations.
 	RegisterMigrationParamsOrder []ParamName // defines the order of parameters for registering a migration.
+	HistoryPageParamsOrder       []ParamName // defines the order of parameters for getting a page of the history.
+	LatestParamsOrder            []ParamName // defines the order of parameters for getting the latest migrations.
+	OrdinalParamsOrder           []ParamName // defines the order of parameters for getting the next ordinal.
+}
+
+// WithIDLength returns a copy of the dialect declaring the id column of the migration table with the given
+// length, as described for NamedParamsDialect.WithIDLength.
+func (b NumberedParamsDialect) WithIDLength(length int) (NumberedParamsDialect, error) {
+	named, err := b.NamedParamsDialect.WithIDLength(length)
+
+	b.NamedParamsDialect = named
+
+	return b, err
+}
+
+// WithOrderColumn returns a copy of the dialect ordering the applied migrations by the given column, as
+// described for NamedParamsDialect.WithOrderColumn.
+func (b NumberedParamsDialect) WithOrderColumn(column string) (NumberedParamsDialect, error) {
+	named, err := b.NamedParamsDialect.WithOrderColumn(column)
+
+	b.NamedParamsDialect = named
+
+	return b, err
+}
+
+// WithQuotedTableName returns a copy of the dialect quoting the table name itself, see
+// NamedParamsDialect.WithQuotedTableName.
+func (b NumberedParamsDialect) WithQuotedTableName() NumberedParamsDialect {
+	b.NamedParamsDialect = b.NamedParamsDialect.WithQuotedTableName()
+
+	return b
 }
 
 // EnsureMigrationTableExists ensures that the migration table, saving the applied migrations ids, exists.
@@ -122,6 +1069,27 @@ func (b NumberedParamsDialect) AppliedMigrations(
 	tableName string,
 	groupName string) ([]string, error) {
 
+	return b.appliedMigrations(ctx, db, tableName, groupName)
+}
+
+// AppliedMigrationsTx gets the already applied migrations within the given transaction, ordered by application
+// date.
+func (b NumberedParamsDialect) AppliedMigrationsTx(
+	ctx context.Context,
+	tx *sql.Tx,
+	tableName string,
+	groupName string) ([]string, error) {
+
+	return b.appliedMigrations(ctx, tx, tableName, groupName)
+}
+
+// appliedMigrations gets the already applied migrations using the given runner.
+func (b NumberedParamsDialect) appliedMigrations(
+	ctx context.Context,
+	runner statementRunner,
+	tableName string,
+	groupName string) ([]string, error) {
+
 	params := make([]any, 0, len(b.AppliedMigrationsParamsOrder))
 
 	for _, p := range b.AppliedMigrationsParamsOrder {
@@ -133,7 +1101,7 @@ func (b NumberedParamsDialect) AppliedMigrations(
 		}
 	}
 
-	rows, rowsErr := db.QueryContext(ctx, fmt.Sprintf(b.AppliedTemplate, tableName), params...)
+	rows, rowsErr := runner.QueryContext(ctx, b.tableSQL(b.AppliedTemplate, tableName), params...)
 
 	if rowsErr != nil {
 		return nil, wrapIfError("could not get applied migrations", rowsErr)
@@ -151,7 +1119,72 @@ func (b NumberedParamsDialect) AppliedMigrations(
 		}
 	}
 
-	return result, errors.Join(rows.Err(), scanErr)
+	// partial results are never returned, so that callers cannot mistake them for complete ones
+	if err := errors.Join(rows.Err(), scanErr); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// HistoryPage gets a page of the applied migrations, including the time of their application, ordered by
+// application date.
+func (b NumberedParamsDialect) HistoryPage(
+	ctx context.Context,
+	db *sql.DB,
+	tableName string,
+	groupName string,
+	limit int,
+	offset int) ([]AppliedMigration, error) {
+
+	if b.HistoryPageTemplate == "" {
+		return nil, ErrHistoryUnsupported
+	}
+
+	params := make([]any, 0, len(b.HistoryPageParamsOrder))
+
+	for _, p := range b.HistoryPageParamsOrder {
+		switch p {
+		case ParamNameMGroup:
+			params = append(params, groupName)
+		case ParamNameLimit:
+			params = append(params, limit)
+		case ParamNameOffset:
+			params = append(params, offset)
+		default:
+			return nil, fmt.Errorf("unexpected param name %v: %w", p, ErrParamNameInvalid)
+		}
+	}
+
+	return queryHistory(ctx, db, b.tableSQL(b.HistoryPageTemplate, tableName), params...)
+}
+
+// LatestMigrations gets the migrations applied last, i.e. sharing the latest timestamp. If the LatestTemplate is
+// not set, all applied migrations are returned.
+func (b NumberedParamsDialect) LatestMigrations(
+	ctx context.Context,
+	db *sql.DB,
+	tableName string,
+	groupName string) ([]string, error) {
+
+	if b.LatestTemplate == "" {
+		return b.AppliedMigrations(ctx, db, tableName, groupName)
+	}
+
+	params := make([]any, 0, len(b.LatestParamsOrder))
+
+	for _, p := range b.LatestParamsOrder {
+		switch p {
+		case ParamNameMGroup:
+			params = append(params, groupName)
+		default:
+			return nil, fmt.Errorf("unexpected param name %v: %w", p, ErrParamNameInvalid)
+		}
+	}
+
+	result, err := queryStrings(ctx, db, b.tableSQL(b.LatestTemplate, tableName), params...)
+
+	return result, wrapIfError("could not get latest migrations", err)
 }
 
 // RegisterMigration registers a migration in the migration table.
@@ -162,6 +1195,137 @@ func (b NumberedParamsDialect) RegisterMigration(
 	tableName string,
 	groupName string) error {
 
+	return b.register(ctx, tx, nil, b.RegisterTemplate, id, tableName, groupName, nil)
+}
+
+// RegisterMigrationWithValues registers a migration in the migration table, passing the given values as
+// additional parameters to the RegisterTemplate. Their positions are defined by their names in the
+// RegisterMigrationParamsOrder.
+func (b NumberedParamsDialect) RegisterMigrationWithValues(
+	ctx context.Context,
+	tx *sql.Tx,
+	id string,
+	tableName string,
+	groupName string,
+	values map[string]any) error {
+
+	return b.register(ctx, tx, nil, b.RegisterTemplate, id, tableName, groupName, values)
+}
+
+// RegisterMigrationIdempotent registers a migration in the migration table, unless it is registered already.
+func (b NumberedParamsDialect) RegisterMigrationIdempotent(
+	ctx context.Context,
+	tx *sql.Tx,
+	id string,
+	tableName string,
+	groupName string) error {
+
+	if b.IdempotentRegisterTemplate == "" {
+		return ErrIdempotentRegisterUnsupported
+	}
+
+	return b.register(ctx, tx, nil, b.IdempotentRegisterTemplate, id, tableName, groupName, nil)
+}
+
+// RegisterMigrationStmt registers a migration in the migration table like RegisterMigration, using the statement
+// prepared by PrepareRegister, that is bound to the given transaction.
+func (b NumberedParamsDialect) RegisterMigrationStmt(
+	ctx context.Context,
+	tx *sql.Tx,
+	stmt *sql.Stmt,
+	id string,
+	tableName string,
+	groupName string) error {
+
+	return b.register(ctx, tx, stmt, b.RegisterTemplate, id, tableName, groupName, nil)
+}
+
+// register registers a migration in the migration table using the given template, or the given statement
+// prepared from it if not nil, and additional values.
+func (b NumberedParamsDialect) register(
+	ctx context.Context,
+	tx *sql.Tx,
+	stmt *sql.Stmt,
+	template string,
+	id string,
+	tableName string,
+	groupName string,
+	values map[string]any) error {
+
+	if b.OrdinalTemplate != "" {
+		ordinalParams := make([]any, 0, len(b.OrdinalParamsOrder))
+
+		for _, p := range b.OrdinalParamsOrder {
+			switch p {
+			case ParamNameMGroup:
+				ordinalParams = append(ordinalParams, groupName)
+			default:
+				return fmt.Errorf("unexpected param name %v: %w", p, ErrParamNameInvalid)
+			}
+		}
+
+		ordinal, err := nextOrdinal(ctx, tx, b.tableSQL(b.OrdinalTemplate, tableName), ordinalParams...)
+
+		if err != nil {
+			return err
+		}
+
+		values = maps.Clone(values)
+
+		if values == nil {
+			values = make(map[string]any, 1)
+		}
+
+		values[string(ParamNameOrdinal)] = ordinal
+	}
+
+	params, paramsErr := b.registerParams(id, groupName, values)
+
+	if paramsErr != nil {
+		return paramsErr
+	}
+
+	return registerError(id, execRegister(ctx, tx, stmt, b.tableSQL(template, tableName), params))
+}
+
+// RenderRegisterMigration returns the statement registering the given migration in the given table, with the
+// parameters replaced by literals, without executing it.
+func (b NumberedParamsDialect) RenderRegisterMigration(tableName string, id string, groupName string) (string, error) {
+	statement, err := b.renderTemplate(b.RegisterTemplate, tableName)
+
+	if err != nil {
+		return "", err
+	}
+
+	params, paramsErr := b.registerParams(id, groupName, nil)
+
+	if paramsErr != nil {
+		return "", paramsErr
+	}
+
+	parts := strings.Split(statement, "?")
+
+	if len(parts) != len(params)+1 {
+		return "", fmt.Errorf("%w: %d placeholders for %d params", ErrParamNameInvalid, len(parts)-1, len(params))
+	}
+
+	result := strings.Builder{}
+
+	for i, part := range parts {
+		result.WriteString(part)
+
+		if i < len(params) {
+			result.WriteString(quoteLiteral(fmt.Sprint(params[i])))
+		}
+	}
+
+	return result.String(), nil
+}
+
+// registerParams returns the positional parameters for registering a migration, in the order defined by
+// RegisterMigrationParamsOrder. Parameter names other than ParamNameID and ParamNameMGroup are taken from the
+// given values.
+func (b NumberedParamsDialect) registerParams(id string, groupName string, values map[string]any) ([]any, error) {
 	params := make([]any, 0, len(b.RegisterMigrationParamsOrder))
 
 	for _, p := range b.RegisterMigrationParamsOrder {
@@ -171,11 +1335,15 @@ func (b NumberedParamsDialect) RegisterMigration(
 		case ParamNameMGroup:
 			params = append(params, groupName)
 		default:
-			return fmt.Errorf("unexpected param name %v: %w", p, ErrParamNameInvalid)
+			value, found := values[string(p)]
+
+			if !found {
+				return nil, fmt.Errorf("unexpected param name %v: %w", p, ErrParamNameInvalid)
+			}
+
+			params = append(params, value)
 		}
 	}
 
-	_, err := tx.ExecContext(ctx, fmt.Sprintf(b.RegisterTemplate, tableName), params...)
-
-	return wrapIfError("could not register migration", err)
+	return params, nil
 }
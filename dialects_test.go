@@ -4,8 +4,11 @@
 package dmorph_test
 
 import (
+	"database/sql"
 	"fmt"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -205,3 +208,1052 @@ func TestAppliedMigrationsNumberedScan(t *testing.T) {
 
 	assert.NoError(t, err, "expected no error")
 }
+
+// TestAppliedMigrationsPartialScan verifies that no partial results are returned if scanning fails midway.
+func TestAppliedMigrationsPartialScan(t *testing.T) {
+	t.Parallel()
+
+	named := dmorph.DialectSQLite()
+	named.AppliedTemplate = `SELECT 'a' AS id WHERE :mgroup <> '%s' UNION ALL SELECT NULL`
+	named.HistoryPageTemplate = `SELECT 'a', CURRENT_TIMESTAMP WHERE :mgroup <> '%s' AND :limit > :offset ` +
+		`UNION ALL SELECT NULL, NULL`
+
+	numbered := dmorph.DialectSQLiteNumbered()
+	numbered.AppliedTemplate = `SELECT 'a' AS id WHERE ? <> '%s' UNION ALL SELECT NULL`
+
+	db := openTempSQLite(t)
+
+	tests := []func() (any, error){
+		func() (any, error) { return named.AppliedMigrations(t.Context(), db, "migrations", "default") },
+		func() (any, error) { return numbered.AppliedMigrations(t.Context(), db, "migrations", "default") },
+		func() (any, error) { return named.HistoryPage(t.Context(), db, "migrations", "default", 10, 0) },
+	}
+
+	for k, test := range tests {
+		result, err := test()
+
+		require.Error(t, err, "expected scan error in test %d", k)
+		assert.Nil(t, result, "expected no partial result in test %d", k)
+	}
+}
+
+// TestQuoteIdentifier verifies that identifiers are enclosed according to the quote style of the dialect.
+func TestQuoteIdentifier(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		quote func(string) string
+		in    string
+		want  string
+	}{
+		{name: "CSVQ", quote: dmorph.DialectCSVQ().QuoteIdentifier, in: "migrations", want: "migrations"},
+		{name: "MSSQL", quote: dmorph.DialectMSSQL().QuoteIdentifier, in: "migrations", want: "[migrations]"},
+		{name: "MSSQL", quote: dmorph.DialectMSSQL().QuoteIdentifier, in: "a]b", want: "[a]]b]"},
+		{name: "MySQL", quote: dmorph.DialectMySQL().QuoteIdentifier, in: "migrations", want: "`migrations`"},
+		{name: "MySQL", quote: dmorph.DialectMySQL().QuoteIdentifier, in: "a`b", want: "`a``b`"},
+		{name: "SQLite", quote: dmorph.DialectSQLite().QuoteIdentifier, in: "migrations", want: `"migrations"`},
+		{name: "SQLite", quote: dmorph.DialectSQLite().QuoteIdentifier, in: `a"b`, want: `"a""b"`},
+		{name: "Postgres", quote: dmorph.DialectPostgres().QuoteIdentifier, in: "order", want: `"order"`},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestQuoteIdentifier-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, test.quote(test.in), "wrong quoting for %v", test.name)
+		})
+	}
+}
+
+// TestQuoteQualified verifies that each part of schema-qualified names is enclosed separately.
+func TestQuoteQualified(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		style dmorph.QuoteStyle
+		in    string
+		want  string
+	}{
+		{style: dmorph.QuoteStyleNone, in: "main.migrations", want: "main.migrations"},
+		{style: dmorph.QuoteStyleDouble, in: "migrations", want: `"migrations"`},
+		{style: dmorph.QuoteStyleDouble, in: "main.migrations", want: `"main"."migrations"`},
+		{style: dmorph.QuoteStyleBrackets, in: "dbo.migrations", want: "[dbo].[migrations]"},
+		{style: dmorph.QuoteStyleBacktick, in: "db.migrations", want: "`db`.`migrations`"},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestQuoteQualified-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, test.style.QuoteQualified(test.in), "wrong quoting")
+		})
+	}
+}
+
+// TestQuotedTableName verifies that creating, registering and reading migrations consistently use the quoted,
+// schema-qualified table name. The name of the table is a keyword, so that it fails unless quoted.
+func TestQuotedTableName(t *testing.T) {
+	t.Parallel()
+
+	const tableName = "main.order"
+
+	tests := []struct {
+		name    string
+		dialect dmorph.Dialect
+	}{
+		{name: "named", dialect: dmorph.DialectSQLite().WithQuotedTableName()},
+		{name: "numbered", dialect: dmorph.DialectSQLiteNumbered().WithQuotedTableName()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			require.NoError(t, test.dialect.EnsureMigrationTableExists(t.Context(), db, tableName),
+				"migration table could not be created")
+
+			tx, txErr := db.BeginTx(t.Context(), nil)
+			require.NoError(t, txErr, "transaction could not be started")
+
+			require.NoError(t,
+				test.dialect.RegisterMigration(t.Context(), tx, "01_base", tableName, dmorph.MigrationGroupName),
+				"migration could not be registered")
+			require.NoError(t, tx.Commit(), "transaction could not be committed")
+
+			applied, appliedErr := test.dialect.AppliedMigrations(t.Context(), db, tableName,
+				dmorph.MigrationGroupName)
+
+			require.NoError(t, appliedErr, "applied migrations could not be read")
+			assert.Equal(t, []string{"01_base"}, applied, "wrong applied migrations")
+		})
+	}
+}
+
+// TestQuotedTableNameLiteral verifies that placeholders of the table name enclosed in single quotes, like the
+// existence probes of Microsoft SQL Server and DB2, get the unqualified table name, while the table itself is
+// used quoted and qualified.
+func TestQuotedTableNameLiteral(t *testing.T) {
+	t.Parallel()
+
+	const tableName = "main.migrations"
+
+	dialect := dmorph.DialectSQLite()
+	dialect.AppliedTemplate = `
+		SELECT id
+		FROM   "%s"
+		WHERE  mgroup = :mgroup
+		AND    EXISTS (SELECT 1 FROM sqlite_master WHERE name = '%[1]s')
+		ORDER BY create_ts`
+	dialect.RegisterTemplate = `
+		INSERT INTO "%s" (id, mgroup)
+		SELECT :id, :mgroup
+		FROM   sqlite_master
+		WHERE  name = '%[1]s'`
+	dialect.IdempotentRegisterTemplate = ""
+	dialect = dialect.WithQuotedTableName()
+
+	db := openTempSQLite(t)
+	migrations := fstest.MapFS{"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")}}
+
+	for range 2 {
+		require.NoError(t, dmorph.Run(t.Context(),
+			db,
+			dmorph.WithDialect(dialect),
+			dmorph.WithTableName(tableName),
+			dmorph.WithMigrationsFromFS(migrations)),
+			"migrations could not be run")
+	}
+
+	assert.Equal(t, []string{"01_base.sql"}, appliedSQLite(t, db), "wrong applied migrations")
+
+	tests := []struct {
+		dialect dmorph.NamedParamsDialect
+		name    string
+		probe   string
+		table   string
+	}{
+		{ // Microsoft SQL Server
+			dialect: dmorph.DialectMSSQL(),
+			name:    "dbo.migrations",
+			probe:   "name = 'migrations'",
+			table:   "[dbo].[migrations]",
+		},
+		{ // DB2, using the folded name
+			dialect: dmorph.DialectDB2(),
+			name:    "DBO.MIGRATIONS",
+			probe:   "NAME = 'MIGRATIONS'",
+			table:   `"DBO"."MIGRATIONS"`,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestQuotedTableNameLiteral-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			create, createErr := test.dialect.WithQuotedTableName().RenderCreate(test.name)
+
+			require.NoError(t, createErr, "could not render create statement")
+			assert.Contains(t, create, test.probe, "wrong existence probe")
+			assert.Contains(t, create, test.table, "wrong table name")
+			assert.NotContains(t, create, "%!", "formatting error")
+		})
+	}
+}
+
+// TestQualifiedTableNameUnquoted verifies that schema-qualified table names are rejected by dialects not quoting
+// the table name.
+func TestQualifiedTableNameUnquoted(t *testing.T) {
+	t.Parallel()
+
+	_, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithTableName("main.migrations"),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")}}))
+
+	require.ErrorIs(t, morpherErr, dmorph.ErrMigrationTableNameInvalid, "expected invalid table name")
+
+	_, renderErr := dmorph.DialectSQLite().RenderCreate("main.migrations")
+
+	assert.ErrorIs(t, renderErr, dmorph.ErrMigrationTableNameInvalid, "expected invalid table name")
+}
+
+// TestIntegrityCheckQualified verifies that the schema and the name of a schema-qualified migration table are
+// bound separately, like the PostgreSQL and MySQL dialects do, so that the table is found outside of the current
+// schema.
+func TestIntegrityCheckQualified(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, dmorph.DialectPostgres().BindIntegrityNames, "PostgreSQL does not bind the names")
+	assert.True(t, dmorph.DialectMySQL().BindIntegrityNames, "MySQL does not bind the names")
+
+	db := openTempSQLite(t)
+
+	_, attachErr := db.ExecContext(t.Context(), "ATTACH DATABASE ':memory:' AS audit")
+	require.NoError(t, attachErr, "schema could not be attached")
+
+	// like the PostgreSQL dialect, unqualified names are looked up in the current schema only
+	dialect := dmorph.DialectSQLite()
+	dialect.IntegrityTemplate = `
+		SELECT name
+		FROM   pragma_table_info(?2, COALESCE(NULLIF(?1, ''), 'main'))`
+	dialect.BindIntegrityNames = true
+	dialect = dialect.WithQuotedTableName()
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dialect),
+		dmorph.WithTableName("audit.migrations"),
+		dmorph.WithIntegrityCheck(true),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")}})),
+		"migrations could not be run")
+
+	exists, existsErr := dialect.MigrationTableExists(t.Context(), db, "migrations")
+
+	require.NoError(t, existsErr, "migration table could not be probed")
+	assert.False(t, exists, "migration table found in the current schema")
+}
+
+// TestIntegrityCheck verifies the detection of missing columns in the migration table.
+func TestIntegrityCheck(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	require.NoError(t,
+		dmorph.DialectSQLite().EnsureMigrationTableExists(t.Context(), db, "complete"),
+		"migration table could not be created")
+
+	_, execErr := db.ExecContext(t.Context(), `CREATE TABLE "incomplete" (id VARCHAR(255))`)
+	require.NoError(t, execErr, "incomplete table could not be created")
+
+	require.NoError(t,
+		dmorph.DialectSQLite().IntegrityCheck(t.Context(), db, "complete"),
+		"expected complete table to pass")
+
+	err := dmorph.DialectSQLiteNumbered().IntegrityCheck(t.Context(), db, "incomplete")
+
+	require.ErrorIs(t, err, dmorph.ErrMigrationTableCorrupt, "expected corrupt table")
+	assert.ErrorContains(t, err, "mgroup, create_ts", "missing columns not listed")
+
+	assert.ErrorIs(t,
+		dmorph.DialectCSVQ().IntegrityCheck(t.Context(), db, "complete"),
+		dmorph.ErrIntegrityCheckUnsupported,
+		"expected unsupported integrity check")
+
+	dialect := dmorph.DialectSQLite()
+	dialect.IntegrityTemplate = "utter nonsense 3"
+
+	assert.Error(t, dialect.IntegrityCheck(t.Context(), db, "complete"), "expected query error")
+}
+
+// TestRenderTemplates verifies that the statement templates are rendered with the table name filled in.
+func TestRenderTemplates(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		dialect dmorph.NamedParamsDialect
+	}{
+		{name: "CSVQ", dialect: dmorph.DialectCSVQ()},
+		{name: "DB2", dialect: dmorph.DialectDB2()},
+		{name: "MSSQL", dialect: dmorph.DialectMSSQL()},
+		{name: "MySQL", dialect: dmorph.DialectMySQL().NamedParamsDialect},
+		{name: "Oracle", dialect: dmorph.DialectOracle()},
+		{name: "Postgres", dialect: dmorph.DialectPostgres()},
+		{name: "SQLite", dialect: dmorph.DialectSQLite()},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestRenderTemplates-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			for _, render := range []func(string) (string, error){
+				test.dialect.RenderCreate,
+				test.dialect.RenderApplied,
+				test.dialect.RenderRegister,
+			} {
+				statement, err := render("dimorphodon")
+
+				require.NoError(t, err, "could not render statement for %v", test.name)
+				assert.Contains(t, statement, "dimorphodon", "table name missing for %v", test.name)
+				assert.NotContains(t, statement, "%!", "formatting error for %v", test.name)
+			}
+
+			_, err := test.dialect.RenderCreate("di/mor/pho/don")
+
+			assert.ErrorIs(t, err, dmorph.ErrMigrationTableNameInvalid, "invalid name accepted for %v", test.name)
+		})
+	}
+}
+
+// TestEnsureMigrationTableExistsWithComment verifies that the comment is set using the CommentTemplate.
+func TestEnsureMigrationTableExistsWithComment(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	// SQLite does not support table comments, so the comment is saved in a separate table
+	dialect := dmorph.DialectSQLite()
+	dialect.CommentTemplate = `CREATE TABLE "%s_comment" AS SELECT '%s' AS comment`
+
+	require.NoError(t,
+		dialect.EnsureMigrationTableExistsWithComment(t.Context(), db, "migrations", "owned by 'ops'"),
+		"migration table could not be created")
+
+	var comment string
+
+	require.NoError(t, db.QueryRowContext(t.Context(), `SELECT comment FROM "migrations_comment"`).Scan(&comment))
+	assert.Equal(t, "owned by 'ops'", comment, "comment was not set")
+
+	dialect.CommentTemplate = "utter nonsense 4"
+
+	assert.Error(t,
+		dialect.EnsureMigrationTableExistsWithComment(t.Context(), db, "migrations", "comment"),
+		"expected error on invalid comment template")
+
+	assert.NoError(t,
+		dialect.EnsureMigrationTableExistsWithComment(t.Context(), db, "migrations", ""),
+		"empty comment should not be set")
+}
+
+// TestEnsureMigrationTableExistsNonTransactional verifies the creation of the migration table without a
+// transaction, as done for databases that implicitly commit DDL statements.
+func TestEnsureMigrationTableExistsNonTransactional(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	dialect := dmorph.DialectSQLite()
+	dialect.NonTransactionalDDL = true
+	dialect.CommentTemplate = `CREATE TABLE "%s_comment" AS SELECT '%s' AS comment`
+
+	require.NoError(t,
+		dialect.EnsureMigrationTableExistsWithComment(t.Context(), db, "migrations", "comment"),
+		"migration table could not be created")
+
+	require.NoError(t,
+		dialect.EnsureMigrationTableExists(t.Context(), db, "migrations"),
+		"existing migration table should be accepted")
+
+	require.NoError(t,
+		dialect.IntegrityCheck(t.Context(), db, "migrations"),
+		"migration table is incomplete")
+
+	dialect.CreateTemplate = "utter nonsense 5"
+
+	assert.Error(t,
+		dialect.EnsureMigrationTableExists(t.Context(), db, "migrations"),
+		"expected error on invalid create template")
+
+	assert.True(t, dmorph.DialectOracle().NonTransactionalDDL, "Oracle commits DDL implicitly")
+}
+
+// TestEnsureMigrationTableExistsDrainResults verifies the creation of the migration table by statements whose
+// results are drained, as done for the procedural blocks of Oracle and DB2.
+func TestEnsureMigrationTableExistsDrainResults(t *testing.T) {
+	t.Parallel()
+
+	tests := []bool{false, true}
+
+	for k, nonTransactional := range tests {
+		t.Run(fmt.Sprintf("TestEnsureMigrationTableExistsDrainResults-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+			db.SetMaxOpenConns(1)
+
+			dialect := dmorph.DialectSQLite()
+			dialect.DrainResults = true
+			dialect.NonTransactionalDDL = nonTransactional
+			dialect.CommentTemplate = `SELECT '%s', '%s' UNION ALL SELECT 'more', 'results'`
+
+			for range 2 {
+				require.NoError(t,
+					dialect.EnsureMigrationTableExistsWithComment(t.Context(), db, "migrations", "comment"),
+					"migration table could not be created")
+			}
+
+			_, appliedErr := dialect.AppliedMigrations(t.Context(), db, "migrations", dmorph.MigrationGroupName)
+
+			require.NoError(t, appliedErr, "expected connection to be usable")
+
+			dialect.CreateTemplate = "utter nonsense 5"
+
+			assert.Error(t,
+				dialect.EnsureMigrationTableExists(t.Context(), db, "migrations"),
+				"expected error on invalid create template")
+		})
+	}
+
+	assert.True(t, dmorph.DialectOracle().DrainResults, "Oracle may return results for procedural blocks")
+	assert.True(t, dmorph.DialectDB2().DrainResults, "DB2 may return results for procedural blocks")
+}
+
+// TestListMigrationTables verifies the discovery of migration tables.
+func TestListMigrationTables(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	for _, name := range []string{"migrations", "app_migrations"} {
+		require.NoError(t,
+			dmorph.DialectSQLite().EnsureMigrationTableExists(t.Context(), db, name),
+			"migration table could not be created")
+	}
+
+	_, execErr := db.ExecContext(t.Context(), `CREATE TABLE "other" (id VARCHAR(255), create_ts TIMESTAMP)`)
+	require.NoError(t, execErr, "other table could not be created")
+
+	tables, err := dmorph.DialectSQLite().ListMigrationTables(t.Context(), db)
+
+	require.NoError(t, err, "migration tables could not be listed")
+	assert.Equal(t, []string{"app_migrations", "migrations"}, tables, "wrong migration tables")
+
+	_, err = dmorph.DialectCSVQ().ListMigrationTables(t.Context(), db)
+
+	require.ErrorIs(t, err, dmorph.ErrTableListingUnsupported, "expected unsupported table listing")
+
+	dialect := dmorph.DialectSQLite()
+	dialect.ListTablesTemplate = "utter nonsense 6"
+
+	_, err = dialect.ListMigrationTables(t.Context(), db)
+
+	assert.Error(t, err, "expected query error")
+}
+
+// TestRegisterMigrationDuplicate verifies that registering the same migration twice results in an error naming
+// the migration.
+func TestRegisterMigrationDuplicate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		dialect dmorph.Dialect
+	}{
+		{name: "SQLite", dialect: dmorph.DialectSQLite()},
+		{name: "SQLiteNumbered", dialect: dmorph.DialectSQLiteNumbered()},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestRegisterMigrationDuplicate-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			require.NoError(t, test.dialect.EnsureMigrationTableExists(t.Context(), db, "migrations"))
+
+			tx, txErr := db.BeginTx(t.Context(), nil)
+
+			require.NoError(t, txErr, "expected no tx error")
+
+			defer func() { _ = tx.Rollback() }()
+
+			require.NoError(t,
+				test.dialect.RegisterMigration(t.Context(), tx, "01_base", "migrations", "default"),
+				"first registration failed for %v", test.name)
+
+			err := test.dialect.RegisterMigration(t.Context(), tx, "01_base", "migrations", "default")
+
+			require.ErrorIs(t, err, dmorph.ErrMigrationRegistered, "expected duplicate error for %v", test.name)
+			assert.ErrorContains(t, err, `"01_base"`, "migration not named for %v", test.name)
+		})
+	}
+}
+
+// TestRegisterMigrationIdempotent verifies that registering the same migration twice succeeds in idempotent mode.
+func TestRegisterMigrationIdempotent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		dialect interface {
+			dmorph.Dialect
+			dmorph.IdempotentRegisterer
+		}
+	}{
+		{name: "SQLite", dialect: dmorph.DialectSQLite()},
+		{name: "SQLiteNumbered", dialect: dmorph.DialectSQLiteNumbered()},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestRegisterMigrationIdempotent-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			require.NoError(t, test.dialect.EnsureMigrationTableExists(t.Context(), db, "migrations"))
+
+			tx, txErr := db.BeginTx(t.Context(), nil)
+
+			require.NoError(t, txErr, "expected no tx error")
+
+			defer func() { _ = tx.Rollback() }()
+
+			for range 2 {
+				require.NoError(t,
+					test.dialect.RegisterMigrationIdempotent(t.Context(), tx, "01_base", "migrations", "default"),
+					"registration failed for %v", test.name)
+			}
+
+			require.NoError(t, tx.Commit(), "commit failed for %v", test.name)
+
+			applied, appliedErr := test.dialect.AppliedMigrations(t.Context(), db, "migrations", "default")
+
+			require.NoError(t, appliedErr, "applied migrations could not be read for %v", test.name)
+			assert.Equal(t, []string{"01_base"}, applied, "wrong registrations for %v", test.name)
+		})
+	}
+}
+
+// TestRegisterMigrationIdempotentUnsupported verifies that dialects without idempotent register template
+// report it.
+func TestRegisterMigrationIdempotentUnsupported(t *testing.T) {
+	t.Parallel()
+
+	assert.ErrorIs(t,
+		dmorph.DialectCSVQ().RegisterMigrationIdempotent(t.Context(), nil, "01_base", "migrations", "default"),
+		dmorph.ErrIdempotentRegisterUnsupported)
+}
+
+// TestMySQLRegisterPositional verifies that the MySQL dialect registers migrations using positional parameters
+// only, as the common MySQL drivers do not support named parameters.
+func TestMySQLRegisterPositional(t *testing.T) {
+	t.Parallel()
+
+	dialect := dmorph.DialectMySQL()
+
+	for _, template := range []string{dialect.RegisterTemplate, dialect.IdempotentRegisterTemplate} {
+		assert.Equal(t, len(dialect.RegisterMigrationParamsOrder), strings.Count(template, "?"),
+			"placeholders do not match parameters in %q", template)
+		assert.NotContains(t, template, ":", "named parameter in %q", template)
+		assert.NotContains(t, template, "@", "named parameter in %q", template)
+	}
+
+	params, paramsErr := dialect.TregisterParams("01_base", "default")
+
+	require.NoError(t, paramsErr, "parameters could not be constructed")
+	assert.Equal(t, []any{"01_base", "default"}, params, "wrong parameters")
+
+	dialect.RegisterMigrationParamsOrder = append(dialect.RegisterMigrationParamsOrder, dmorph.ParamName("x"))
+
+	_, paramsErr = dialect.TregisterParams("01_base", "default")
+
+	assert.ErrorIs(t, paramsErr, dmorph.ErrParamNameInvalid, "invalid parameter not reported")
+}
+
+// TestEnsureMigrationTableVerified verifies that a CreateTemplate ignoring the requested table name is detected.
+func TestEnsureMigrationTableVerified(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	dialect := dmorph.DialectSQLite()
+	dialect.CreateTemplate = "CREATE TABLE IF NOT EXISTS other (id INTEGER) -- %s"
+
+	err := dialect.EnsureMigrationTableExists(t.Context(), db, "migrations")
+
+	require.ErrorIs(t, err, dmorph.ErrNoMigrationTable, "wrong table not detected")
+	assert.ErrorContains(t, err, "migrations", "table not named")
+
+	exists, existsErr := dmorph.DialectSQLite().MigrationTableExists(t.Context(), db, "other")
+
+	require.NoError(t, existsErr, "existence could not be checked")
+	assert.True(t, exists, "table not found")
+
+	_, existsErr = dmorph.DialectCSVQ().MigrationTableExists(t.Context(), db, "other")
+
+	assert.ErrorIs(t, existsErr, dmorph.ErrIntegrityCheckUnsupported, "missing template not reported")
+}
+
+// TestDialectWithIDLength verifies that the id column of the included dialects can be resized within the limits
+// of the database.
+func TestDialectWithIDLength(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		dialect func(length int) (dmorph.NamedParamsDialect, error)
+		length  int
+		wantErr bool
+	}{
+		{dialect: dmorph.DialectSQLite().WithIDLength, length: 1024},
+		{dialect: dmorph.DialectPostgres().WithIDLength, length: 1024},
+		{dialect: dmorph.DialectMSSQL().WithIDLength, length: 400},
+		{dialect: dmorph.DialectOracle().WithIDLength, length: 1024},
+		{dialect: dmorph.DialectDB2().WithIDLength, length: 1024},
+		{dialect: dmorph.DialectSQLite().WithIDLength, length: 0, wantErr: true},
+		{
+			dialect: func(length int) (dmorph.NamedParamsDialect, error) {
+				d, err := dmorph.DialectMySQL().WithIDLength(length)
+
+				return d.NamedParamsDialect, err
+			},
+			length: 512,
+		},
+		{
+			dialect: func(length int) (dmorph.NamedParamsDialect, error) {
+				d, err := dmorph.DialectMySQL().WithIDLength(length)
+
+				return d.NamedParamsDialect, err
+			},
+			length:  513,
+			wantErr: true,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestDialectWithIDLength-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			d, err := test.dialect(test.length)
+
+			if test.wantErr {
+				assert.ErrorIs(t, err, dmorph.ErrIDLengthInvalid, "expected invalid length")
+
+				return
+			}
+
+			require.NoError(t, err, "expected no error")
+			assert.Equal(t, test.length, d.KeyLength(), "expected length to be declared")
+			assert.Contains(t, d.CreateTemplate, fmt.Sprintf("(%d)", test.length), "expected id column resized")
+			assert.Equal(t, 1, strings.Count(d.CreateTemplate, fmt.Sprintf("(%d)", test.length)),
+				"expected only the id column resized")
+		})
+	}
+}
+
+// TestServerVersion verifies that the server version is reported.
+func TestServerVersion(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	version, err := dmorph.DialectSQLite().ServerVersion(t.Context(), db)
+
+	require.NoError(t, err, "server version could not be read")
+	assert.NotEmpty(t, version, "empty server version")
+
+	version, err = dmorph.DialectSQLiteNumbered().ServerVersion(t.Context(), db)
+
+	require.NoError(t, err, "server version could not be read")
+	assert.NotEmpty(t, version, "empty server version")
+
+	dialect := dmorph.DialectSQLite()
+	dialect.VersionTemplate = ""
+
+	_, err = dialect.ServerVersion(t.Context(), db)
+
+	require.ErrorIs(t, err, dmorph.ErrVersionUnsupported, "expected unsupported server version")
+
+	dialect.VersionTemplate = "utter nonsense 7"
+
+	_, err = dialect.ServerVersion(t.Context(), db)
+
+	assert.Error(t, err, "expected query error")
+}
+
+// TestOrdinal verifies that migrations registered with the same timestamp are ordered by their ordinal, while
+// ordering by the timestamp and id mixes them up.
+func TestOrdinal(t *testing.T) {
+	t.Parallel()
+
+	ordinal := dmorph.DialectSQLite()
+	ordinal.CreateTemplate = `
+		CREATE TABLE IF NOT EXISTS "%s" (
+			id        VARCHAR(255) NOT NULL,
+			mgroup    VARCHAR(255) NOT NULL,
+			ordinal   INTEGER      NOT NULL,
+			create_ts TIMESTAMP DEFAULT '2000-01-01 00:00:00',
+			PRIMARY KEY (id, mgroup)
+		)`
+	ordinal.RegisterTemplate = `INSERT INTO "%s" (id, mgroup, ordinal) VALUES(:id, :mgroup, :ordinal)`
+	ordinal.OrdinalTemplate = `SELECT COALESCE(MAX(ordinal), 0) + 1 FROM "%s" WHERE mgroup = :mgroup`
+	ordinal.AppliedTemplate = `SELECT id FROM "%s" WHERE mgroup = :mgroup ORDER BY ordinal ASC`
+
+	ordinalNumbered := dmorph.DialectSQLiteNumbered()
+	ordinalNumbered.CreateTemplate = ordinal.CreateTemplate
+	ordinalNumbered.RegisterTemplate = `INSERT INTO "%s" (id, mgroup, ordinal) VALUES(?, ?, ?)`
+	ordinalNumbered.RegisterMigrationParamsOrder = []dmorph.ParamName{
+		dmorph.ParamNameID, dmorph.ParamNameMGroup, dmorph.ParamNameOrdinal,
+	}
+	ordinalNumbered.OrdinalTemplate = `SELECT COALESCE(MAX(ordinal), 0) + 1 FROM "%s" WHERE mgroup = ?`
+	ordinalNumbered.OrdinalParamsOrder = []dmorph.ParamName{dmorph.ParamNameMGroup}
+	ordinalNumbered.AppliedTemplate = `SELECT id FROM "%s" WHERE mgroup = ? ORDER BY ordinal ASC`
+
+	clockOnly := ordinal
+	clockOnly.OrdinalTemplate = ""
+	clockOnly.RegisterTemplate = `INSERT INTO "%s" (id, mgroup, ordinal) VALUES(:id, :mgroup, 0)`
+	clockOnly.AppliedTemplate = `SELECT id FROM "%s" WHERE mgroup = :mgroup ORDER BY create_ts ASC, id ASC`
+
+	tests := []struct {
+		dialect dmorph.Dialect
+		wantErr error
+	}{
+		{dialect: ordinal},
+		{dialect: ordinalNumbered},
+		{dialect: clockOnly, wantErr: dmorph.ErrMigrationsUnsorted},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestOrdinal-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			morpher, morpherErr := dmorph.NewMorpher(
+				dmorph.WithDialect(test.dialect),
+				dmorph.WithMigrationKeyProperties(dmorph.MigrationKeySemVerPrefix()),
+				dmorph.WithMigrations(oneMigration{key: "v2.0.0_a"}, oneMigration{key: "v10.0.0_b"}))
+
+			require.NoError(t, morpherErr, "morpher could not be created")
+			require.NoError(t, morpher.Run(t.Context(), db), "migrations could not be run")
+
+			if test.wantErr != nil {
+				assert.ErrorIs(t, morpher.Run(t.Context(), db), test.wantErr, "expected order by id to fail")
+
+				return
+			}
+
+			require.NoError(t, morpher.Run(t.Context(), db), "migrations not ordered by ordinal")
+
+			applied, appliedErr := test.dialect.AppliedMigrations(t.Context(),
+				db, dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+			require.NoError(t, appliedErr, "applied migrations could not be read")
+			assert.Equal(t, []string{"v2.0.0_a", "v10.0.0_b"}, applied, "wrong order of applied migrations")
+		})
+	}
+}
+
+// TestWithOrderColumn verifies that the migration table is created, ordered and checked using a custom order
+// column, and that invalid columns are rejected.
+func TestWithOrderColumn(t *testing.T) {
+	t.Parallel()
+
+	named, namedErr := dmorph.DialectSQLite().WithOrderColumn("applied_at")
+
+	require.NoError(t, namedErr, "order column could not be set")
+
+	numbered, numberedErr := dmorph.DialectSQLiteNumbered().WithOrderColumn("applied_at")
+
+	require.NoError(t, numberedErr, "order column could not be set")
+
+	tests := []struct {
+		dialect interface {
+			dmorph.Dialect
+			dmorph.IntegrityChecker
+		}
+	}{
+		{dialect: named},
+		{dialect: numbered},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestWithOrderColumn-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			require.NoError(t, test.dialect.EnsureMigrationTableExists(t.Context(), db, dmorph.MigrationTableName),
+				"migration table could not be created")
+
+			// registered in reverse order, so that the order of insertion differs from the order column
+			_, insertErr := db.ExecContext(t.Context(), `
+				INSERT INTO "`+dmorph.MigrationTableName+`" (id, mgroup, applied_at) VALUES
+					('02_second', '`+dmorph.MigrationGroupName+`', '2001-01-01 00:00:00'),
+					('01_first',  '`+dmorph.MigrationGroupName+`', '2000-01-01 00:00:00')`)
+
+			require.NoError(t, insertErr, "migrations could not be registered")
+
+			applied, appliedErr := test.dialect.AppliedMigrations(t.Context(),
+				db, dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+			require.NoError(t, appliedErr, "applied migrations could not be read")
+			assert.Equal(t, []string{"01_first", "02_second"}, applied, "not ordered by the order column")
+			assert.NoError(t, test.dialect.IntegrityCheck(t.Context(), db, dmorph.MigrationTableName),
+				"order column not accepted by the integrity check")
+
+			runErr := dmorph.Run(t.Context(),
+				db,
+				dmorph.WithDialect(test.dialect),
+				dmorph.WithMigrations(
+					oneMigration{key: "01_first"},
+					oneMigration{key: "02_second"},
+					oneMigration{key: "03_third"}))
+
+			require.NoError(t, runErr, "migrations could not be run")
+
+			applied, appliedErr = test.dialect.AppliedMigrations(t.Context(),
+				db, dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+			require.NoError(t, appliedErr, "applied migrations could not be read")
+			assert.Equal(t, []string{"01_first", "02_second", "03_third"}, applied, "wrong applied migrations")
+		})
+	}
+
+	_, invalidErr := dmorph.DialectSQLite().WithOrderColumn("applied_at; DROP TABLE x")
+
+	assert.ErrorIs(t, invalidErr, dmorph.ErrOrderColumnInvalid, "expected invalid order column")
+}
+
+// TestValidateTableName verifies that table names exceeding the limits of the database or being reserved words
+// are rejected before running.
+func TestValidateTableName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		dialect dmorph.TableNameValidator
+		name    string
+		wantErr error
+	}{
+		{ // maximum length of MySQL
+			dialect: dmorph.DialectMySQL(),
+			name:    strings.Repeat("m", 64),
+		},
+		{ // exceeding the maximum length of MySQL
+			dialect: dmorph.DialectMySQL(),
+			name:    strings.Repeat("m", 65),
+			wantErr: dmorph.ErrMigrationTableNameInvalid,
+		},
+		{ // exceeding the maximum length of PostgreSQL
+			dialect: dmorph.DialectPostgres(),
+			name:    strings.Repeat("m", 64),
+			wantErr: dmorph.ErrMigrationTableNameInvalid,
+		},
+		{ // no maximum length for SQLite
+			dialect: dmorph.DialectSQLite(),
+			name:    strings.Repeat("m", 256),
+		},
+		{ // reserved word
+			dialect: dmorph.DialectSQLite(),
+			name:    "Order",
+			wantErr: dmorph.ErrMigrationTableNameInvalid,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestValidateTableName-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			assert.ErrorIs(t, test.dialect.ValidateTableName(test.name), test.wantErr, "unexpected validation result")
+		})
+	}
+
+	_, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectMySQL()),
+		dmorph.WithTableName(strings.Repeat("m", 65)),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	assert.ErrorIs(t, morpherErr, dmorph.ErrMigrationTableNameInvalid, "over-length table name not rejected")
+}
+
+// TestFoldTableName verifies that the dialects quote the table name preserving its case, and that
+// WithFoldTableName normalizes it to the case of unquoted identifiers of the database.
+func TestFoldTableName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		dialect    dmorph.Dialect
+		quote      func(name string) string
+		wantQuoted string
+		wantFolded string
+	}{
+		{ // folds unquoted identifiers to lower case
+			dialect:    dmorph.DialectPostgres(),
+			quote:      dmorph.DialectPostgres().QuoteIdentifier,
+			wantQuoted: `"Migrations"`,
+			wantFolded: "migrations",
+		},
+		{ // folds unquoted identifiers to upper case
+			dialect:    dmorph.DialectOracle(),
+			quote:      dmorph.DialectOracle().QuoteIdentifier,
+			wantQuoted: `"Migrations"`,
+			wantFolded: "MIGRATIONS",
+		},
+		{ // folds unquoted identifiers to upper case
+			dialect:    dmorph.DialectDB2(),
+			quote:      dmorph.DialectDB2().QuoteIdentifier,
+			wantQuoted: `"Migrations"`,
+			wantFolded: "MIGRATIONS",
+		},
+		{ // compares identifiers case-insensitively, depending on the configuration
+			dialect:    dmorph.DialectMySQL(),
+			quote:      dmorph.DialectMySQL().QuoteIdentifier,
+			wantQuoted: "`Migrations`",
+			wantFolded: "Migrations",
+		},
+		{ // compares identifiers case-insensitively
+			dialect:    dmorph.DialectMSSQL(),
+			quote:      dmorph.DialectMSSQL().QuoteIdentifier,
+			wantQuoted: "[Migrations]",
+			wantFolded: "Migrations",
+		},
+		{ // compares identifiers case-insensitively
+			dialect:    dmorph.DialectSQLite(),
+			quote:      dmorph.DialectSQLite().QuoteIdentifier,
+			wantQuoted: `"Migrations"`,
+			wantFolded: "Migrations",
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestFoldTableName-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.wantQuoted, test.quote("Migrations"), "case not preserved")
+
+			unfolded, unfoldedErr := dmorph.NewMorpher(
+				dmorph.WithDialect(test.dialect),
+				dmorph.WithTableName("Migrations"),
+				dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+			require.NoError(t, unfoldedErr, "morpher could not be created")
+			assert.Equal(t, "Migrations", unfolded.TableName, "table name folded without option")
+
+			folded, foldedErr := dmorph.NewMorpher(
+				dmorph.WithFoldTableName(true),
+				dmorph.WithTableName("Migrations"),
+				dmorph.WithDialect(test.dialect),
+				dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+			require.NoError(t, foldedErr, "morpher could not be created")
+			assert.Equal(t, test.wantFolded, folded.TableName, "wrong folded table name")
+		})
+	}
+}
+
+// TestRegisterMigrationStmt verifies that registering migrations using the prepared statement behaves like the
+// registration without it, including the detection of already registered migrations.
+func TestRegisterMigrationStmt(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		dialect interface {
+			dmorph.Dialect
+			dmorph.PreparedRegisterer
+		}
+	}{
+		{dialect: dmorph.DialectSQLite()},
+		{dialect: dmorph.DialectSQLiteNumbered()},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestRegisterMigrationStmt-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			require.NoError(t, test.dialect.EnsureMigrationTableExists(t.Context(), db, dmorph.MigrationTableName),
+				"migration table could not be created")
+
+			stmt, prepareErr := test.dialect.PrepareRegister(t.Context(), db, dmorph.MigrationTableName)
+
+			require.NoError(t, prepareErr, "register statement could not be prepared")
+
+			defer func() { _ = stmt.Close() }()
+
+			for _, key := range []string{"01_first", "02_second", "01_first"} {
+				tx, txErr := db.BeginTx(t.Context(), nil)
+
+				require.NoError(t, txErr, "transaction could not be started")
+
+				registerErr := test.dialect.RegisterMigrationStmt(t.Context(), tx, stmt,
+					key, dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+				if registerErr != nil {
+					_ = tx.Rollback()
+
+					assert.ErrorIs(t, registerErr, dmorph.ErrMigrationRegistered, "expected registered error")
+
+					continue
+				}
+
+				require.NoError(t, tx.Commit(), "transaction could not be committed")
+			}
+
+			assert.Equal(t, []string{"01_first", "02_second"}, appliedSQLite(t, db), "wrong registered migrations")
+		})
+	}
+}
+
+// BenchmarkRegisterMigration measures registering migrations, each in its own transaction, with the statement
+// prepared for every migration and prepared once.
+func BenchmarkRegisterMigration(b *testing.B) {
+	for _, prepared := range []bool{false, true} {
+		b.Run(fmt.Sprintf("prepared-%v", prepared), func(b *testing.B) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			require.NoError(b, err, "DB could not be opened")
+			b.Cleanup(func() { _ = db.Close() })
+
+			db.SetMaxOpenConns(1)
+
+			dialect := dmorph.DialectSQLite()
+
+			require.NoError(b, dialect.EnsureMigrationTableExists(b.Context(), db, dmorph.MigrationTableName))
+
+			stmt, err := dialect.PrepareRegister(b.Context(), db, dmorph.MigrationTableName)
+			require.NoError(b, err, "register statement could not be prepared")
+			b.Cleanup(func() { _ = stmt.Close() })
+
+			for i := 0; b.Loop(); i++ {
+				tx, txErr := db.BeginTx(b.Context(), nil)
+				require.NoError(b, txErr, "transaction could not be started")
+
+				key := fmt.Sprintf("%010d", i)
+
+				if prepared {
+					err = dialect.RegisterMigrationStmt(b.Context(), tx, stmt,
+						key, dmorph.MigrationTableName, dmorph.MigrationGroupName)
+				} else {
+					err = dialect.RegisterMigration(b.Context(), tx, key, dmorph.MigrationTableName,
+						dmorph.MigrationGroupName)
+				}
+
+				require.NoError(b, err, "migration could not be registered")
+				require.NoError(b, tx.Commit(), "transaction could not be committed")
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// directivePrefix introduces a directive inside a comment, e.g. `-- dmorph:env test`.
+const directivePrefix = "dmorph:"
+
+// directiveEnv declares the tags of a migration, see TaggedMigration.
+const directiveEnv = "env"
+
+// directiveRequires declares the migrations a migration depends on, see DependentMigration.
+const directiveRequires = "requires"
+
+// directiveIsolation declares the isolation level of the transaction of a migration, see IsolatedMigration.
+const directiveIsolation = "isolation"
+
+// directiveMinVersion declares the minimum server version supported by a migration, see VersionedMigration.
+const directiveMinVersion = "min-version"
+
+// directiveMaxVersion declares the maximum server version supported by a migration, see VersionedMigration.
+const directiveMaxVersion = "max-version"
+
+// directiveCategory declares the category of a migration, see CategorizedMigration.
+const directiveCategory = "category"
+
+// directiveLog declares a diagnostic query, whose scalar result is logged after applying a migration.
+const directiveLog = "log"
+
+// leadingLineRex matches lines that may precede a statement, i.e. empty lines and comments. The content of the
+// comment is captured.
+var leadingLineRex = regexp.MustCompile(`^\s*(?:--\s*(.*?))?\s*$`)
+
+// directive is an instruction to DMorph, given in a comment preceding a statement.
+type directive struct {
+	Name string // name of the directive, e.g. "env"
+	Args string // arguments of the directive, not further parsed
+}
+
+// parseLeadingLine checks if the given line may precede a statement, being empty or a comment. If the comment
+// contains a directive, it is returned. Comments without directive and empty lines are to be discarded.
+func parseLeadingLine(line string) (bool, *directive) {
+	match := leadingLineRex.FindStringSubmatch(line)
+
+	if match == nil {
+		return false, nil
+	}
+
+	content, isDirective := strings.CutPrefix(match[1], directivePrefix)
+
+	if !isDirective {
+		return true, nil
+	}
+
+	name, args := content, ""
+
+	if i := strings.IndexFunc(content, unicode.IsSpace); i >= 0 {
+		name, args = content[:i], strings.TrimSpace(content[i:])
+	}
+
+	return true, &directive{Name: name, Args: args}
+}
+
+// splitDirectiveArgs splits the arguments of a directive separated by whitespace or commas.
+func splitDirectiveArgs(args string) []string {
+	return strings.FieldsFunc(args, func(c rune) bool {
+		return c == ',' || unicode.IsSpace(c)
+	})
+}
+
+// parseIsolationLevel returns the isolation level of the given name, as returned by sql.IsolationLevel.String.
+// The name is case-insensitive, words may also be separated by underscores or hyphens.
+func parseIsolationLevel(name string) (sql.IsolationLevel, error) {
+	name = strings.NewReplacer("_", " ", "-", " ").Replace(name)
+
+	for level := sql.LevelDefault; level <= sql.LevelLinearizable; level++ {
+		if strings.EqualFold(level.String(), name) {
+			return level, nil
+		}
+	}
+
+	return sql.LevelDefault, fmt.Errorf("%w: %s", ErrIsolationLevelUnknown, name)
+}
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// TestParseLeadingLine verifies the classification of lines preceding a statement and the recognition of
+// directives.
+func TestParseLeadingLine(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		line        string
+		wantLeading bool
+		wantName    string
+		wantArgs    string
+	}{
+		{line: "", wantLeading: true},
+		{line: "  \t", wantLeading: true},
+		{line: "-- plain comment", wantLeading: true},
+		{line: "   --", wantLeading: true},
+		{line: "-- SET search_path = x", wantLeading: true},
+		{line: "CREATE TABLE t0 (id INTEGER)", wantLeading: false},
+		{line: "SELECT 1 -- trailing comment", wantLeading: false},
+		{line: "-- dmorph:env test, dev", wantLeading: true, wantName: "env", wantArgs: "test, dev"},
+		{line: "  --dmorph:env   prod  ", wantLeading: true, wantName: "env", wantArgs: "prod"},
+		{line: "-- dmorph:noargs", wantLeading: true, wantName: "noargs"},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestParseLeadingLine-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			leading, d := dmorph.TparseLeadingLine(test.line)
+
+			assert.Equal(t, test.wantLeading, leading, "wrong classification of %q", test.line)
+
+			if test.wantName == "" {
+				assert.Nil(t, d, "unexpected directive in %q", test.line)
+
+				return
+			}
+
+			require.NotNil(t, d, "missing directive in %q", test.line)
+			assert.Equal(t, test.wantName, d.Name, "wrong directive name in %q", test.line)
+			assert.Equal(t, test.wantArgs, d.Args, "wrong directive args in %q", test.line)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build integration
+
+// The integration harness verifies the DMorph dialects against real database management systems. The databases
+// are configured using environment variables, for each Target `DMORPH_<NAME>_DRIVER` giving the name of the
+// registered database/sql driver and `DMORPH_<NAME>_DSN` giving the data source name. Targets that are not
+// configured, or whose driver is not registered, are skipped, as are all targets in short mode.
+
+package dmorphtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// Target describes a database management system to run the scenarios against.
+type Target struct {
+	Name    string         // name of the target, used for the environment variables
+	Dialect dmorph.Dialect // dialect to use for the target
+}
+
+// Targets returns the database management systems with DMorph dialects that can be run in containers.
+func Targets() []Target {
+	return []Target{
+		{Name: "DB2", Dialect: dmorph.DialectDB2()},
+		{Name: "MSSQL", Dialect: dmorph.DialectMSSQL()},
+		{Name: "MySQL", Dialect: dmorph.DialectMySQL()},
+		{Name: "Oracle", Dialect: dmorph.DialectOracle()},
+		{Name: "Postgres", Dialect: dmorph.DialectPostgres()},
+		{Name: "SQLite", Dialect: dmorph.DialectSQLite()},
+	}
+}
+
+// Open opens the database of the given target as configured in the environment. The test or benchmark is
+// skipped if the target is not configured, its driver is not registered, or the tests run in short mode. The
+// database is closed after the test ends.
+func Open(t testing.TB, target Target) *sql.DB {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("integration tests are skipped in short mode")
+	}
+
+	prefix := "DMORPH_" + strings.ToUpper(target.Name)
+	driver := os.Getenv(prefix + "_DRIVER")
+	dsn := os.Getenv(prefix + "_DSN")
+
+	if driver == "" || dsn == "" {
+		t.Skipf("%s_DRIVER or %s_DSN not set", prefix, prefix)
+	}
+
+	if !slices.Contains(sql.Drivers(), driver) {
+		t.Skipf("driver %s not registered", driver)
+	}
+
+	db, err := sql.Open(driver, dsn)
+
+	if err != nil {
+		t.Fatalf("could not open %s: %v", target.Name, err)
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	if err := db.PingContext(t.Context()); err != nil {
+		t.Fatalf("could not connect to %s: %v", target.Name, err)
+	}
+
+	return db
+}
+
+// RunHappyPath applies two migrations to the database, runs them again to check that nothing is applied
+// twice, and verifies the registered migrations. The created tables are dropped after the test ends.
+func RunHappyPath(t *testing.T, db *sql.DB, dialect dmorph.Dialect) {
+	t.Helper()
+
+	const tableName = "dmorph_it_migrations"
+
+	t.Cleanup(func() {
+		for _, table := range []string{"dmorph_it_t1", "dmorph_it_t0", tableName} {
+			_, _ = db.ExecContext(context.Background(), "DROP TABLE "+table)
+		}
+	})
+
+	migrations := fstest.MapFS{
+		"01_base.sql":  {Data: []byte("CREATE TABLE dmorph_it_t0 (id INTEGER NOT NULL PRIMARY KEY)")},
+		"02_addon.sql": {Data: []byte("CREATE TABLE dmorph_it_t1 (id INTEGER NOT NULL PRIMARY KEY)")},
+	}
+
+	for run := range 2 {
+		err := dmorph.Run(t.Context(),
+			db,
+			dmorph.WithDialect(dialect),
+			dmorph.WithTableName(tableName),
+			dmorph.WithMigrationsFromFS(migrations))
+
+		if err != nil {
+			t.Fatalf("run %d failed: %v", run, err)
+		}
+	}
+
+	applied, err := dialect.AppliedMigrations(t.Context(), db, tableName, dmorph.MigrationGroupName)
+
+	if err != nil {
+		t.Fatalf("could not get applied migrations: %v", err)
+	}
+
+	if want := []string{"01_base.sql", "02_addon.sql"}; !slices.Equal(want, applied) {
+		t.Fatalf("applied migrations %v, but wanted %v", applied, want)
+	}
+}
+
+// RunEnsureTwice ensures the existence of the migration table repeatedly and checks that the connections of the
+// database are still usable afterward, e.g. for the procedural create statements of Oracle and DB2 returning
+// results to be drained. The migration table is dropped after the test ends.
+func RunEnsureTwice(t *testing.T, db *sql.DB, dialect dmorph.Dialect) {
+	t.Helper()
+
+	const tableName = "dmorph_it_ensure"
+
+	t.Cleanup(func() { _, _ = db.ExecContext(context.Background(), "DROP TABLE "+tableName) })
+
+	// a single connection, so that a connection left in a bad state is reused
+	db.SetMaxOpenConns(1)
+
+	for run := range 3 {
+		if err := dialect.EnsureMigrationTableExists(t.Context(), db, tableName); err != nil {
+			t.Fatalf("ensure %d failed: %v", run, err)
+		}
+
+		if _, err := dialect.AppliedMigrations(t.Context(), db, tableName, dmorph.MigrationGroupName); err != nil {
+			t.Fatalf("connection unusable after ensure %d: %v", run, err)
+		}
+	}
+}
+
+// RunAll runs the happy path scenario for all Targets as subtests.
+func RunAll(t *testing.T) {
+	t.Helper()
+
+	for _, target := range Targets() {
+		t.Run(fmt.Sprintf("HappyPath-%s", target.Name), func(t *testing.T) {
+			RunHappyPath(t, Open(t, target), target.Dialect)
+		})
+
+		t.Run(fmt.Sprintf("EnsureTwice-%s", target.Name), func(t *testing.T) {
+			RunEnsureTwice(t, Open(t, target), target.Dialect)
+		})
+	}
+}
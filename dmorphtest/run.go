@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package dmorphtest provides helpers to test migrations using DMorph, keeping the testing package out of the
+// main package. With the `integration` build tag, it additionally provides a harness to verify the DMorph
+// dialects against real database management systems.
+package dmorphtest
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// OpenDB opens the database with the given registered driver and data source name for testing. The database is
+// limited to a single connection, so that in-memory databases, e.g. SQLite's `:memory:`, keep their content
+// for the whole test. The database is closed after the test ends.
+func OpenDB(t testing.TB, driver string, dsn string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open(driver, dsn)
+
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	return db
+}
+
+// TestRun applies the migrations configured by the given options to the database and returns the Morpher for
+// further checks. As DMorph applies each migration in a transaction, a failing migration is rolled back. The
+// test is then failed with a report of the error, the already applied and the still pending migrations.
+func TestRun(t testing.TB, db *sql.DB, options ...dmorph.MorphOption) *dmorph.Morpher {
+	t.Helper()
+
+	morpher, err := dmorph.NewMorpher(options...)
+
+	if err != nil {
+		t.Fatalf("could not configure migrations: %v", err)
+	}
+
+	if err := morpher.Run(t.Context(), db); err != nil {
+		t.Fatalf("migrations failed: %v\n%s", err, report(t, db, morpher))
+	}
+
+	return morpher
+}
+
+// report describes the state of the migrations in the database, as far as it can be determined.
+func report(t testing.TB, db *sql.DB, morpher *dmorph.Morpher) string {
+	t.Helper()
+
+	pending, err := morpher.Pending(t.Context(), db)
+
+	if err != nil {
+		return "state unknown: " + err.Error()
+	}
+
+	applied := make([]string, 0, len(morpher.Migrations))
+
+	for _, migration := range morpher.Migrations {
+		if !slices.Contains(pending, migration.Key()) {
+			applied = append(applied, migration.Key())
+		}
+	}
+
+	return "applied: " + listOrNone(applied) + "\npending: " + listOrNone(pending)
+}
+
+// listOrNone joins the given keys, or reports that there are none.
+func listOrNone(keys []string) string {
+	if len(keys) == 0 {
+		return "none"
+	}
+
+	return strings.Join(keys, ", ")
+}
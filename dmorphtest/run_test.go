@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorphtest_test
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/ncruces/go-sqlite3/driver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+	"github.com/AlphaOne1/dmorph/dmorphtest"
+)
+
+// fatalRecorder is a testing.TB recording the message of Fatalf instead of failing the surrounding test.
+type fatalRecorder struct {
+	testing.TB
+
+	message string
+}
+
+func (r *fatalRecorder) Fatalf(format string, args ...any) {
+	r.message = fmt.Sprintf(format, args...)
+
+	runtime.Goexit()
+}
+
+// recordFatal runs the given function with a fatalRecorder, returning the recorded message.
+func recordFatal(t *testing.T, f func(tb testing.TB)) string {
+	t.Helper()
+
+	recorder := &fatalRecorder{TB: t}
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		f(recorder)
+	}()
+
+	<-done
+
+	return recorder.message
+}
+
+// TestTestRun verifies that TestRun applies the migrations.
+func TestTestRun(t *testing.T) {
+	t.Parallel()
+
+	db := dmorphtest.OpenDB(t, "sqlite3", ":memory:")
+
+	morpher := dmorphtest.TestRun(t,
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{
+			"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER);")},
+		}))
+
+	pending, err := morpher.Pending(t.Context(), db)
+
+	require.NoError(t, err, "could not get pending migrations")
+	assert.Empty(t, pending, "expected all migrations applied")
+}
+
+// TestTestRunFailure verifies that TestRun fails the test with a report of the migration state.
+func TestTestRunFailure(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		options []dmorph.MorphOption
+		want    []string
+	}{
+		{ // invalid configuration
+			options: []dmorph.MorphOption{dmorph.WithDialect(dmorph.DialectSQLite())},
+			want:    []string{"could not configure migrations", dmorph.ErrNoMigrations.Error()},
+		},
+		{ // failing migration
+			options: []dmorph.MorphOption{
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrationsFromFS(fstest.MapFS{
+					"01_base.sql":   {Data: []byte("CREATE TABLE t0 (id INTEGER);")},
+					"02_broken.sql": {Data: []byte("CREATE TABLE;")},
+					"03_addon.sql":  {Data: []byte("CREATE TABLE t1 (id INTEGER);")},
+				}),
+			},
+			want: []string{
+				"migrations failed",
+				"applied: 01_base.sql\n",
+				"pending: 02_broken.sql, 03_addon.sql",
+			},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestTestRunFailure-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := dmorphtest.OpenDB(t, "sqlite3", ":memory:")
+
+			message := recordFatal(t, func(tb testing.TB) {
+				dmorphtest.TestRun(tb, db, test.options...)
+			})
+
+			for _, want := range test.want {
+				assert.Contains(t, message, want, "expected report")
+			}
+		})
+	}
+}
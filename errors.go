@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnrelatedError details ErrMigrationsUnrelated, giving the first position where the applied migrations
+// differ from the configured ones. It is matched by errors.Is(err, ErrMigrationsUnrelated).
+type UnrelatedError struct {
+	Position   int    // index of the first differing migration, in application order
+	Applied    string // key of the migration applied to the database at Position
+	Configured string // key of the configured migration at Position, empty if there is none
+}
+
+// Error returns a description of the divergence.
+func (e *UnrelatedError) Error() string {
+	if e.Configured == "" {
+		return fmt.Sprintf("%v: at position %d database has %q but source has no migration",
+			ErrMigrationsUnrelated, e.Position, e.Applied)
+	}
+
+	return fmt.Sprintf("%v: at position %d database has %q but source has %q",
+		ErrMigrationsUnrelated, e.Position, e.Applied, e.Configured)
+}
+
+// Unwrap returns ErrMigrationsUnrelated.
+func (e *UnrelatedError) Unwrap() error {
+	return ErrMigrationsUnrelated
+}
+
+// KeyFormatError details ErrMigrationKeyFormat, giving the key not matching the expected format. It is matched
+// by errors.Is(err, ErrMigrationKeyFormat).
+type KeyFormatError struct {
+	Key     string // key not matching the expected format
+	Applied bool   // the key is of an applied migration, not of a configured one
+}
+
+// Error returns a description of the invalid key.
+func (e *KeyFormatError) Error() string {
+	if e.Applied {
+		return fmt.Sprintf("%v: applied migration %q", ErrMigrationKeyFormat, e.Key)
+	}
+
+	return fmt.Sprintf("%v: migration %q", ErrMigrationKeyFormat, e.Key)
+}
+
+// Unwrap returns ErrMigrationKeyFormat.
+func (e *KeyFormatError) Unwrap() error {
+	return ErrMigrationKeyFormat
+}
+
+// UnsortedError details ErrMigrationsUnsorted, giving the applied migrations in the order of their application.
+// It is matched by errors.Is(err, ErrMigrationsUnsorted).
+type UnsortedError struct {
+	Applied []string // keys of the applied migrations, in the order of their application
+}
+
+// Error returns a description of the unsorted migrations.
+func (e *UnsortedError) Error() string {
+	return fmt.Sprintf("%v: applied %s", ErrMigrationsUnsorted, strings.Join(e.Applied, ", "))
+}
+
+// Unwrap returns ErrMigrationsUnsorted.
+func (e *UnsortedError) Unwrap() error {
+	return ErrMigrationsUnsorted
+}
+
+// TooOldError details ErrMigrationsTooOld, giving the last configured and the last applied migration. It is
+// matched by errors.Is(err, ErrMigrationsTooOld).
+type TooOldError struct {
+	ConfiguredLast string // key of the last configured migration
+	AppliedLast    string // key of the last applied migration, newer than ConfiguredLast
+}
+
+// Error returns a description of the age difference.
+func (e *TooOldError) Error() string {
+	return fmt.Sprintf("%v: last configured %q is older than last applied %q",
+		ErrMigrationsTooOld, e.ConfiguredLast, e.AppliedLast)
+}
+
+// Unwrap returns ErrMigrationsTooOld.
+func (e *TooOldError) Unwrap() error {
+	return ErrMigrationsTooOld
+}
@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// TestUnrelatedError verifies the message of UnrelatedError and its match with ErrMigrationsUnrelated.
+func TestUnrelatedError(t *testing.T) {
+	t.Parallel()
+
+	err := &dmorph.UnrelatedError{Position: 2, Applied: "03_x"}
+
+	assert.ErrorIs(t, err, dmorph.ErrMigrationsUnrelated)
+	assert.EqualError(t, err, `migrations unrelated: at position 2 database has "03_x" but source has no migration`)
+
+	err.Configured = "03_y"
+
+	assert.EqualError(t, err, `migrations unrelated: at position 2 database has "03_x" but source has "03_y"`)
+}
+
+// TestDetailedErrors verifies the messages of the detailed errors and their match with the sentinel errors.
+func TestDetailedErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err      error
+		sentinel error
+		want     string
+	}{
+		{
+			err:      &dmorph.KeyFormatError{Key: "x"},
+			sentinel: dmorph.ErrMigrationKeyFormat,
+			want:     `migration key format invalid: migration "x"`,
+		},
+		{
+			err:      &dmorph.KeyFormatError{Key: "x", Applied: true},
+			sentinel: dmorph.ErrMigrationKeyFormat,
+			want:     `migration key format invalid: applied migration "x"`,
+		},
+		{
+			err:      &dmorph.UnsortedError{Applied: []string{"02_b", "01_a"}},
+			sentinel: dmorph.ErrMigrationsUnsorted,
+			want:     `migrations unsorted: applied 02_b, 01_a`,
+		},
+		{
+			err:      &dmorph.TooOldError{ConfiguredLast: "01_a", AppliedLast: "02_b"},
+			sentinel: dmorph.ErrMigrationsTooOld,
+			want:     `migrations too old: last configured "01_a" is older than last applied "02_b"`,
+		},
+	}
+
+	for _, test := range tests {
+		assert.ErrorIs(t, test.err, test.sentinel)
+		assert.EqualError(t, test.err, test.want)
+	}
+}
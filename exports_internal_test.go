@@ -6,19 +6,51 @@ package dmorph
 import (
 	"context"
 	"database/sql"
+	"io"
+	"io/fs"
+	"log/slog"
 )
 
 // The exported names in this file are only used for internal testing and are not part of the public API.
 
 //nolint:gochecknoglobals // these are used for testing and not visible or used otherwise
 var (
-	TapplyStepsStream          = applyStepsStream
-	TmigrationFromFileFS       = migrationFromFileFS
 	TmigrationOrder            = migrationOrderAlphabetical
+	TparseLeadingLine          = parseLeadingLine
 	TwrapIfError               = wrapIfError
 	TsemVerPrefixSortPredicate = semVerPrefixSortPredicate
 )
 
 func (m *Morpher) TapplyMigrations(ctx context.Context, db *sql.DB, lastMigration string) error {
-	return m.applyMigrations(ctx, db, lastMigration)
+	_, err := m.applyMigrations(ctx, db, lastMigration)
+
+	return err
+}
+
+func TapplyStepsStream(ctx context.Context, tx *sql.Tx, r io.Reader, migrationID string, log *slog.Logger) error {
+	return applyStepsStream(ctx, tx, r, migrationID, stepsConfig{Log: log, LogEvery: 1})
+}
+
+func (m *Morpher) TapplyStepsStream(ctx context.Context, tx *sql.Tx, r io.Reader, migrationID string) error {
+	return applyStepsStream(ctx, tx, r, migrationID, m.stepsConfig())
+}
+
+func (m *Morpher) TscanSteps(r io.Reader) ([]string, error) {
+	var result []string
+
+	err := scanSteps(context.Background(), r, "test", m.stepsConfig(), func(_ int, statement string, _ bool) error {
+		result = append(result, statement)
+
+		return nil
+	})
+
+	return result, err
+}
+
+func TmigrationFromFileFS(dir fs.FS, log *slog.Logger, name string) FileMigration {
+	return migrationFromFileFS(dir, &Morpher{Log: log}, name, "test:"+name)
+}
+
+func (b NumberedParamsDialect) TregisterParams(id string, groupName string) ([]any, error) {
+	return b.registerParams(id, groupName, nil)
 }
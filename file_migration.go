@@ -8,20 +8,24 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"embed"
 	"fmt"
 	"io"
 	"io/fs"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"sync"
+	"unicode"
 )
 
 // FileMigration implements the Migration interface. It helps to apply migrations from a file or fs.FS.
 type FileMigration struct {
 	Name          string
 	FS            fs.FS
+	Origin        string // source of the migration for diagnostics, e.g. `embed:migrations/01.sql`
 	migrationFunc func(ctx context.Context, tx *sql.Tx, migration string) error
 }
 
@@ -35,22 +39,146 @@ func (f FileMigration) Migrate(ctx context.Context, tx *sql.Tx) error {
 	return f.migrationFunc(ctx, tx, f.Name)
 }
 
+// Tags returns the tags declared in the leading comments of the migration file. A tag is declared using
+// the directive `-- dmorph:env <tag>...`, multiple tags are separated by whitespace or commas.
+func (f FileMigration) Tags() ([]string, error) {
+	m, mErr := f.open()
+
+	if mErr != nil {
+		return nil, mErr
+	}
+
+	defer func() { _ = m.Close() }()
+
+	return readDirectiveArgs(m, directiveEnv)
+}
+
+// Category returns the category of the migration file, declared in its leading comments using the directive
+// `-- dmorph:category <category>`, e.g. `data`. Without directive, the empty string is returned.
+func (f FileMigration) Category() (string, error) {
+	m, mErr := f.open()
+
+	if mErr != nil {
+		return "", mErr
+	}
+
+	defer func() { _ = m.Close() }()
+
+	args, err := readDirectiveArgs(m, directiveCategory)
+
+	if err != nil || len(args) == 0 {
+		return "", err
+	}
+
+	if len(args) > 1 {
+		return "", fmt.Errorf("%w: %s", ErrCategoryInvalid, strings.Join(args, " "))
+	}
+
+	return args[0], nil
+}
+
+// Requires returns the keys of the migrations required by the migration file, declared in its leading comments
+// using the directive `-- dmorph:requires <key>...`, multiple keys are separated by whitespace or commas.
+func (f FileMigration) Requires() ([]string, error) {
+	m, mErr := f.open()
+
+	if mErr != nil {
+		return nil, mErr
+	}
+
+	defer func() { _ = m.Close() }()
+
+	return readDirectiveArgs(m, directiveRequires)
+}
+
+// TxOptions returns the options of the transaction of the migration file, if its leading comments declare an
+// isolation level using the directive `-- dmorph:isolation <level>`, e.g. `serializable` or `read committed`.
+// Without directive, nil is returned, so that the options configured using WithTxOptions apply.
+func (f FileMigration) TxOptions() (*sql.TxOptions, error) {
+	m, mErr := f.open()
+
+	if mErr != nil {
+		return nil, mErr
+	}
+
+	defer func() { _ = m.Close() }()
+
+	levels, err := readDirectiveArgs(m, directiveIsolation)
+
+	if err != nil || len(levels) == 0 {
+		return nil, err
+	}
+
+	level, err := parseIsolationLevel(strings.Join(levels, " "))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &sql.TxOptions{Isolation: level}, nil
+}
+
+// VersionRange returns the server versions supported by the migration file, declared in its leading comments
+// using the directives `-- dmorph:min-version <version>` and `-- dmorph:max-version <version>`, e.g. `14` or
+// `8.0.13`. Without directive, the respective bound is empty.
+func (f FileMigration) VersionRange() (string, string, error) {
+	var bounds [2]string
+
+	for i, name := range []string{directiveMinVersion, directiveMaxVersion} {
+		m, mErr := f.open()
+
+		if mErr != nil {
+			return "", "", mErr
+		}
+
+		args, err := readDirectiveArgs(m, name)
+
+		_ = m.Close()
+
+		if err != nil {
+			return "", "", err
+		}
+
+		if len(args) > 1 {
+			return "", "", fmt.Errorf("%w: %s %s", ErrVersionInvalid, name, strings.Join(args, " "))
+		}
+
+		if len(args) == 1 {
+			bounds[i] = args[0]
+		}
+	}
+
+	return bounds[0], bounds[1], nil
+}
+
+// open opens the migration file, from the FS if given. All file migrations open their files using this method,
+// so that the underlying error, e.g. a *fs.PathError, stays retrievable using errors.As.
+func (f FileMigration) open() (io.ReadCloser, error) {
+	var m io.ReadCloser
+	var mErr error
+
+	if f.FS != nil {
+		m, mErr = f.FS.Open(f.Name)
+	} else {
+		m, mErr = os.Open(filepath.Clean(f.Name))
+	}
+
+	if mErr != nil {
+		return nil, wrapIfError("could not open file "+f.Name, mErr)
+	}
+
+	return m, nil
+}
+
 // WithMigrationsFromFiles generates a FileMigration that will run the content of the given file.
 func WithMigrationsFromFiles(names ...string) MorphOption {
 	return func(morpher *Morpher) error {
 		for _, n := range names {
 			morpher.Migrations = append(morpher.Migrations, FileMigration{
-				Name: n,
+				Name:   n,
+				Origin: "file:" + n,
 				migrationFunc: func(ctx context.Context, tx *sql.Tx, migration string) error {
-					m, mErr := os.Open(filepath.Clean(migration))
-
-					if mErr != nil {
-						return wrapIfError("could not open file "+migration, mErr)
-					}
-
-					defer func() { _ = m.Close() }()
-
-					return applyStepsStream(ctx, tx, m, migration, morpher.Log)
+					return applyMigrationFile(ctx, tx, morpher, migration, FileMigration{Name: migration}.open)
 				},
 			})
 		}
@@ -60,11 +188,11 @@ func WithMigrationsFromFiles(names ...string) MorphOption {
 }
 
 // WithMigrationsFromFilesFS generates FileMigration that will run the content of the given files from the
-// given filesystem.
+// given filesystem. This selects a subset of the files, that is added in the given order and sorted by Run.
 func WithMigrationsFromFilesFS(dir fs.FS, names ...string) MorphOption {
 	return func(morpher *Morpher) error {
 		for _, n := range names {
-			morpher.Migrations = append(morpher.Migrations, migrationFromFileFS(dir, morpher.Log, n))
+			morpher.Migrations = append(morpher.Migrations, migrationFromFileFS(dir, morpher, n, fsOrigin(dir)+n))
 		}
 
 		return nil
@@ -75,55 +203,537 @@ func WithMigrationsFromFilesFS(dir fs.FS, names ...string) MorphOption {
 // files in the given filesystem.
 func WithMigrationsFromFS(d fs.FS) MorphOption {
 	return func(morpher *Morpher) error {
-		dirEntry, err := fs.ReadDir(d, ".")
+		migrations, err := migrationsFromFS(d, morpher, fsOrigin(d))
 
-		if err == nil {
-			for _, entry := range dirEntry {
-				morpher.Log.Info("entry", slog.String("name", entry.Name()))
+		morpher.Migrations = append(morpher.Migrations, migrations...)
 
-				if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), ".sql") {
-					morpher.Migrations = append(morpher.Migrations,
-						migrationFromFileFS(d, morpher.Log, entry.Name()))
-				}
-			}
+		return err
+	}
+}
+
+// MigrationsFromFS returns a FileMigration for each `.sql` file in the given filesystem, discovered the same
+// way as by WithMigrationsFromFS, without the need of a Morpher or a database. The migrations are ordered by
+// their file names. When applied outside a Morpher, they do not log.
+func MigrationsFromFS(d fs.FS) ([]Migration, error) {
+	return migrationsFromFS(d, nil, fsOrigin(d))
+}
+
+// migrationsFromFS discovers the migrations in the given filesystem for the given, possibly nil, Morpher. The
+// origin of each migration is its file name prefixed by the given origin of the filesystem.
+func migrationsFromFS(d fs.FS, morpher *Morpher, origin string) ([]Migration, error) {
+	dirEntry, err := fs.ReadDir(d, ".")
+
+	if err != nil {
+		return nil, wrapIfError("could not read directory", err)
+	}
+
+	log := slog.New(slog.DiscardHandler)
+
+	if morpher != nil {
+		log = morpher.logger()
+	}
+
+	var result []Migration
+
+	for _, entry := range dirEntry {
+		log.Debug("entry", slog.String("name", entry.Name()))
+
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), ".sql") {
+			result = append(result, migrationFromFileFS(d, morpher, entry.Name(), origin+entry.Name()))
+		}
+	}
+
+	if morpher != nil && morpher.Manifest != "" {
+		return morpher.orderByManifest(d, result)
+	}
+
+	return result, nil
+}
+
+// WithMigrationsFromSubFS generates FileMigration instances for all `.sql` files in the given subdirectory
+// of the filesystem. The keys of the migrations are relative to the subdirectory.
+func WithMigrationsFromSubFS(d fs.FS, subpath string) MorphOption {
+	return func(morpher *Morpher) error {
+		sub, err := fs.Sub(d, subpath)
+
+		if err != nil {
+			return wrapIfError("could not open sub directory "+subpath, err)
 		}
 
-		return wrapIfError("could not read directory", err)
+		migrations, err := migrationsFromFS(sub, morpher, fsOrigin(d)+path.Clean(subpath)+"/")
+
+		morpher.Migrations = append(morpher.Migrations, migrations...)
+
+		return err
+	}
+}
+
+// fsOrigin returns the prefix of the origins of migrations from the given filesystem, distinguishing embedded
+// filesystems from others.
+func fsOrigin(dir fs.FS) string {
+	if _, isEmbedded := dir.(embed.FS); isEmbedded {
+		return "embed:"
 	}
+
+	return "fs:"
 }
 
-// migrationFromFileFS creates a FileMigration instance for a specific migration file from a fs.FS directory.
-func migrationFromFileFS(dir fs.FS, log *slog.Logger, name string) FileMigration {
+// migrationFromFileFS creates a FileMigration instance with the given origin for a specific migration file from
+// a fs.FS directory. The configuration of the steps is taken from the given Morpher at the time the migration is
+// applied. If no Morpher is given, the default configuration is used.
+func migrationFromFileFS(dir fs.FS, morpher *Morpher, name string, origin string) FileMigration {
 	return FileMigration{
-		Name: name,
-		FS:   dir,
+		Name:   name,
+		FS:     dir,
+		Origin: origin,
 		migrationFunc: func(ctx context.Context, tx *sql.Tx, migration string) error {
-			m, mErr := dir.Open(migration)
+			return applyMigrationFile(ctx, tx, morpher, migration, FileMigration{Name: migration, FS: dir}.open)
+		},
+	}
+}
+
+// applyMigrationFile applies the migration file opened by the given function, using the configuration of the
+// given, possibly nil, Morpher. If configured, the parsed steps are cached in the Morpher.
+func applyMigrationFile(
+	ctx context.Context,
+	tx *sql.Tx,
+	morpher *Morpher,
+	migrationID string,
+	open func() (io.ReadCloser, error)) error {
+
+	cfg := stepsConfig{Log: slog.New(slog.DiscardHandler), LogEvery: 1}
+
+	if morpher != nil {
+		cfg = morpher.stepsConfig()
+	}
+
+	if morpher == nil || morpher.parsedSteps == nil {
+		m, mErr := open()
+
+		if mErr != nil {
+			return mErr
+		}
+
+		defer func() { _ = m.Close() }()
+
+		if err := applyStepsStream(ctx, tx, m, migrationID, cfg); err != nil {
+			return err
+		}
+
+		logDiagnostics(ctx, tx, migrationID, cfg, open)
+
+		return nil
+	}
 
-			if mErr != nil {
-				return wrapIfError("could not open file migration", mErr)
+	steps, stepsErr := morpher.cachedSteps(ctx, migrationID, open, cfg)
+
+	if stepsErr != nil {
+		return stepsErr
+	}
+
+	exec := execStep(ctx, tx, migrationID, cfg)
+	logStep, _ := cfg.stepLogger(ctx, migrationID)
+
+	for i, step := range steps {
+		logStep(i, step.final || i == len(steps)-1)
+
+		if err := exec(i, step.statement, step.final); err != nil {
+			return err
+		}
+	}
+
+	logDiagnostics(ctx, tx, migrationID, cfg, open)
+
+	return nil
+}
+
+// runStepwise applies the given file migration committing each step in a separate transaction, and registers it
+// in a further one.
+func (m *Morpher) runStepwise(
+	ctx context.Context,
+	db *sql.DB,
+	opts *sql.TxOptions,
+	migrationID string,
+	source sqlSource,
+) error {
+	r, openErr := source.open()
+
+	if openErr != nil {
+		return openErr
+	}
+
+	defer func() { _ = r.Close() }()
+
+	cfg := m.stepsConfig()
+	lastStep := -1
+
+	logStep, logLast := cfg.stepLogger(ctx, migrationID)
+
+	err := scanSteps(ctx, r, migrationID, cfg, func(step int, statement string, final bool) error {
+		logStep(step, final)
+
+		err := m.inMigrationTx(ctx, db, opts, func(tx *sql.Tx) error {
+			if err := m.annotateTx(ctx, tx, migrationID); err != nil {
+				return err
 			}
 
-			defer func() { _ = m.Close() }()
+			return execStep(ctx, tx, migrationID, cfg)(step, statement, final)
+		})
 
-			return applyStepsStream(ctx, tx, m, migration, log)
-		},
+		if err == nil {
+			lastStep = step
+		}
+
+		return err
+	})
+
+	if err == nil {
+		logLast()
+
+		err = m.inMigrationTx(ctx, db, opts, func(tx *sql.Tx) error {
+			logDiagnostics(ctx, tx, migrationID, cfg, source.open)
+
+			return m.registerMigration(ctx, tx, migrationID)
+		})
+	}
+
+	if err != nil && lastStep >= 0 {
+		cfg.Log.WarnContext(ctx, "migration partially applied",
+			slog.String("file", migrationID),
+			slog.Int("lastStep", lastStep),
+		)
+	}
+
+	return err
+}
+
+// inTransaction calls the given function in a transaction with the given options, that is committed if the
+// function succeeds.
+func inTransaction(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return wrapIfError("commit tx", tx.Commit())
+}
+
+// parsedStep is a step of a file migration, as cached if configured using WithCacheParsedMigrations.
+type parsedStep struct {
+	statement string // statement of the step
+	final     bool   // step is not terminated by a separator
+}
+
+// cachedSteps returns the steps of the migration file opened by the given function, reading and splitting it
+// only if not already cached.
+func (m *Morpher) cachedSteps(
+	ctx context.Context,
+	migrationID string,
+	open func() (io.ReadCloser, error),
+	cfg stepsConfig) ([]parsedStep, error) {
+
+	if steps, cached := m.parsedSteps.Load(migrationID); cached {
+		return steps.([]parsedStep), nil //nolint:forcetypeassert // only parsed steps are stored
+	}
+
+	r, openErr := open()
+
+	if openErr != nil {
+		return nil, openErr
+	}
+
+	defer func() { _ = r.Close() }()
+
+	var steps []parsedStep
+
+	err := scanSteps(ctx, r, migrationID, cfg, func(_ int, statement string, final bool) error {
+		steps = append(steps, parsedStep{statement: statement, final: final})
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	m.parsedSteps.Store(migrationID, steps)
+
+	return steps, nil
+}
+
+// readDirectiveArgs reads the arguments of the directives with the given name from the leading comments of a
+// migration. Reading stops at the first line that is neither empty nor a comment.
+func readDirectiveArgs(r io.Reader, name string) ([]string, error) {
+	directives, err := readDirectives(r, name)
+
+	var result []string
+
+	for _, args := range directives {
+		result = append(result, splitDirectiveArgs(args)...)
+	}
+
+	return result, err
+}
+
+// readDirectives reads the unparsed arguments of each directive with the given name from the leading comments of
+// a migration, as described for readDirectiveArgs.
+func readDirectives(r io.Reader, name string) ([]string, error) {
+	var result []string
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		leading, d := parseLeadingLine(scanner.Text())
+
+		if !leading {
+			break
+		}
+
+		if d != nil && d.Name == name {
+			result = append(result, d.Args)
+		}
+	}
+
+	return result, wrapIfError("scanner error", scanner.Err())
+}
+
+// logDiagnostics executes the diagnostic queries declared in the leading comments of the migration opened by the
+// given function using the directive `-- dmorph:log <query>`, and logs their scalar results. Failing queries are
+// only logged, so that they do not affect the migration.
+func logDiagnostics(
+	ctx context.Context,
+	tx *sql.Tx,
+	migrationID string,
+	cfg stepsConfig,
+	open func() (io.ReadCloser, error)) {
+
+	r, openErr := open()
+
+	if openErr != nil {
+		cfg.Log.WarnContext(ctx, "could not read diagnostics",
+			slog.String("file", migrationID), slog.Any("error", openErr))
+
+		return
+	}
+
+	defer func() { _ = r.Close() }()
+
+	queries, readErr := readDirectives(r, directiveLog)
+
+	if readErr != nil {
+		cfg.Log.WarnContext(ctx, "could not read diagnostics",
+			slog.String("file", migrationID), slog.Any("error", readErr))
+	}
+
+	for _, query := range queries {
+		var value any
+
+		if err := tx.QueryRowContext(ctx, query).Scan(&value); err != nil {
+			cfg.Log.WarnContext(ctx, "migration diagnostic failed",
+				slog.String("file", migrationID), slog.String("query", query), slog.Any("error", err))
+
+			continue
+		}
+
+		if raw, isRaw := value.([]byte); isRaw {
+			value = string(raw)
+		}
+
+		cfg.Log.InfoContext(ctx, "migration diagnostic",
+			slog.String("file", migrationID), slog.String("query", query), slog.Any("value", value))
+	}
+}
+
+// WithCacheParsedMigrations caches the steps of file migrations after their first use, so that later runs of
+// the same Morpher, e.g. in long-lived services running it repeatedly, neither read nor split the files again.
+// Changes of the files after their first use are not noticed, so the cache is intended for immutable files,
+// like embedded ones.
+func WithCacheParsedMigrations(cache bool) MorphOption {
+	return func(morpher *Morpher) error {
+		morpher.parsedSteps = nil
+
+		if cache {
+			morpher.parsedSteps = &sync.Map{}
+		}
+
+		return nil
+	}
+}
+
+// preflight reads each of the given migrations consisting of SQL and splits it into its steps, without
+// executing them. The first migration that cannot be read or split is reported.
+func (m *Morpher) preflight(ctx context.Context, migrations []Migration) error {
+	cfg := m.stepsConfig()
+
+	for _, migration := range migrations {
+		source, isSource := migration.(sqlSource)
+
+		if !isSource {
+			continue
+		}
+
+		cfg.Log.DebugContext(ctx, "preflight migration", slog.String("file", migration.Key()))
+
+		r, openErr := source.open()
+
+		if openErr != nil {
+			return fmt.Errorf("preflight of migration %s failed: %w", migration.Key(), openErr)
+		}
+
+		data, readErr := io.ReadAll(r)
+		_ = r.Close()
+
+		if readErr != nil {
+			return fmt.Errorf("preflight of migration %s failed: %w", migration.Key(), readErr)
+		}
+
+		noop := func(int, string, bool) error { return nil }
+
+		if err := scanSteps(ctx, bytes.NewReader(data), migration.Key(), cfg, noop); err != nil {
+			return fmt.Errorf("preflight of migration %s failed: %w", migration.Key(), err)
+		}
+	}
+
+	return nil
+}
+
+// stepsConfig configures how applyStepsStream splits a migration into steps and executes them.
+type stepsConfig struct {
+	Log               *slog.Logger // logger to be used
+	StrictTermination bool         // every statement has to be terminated by a separator
+	BatchSeparator    string       // line separating steps in addition to `;`, optional
+	KeepComments      bool         // pass leading comments on to the database instead of removing them
+	LogEvery          int          // log every nth step, besides the first and the last, none if less than 1
+
+	Transform func(statement string) string // rewrites the statements before execution, optional
+}
+
+// stepsConfig returns the configuration for applyStepsStream as set in the Morpher.
+func (m *Morpher) stepsConfig() stepsConfig {
+	cfg := stepsConfig{
+		Log:               m.logger(),
+		StrictTermination: m.StrictTermination,
+		KeepComments:      m.KeepComments,
+		LogEvery:          m.StepLog,
+		Transform:         m.Transform,
+	}
+
+	if separator, isSeparator := m.Dialect.(StepSeparator); isSeparator {
+		cfg.BatchSeparator = separator.StepSeparator()
+	}
+
+	return cfg
+}
+
+// transform applies the configured transformation to the given statement.
+func (c stepsConfig) transform(statement string) string {
+	if c.Transform == nil {
+		return statement
 	}
+
+	return c.Transform(statement)
+}
+
+// isSeparator checks if the given line separates two steps.
+func (c stepsConfig) isSeparator(line string) bool {
+	trimmed := strings.TrimSpace(line)
+
+	return trimmed == ";" || (c.BatchSeparator != "" && strings.EqualFold(trimmed, c.BatchSeparator))
 }
 
 // applyStepsStream executes database migration steps read from an io.Reader, separated by semicolons, in a transaction.
 // Returns the corresponding error if any step execution fails. Also, as some database drivers or engines seem to not
 // support comments, leading comments are removed. This function does not undertake efforts to scan the SQL to find
 // other comments. Such leading comments telling what a step is going to do, work. But comments in the middle of a
-// statement will not be removed. At least with SQLite this will lead to hard-to-find errors.
-func applyStepsStream(ctx context.Context, tx *sql.Tx, r io.Reader, migrationID string, log *slog.Logger) error {
+// statement will not be removed. At least with SQLite this will lead to hard-to-find errors. The removal of leading
+// comments can be disabled, see WithStripComments.
+func applyStepsStream(ctx context.Context, tx *sql.Tx, r io.Reader, migrationID string, cfg stepsConfig) error {
+	logStep, logLast := cfg.stepLogger(ctx, migrationID)
+
+	err := scanSteps(ctx, r, migrationID, cfg, func(step int, statement string, final bool) error {
+		logStep(step, final)
+
+		return execStep(ctx, tx, migrationID, cfg)(step, statement, final)
+	})
+
+	if err == nil {
+		logLast()
+	}
+
+	return err
+}
+
+// stepLogger returns a function logging the steps of the given migration as sampled by the LogEvery setting, and
+// a function logging the last step afterward, if it was not sampled.
+func (c stepsConfig) stepLogger(ctx context.Context, migrationID string) (func(step int, final bool), func()) {
+	lastStep, lastLogged := -1, false
+
+	logStep := func(step int) {
+		c.Log.DebugContext(ctx, "migration step",
+			slog.String("migrationID", migrationID),
+			slog.Int("step", step),
+		)
+	}
+
+	sample := func(step int, final bool) {
+		lastStep = step
+		lastLogged = c.LogEvery > 0 && (step%c.LogEvery == 0 || final)
+
+		if lastLogged {
+			logStep(step)
+		}
+	}
+
+	last := func() {
+		if c.LogEvery > 0 && lastStep >= 0 && !lastLogged {
+			logStep(lastStep)
+		}
+	}
+
+	return sample, last
+}
+
+// execStep returns a function executing a step of the given migration on the transaction.
+func execStep(
+	ctx context.Context,
+	tx *sql.Tx,
+	migrationID string,
+	cfg stepsConfig) func(step int, statement string, final bool) error {
+
+	return func(step int, statement string, final bool) error {
+		if _, err := tx.ExecContext(ctx, cfg.transform(statement)); err != nil {
+			if final {
+				return fmt.Errorf("apply migration %q step %d (final): %w", migrationID, step, err)
+			}
+
+			return fmt.Errorf("apply migration %q step %d: %w", migrationID, step, err)
+		}
+
+		return nil
+	}
+}
+
+// scanSteps splits the migration read from an io.Reader into its steps, as described for applyStepsStream, and
+// calls the given function for each of them. The final step, that is not terminated by a separator, is marked.
+func scanSteps(
+	ctx context.Context,
+	r io.Reader,
+	migrationID string,
+	cfg stepsConfig,
+	fn func(step int, statement string, final bool) error) error {
+
+	log := cfg.Log
+
 	const InitialScannerBufSize = 64 * 1024
 	const MaxScannerBufSize = 1024 * 1024
 
-	// The regex is here, as we do not expect to have overwhelming lots of calls even during larger migrations.
-	// No need to pollute the global namespace.
-	initialEmptyRegex := regexp.MustCompile(`^\s*(?:--.*)?$`)
-
 	buf := bytes.Buffer{}
 
 	scanner := bufio.NewScanner(r)
@@ -131,26 +741,38 @@ func applyStepsStream(ctx context.Context, tx *sql.Tx, r io.Reader, migrationID
 
 	newStep := true
 
+	// length of the buffer up to the last line that is neither empty nor a comment
+	contentLen := 0
+
 	var step int
 
 	for step = 0; scanner.Scan(); {
-		if newStep && initialEmptyRegex.MatchString(scanner.Text()) {
-			// skip leading comments
-			continue
-		}
+		if newStep {
+			// skip leading comments unless kept, directives are already handled when loading the migration
+			if leading, d := parseLeadingLine(scanner.Text()); leading {
+				if d != nil {
+					log.DebugContext(ctx, "migration directive",
+						slog.String("migrationID", migrationID),
+						slog.String("name", d.Name),
+						slog.String("args", d.Args),
+					)
+				}
 
-		if scanner.Text() == ";" {
-			log.Info("migration step",
-				slog.String("migrationID", migrationID),
-				slog.Int("step", step),
-			)
+				// empty lines are skipped in any case, as they would form empty statements
+				if !cfg.KeepComments || strings.TrimSpace(scanner.Text()) == "" {
+					continue
+				}
+			}
+		}
 
-			if _, err := tx.ExecContext(ctx, buf.String()); err != nil {
-				return fmt.Errorf("apply migration %q step %d: %w", migrationID, step, err)
+		if cfg.isSeparator(scanner.Text()) {
+			if err := fn(step, buf.String(), false); err != nil {
+				return err
 			}
 
 			buf.Reset()
 
+			contentLen = 0
 			newStep = true
 
 			step++
@@ -165,18 +787,28 @@ func applyStepsStream(ctx context.Context, tx *sql.Tx, r io.Reader, migrationID
 
 		buf.Write(scanner.Bytes())
 
+		// comments are only part of the statement if it continues after them
+		if leading, _ := parseLeadingLine(scanner.Text()); !leading {
+			contentLen = buf.Len()
+		}
+
 		newStep = false
 	}
 
-	// cleanup after, for the final statement without the closing `;` on a new line
-	if buf.Len() > 0 {
-		log.Info("migration step",
-			slog.String("migrationID", migrationID),
-			slog.Int("step", step),
-		)
+	if contentLen < buf.Len() {
+		log.DebugContext(ctx, "trailing comments ignored", slog.String("migrationID", migrationID))
+	}
+
+	// cleanup after, for the final statement without the closing `;` on a new line. Comments trailing the last
+	// statement are never executed. Trailing whitespace is removed, as some drivers reject statements only
+	// consisting of whitespace.
+	if final := strings.TrimRightFunc(buf.String()[:contentLen], unicode.IsSpace); final != "" {
+		if cfg.StrictTermination {
+			return fmt.Errorf("apply migration %q step %d (final): %w", migrationID, step, ErrStatementUnterminated)
+		}
 
-		if _, err := tx.ExecContext(ctx, buf.String()); err != nil {
-			return fmt.Errorf("apply migration %q step %d (final): %w", migrationID, step, err)
+		if err := fn(step, final, true); err != nil {
+			return err
 		}
 	}
 
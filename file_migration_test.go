@@ -5,10 +5,15 @@ package dmorph_test
 
 import (
 	"bytes"
+	"database/sql"
+	"fmt"
 	"io/fs"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/AlphaOne1/dmorph"
 	"github.com/stretchr/testify/assert"
@@ -42,6 +47,69 @@ func TestWithMigrationFromFileError(t *testing.T) {
 	assert.ErrorAs(t, runErr, &pathErr, "unexpected error")
 }
 
+// TestWithMigrationsOpenError verifies that the error of opening a migration file stays retrievable for all
+// file based migration options, also when the file is removed after the migrations were discovered.
+func TestWithMigrationsOpenError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		option func(t *testing.T, name string) (dmorph.MorphOption, func())
+		name   string
+	}{
+		{ // single file
+			option: func(t *testing.T, name string) (dmorph.MorphOption, func()) {
+				t.Helper()
+
+				file := filepath.Join(t.TempDir(), name)
+
+				require.NoError(t, os.WriteFile(file, []byte("CREATE TABLE t0 (id INTEGER)"), 0o600),
+					"could not write migration")
+
+				return dmorph.WithMigrationsFromFiles(file), func() { _ = os.Remove(file) }
+			},
+			name: "01_removed.sql",
+		},
+		{ // selected files of a filesystem
+			option: func(_ *testing.T, name string) (dmorph.MorphOption, func()) {
+				dir := fstest.MapFS{name: {Data: []byte("CREATE TABLE t0 (id INTEGER)")}}
+
+				return dmorph.WithMigrationsFromFilesFS(dir, name), func() { delete(dir, name) }
+			},
+			name: "01_removed.sql",
+		},
+		{ // all files of a filesystem
+			option: func(_ *testing.T, name string) (dmorph.MorphOption, func()) {
+				dir := fstest.MapFS{name: {Data: []byte("CREATE TABLE t0 (id INTEGER)")}}
+
+				return dmorph.WithMigrationsFromFS(dir), func() { delete(dir, name) }
+			},
+			name: "01_removed.sql",
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestWithMigrationsOpenError-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			option, remove := test.option(t, test.name)
+
+			morpher, morpherErr := dmorph.NewMorpher(dmorph.WithDialect(dmorph.DialectSQLite()), option)
+
+			require.NoError(t, morpherErr, "morpher could not be created")
+
+			remove()
+
+			runErr := morpher.Run(t.Context(), openTempSQLite(t))
+
+			var pathErr *fs.PathError
+
+			require.ErrorAs(t, runErr, &pathErr, "path error not retrievable")
+			assert.ErrorIs(t, runErr, fs.ErrNotExist, "expected not exist error")
+			assert.ErrorContains(t, runErr, test.name, "file name not reported")
+		})
+	}
+}
+
 // TestMigrationFromFileFSError validates that migrationFromFileFS returns an error
 // when the specified file does not exist.
 func TestMigrationFromFileFSError(t *testing.T) {
@@ -88,3 +156,887 @@ func TestApplyStepsStreamError(t *testing.T) {
 
 	_ = tx.Rollback()
 }
+
+// TestWithMigrationsFromSubFS verifies that all migrations of a subdirectory are found, keyed relative to it.
+func TestWithMigrationsFromSubFS(t *testing.T) {
+	t.Parallel()
+
+	morpher, err := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromSubFS(testMigrationsDir, "testData"))
+
+	require.NoError(t, err, "morpher could not be created")
+	require.Len(t, morpher.Migrations, 2, "unexpected number of migrations")
+
+	keys := []string{morpher.Migrations[0].Key(), morpher.Migrations[1].Key()}
+
+	assert.ElementsMatch(t, []string{"01_base_table.sql", "02_addon_table.sql"}, keys)
+
+	runErr := morpher.Run(t.Context(), openTempSQLite(t))
+
+	assert.NoError(t, runErr, "migrations could not be run")
+}
+
+// TestWithMigrationsFromSubFSError verifies that an invalid subpath is reported.
+func TestWithMigrationsFromSubFSError(t *testing.T) {
+	t.Parallel()
+
+	_, err := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromSubFS(testMigrationsDir, "../invalid"))
+
+	assert.Error(t, err, "expected error on invalid sub path")
+}
+
+// TestApplyStepsStreamTrailing tests that trailing blank lines and comments after the last statement
+// do not lead to additional statements being executed.
+func TestApplyStepsStreamTrailing(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{
+			name:   "blank lines after separator",
+			script: "CREATE TABLE t0 (id INTEGER)\n;\n\n\n   \n\t\n",
+		},
+		{
+			name:   "blank lines after final statement",
+			script: "CREATE TABLE t0 (id INTEGER)\n\n\n   \n\t\n",
+		},
+		{
+			name:   "comment after separator",
+			script: "CREATE TABLE t0 (id INTEGER)\n;\n-- the end\n",
+		},
+		{
+			name:   "separator with surrounding whitespace",
+			script: "CREATE TABLE t0 (id INTEGER)\n  ;  \n\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			tx, txErr := db.BeginTx(t.Context(), nil)
+
+			require.NoError(t, txErr, "expected no tx error")
+
+			defer func() { _ = tx.Rollback() }()
+
+			buf := bytes.NewBufferString(test.script)
+
+			require.NoError(t,
+				dmorph.TapplyStepsStream(t.Context(), tx, buf, "test", slog.New(slog.DiscardHandler)),
+				"expected no error")
+
+			var count int
+
+			require.NoError(t, tx.QueryRowContext(t.Context(),
+				"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&count))
+			assert.Equal(t, 1, count, "unexpected number of tables")
+		})
+	}
+}
+
+// TestWithTags verifies that tagged migrations are only included if one of their tags is active.
+func TestWithTags(t *testing.T) {
+	t.Parallel()
+
+	migrations := fstest.MapFS{
+		"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+		"02_fixture.sql": {Data: []byte(
+			"-- creates fixture data\n-- dmorph:env test, dev\nINSERT INTO t0 (id) VALUES (1)")},
+		"03_prod.sql": {Data: []byte("-- dmorph:env prod\nINSERT INTO t0 (id) VALUES (2)")},
+	}
+
+	tests := []struct {
+		name    string
+		options []dmorph.MorphOption
+		want    []string
+	}{
+		{
+			name: "no tags",
+			want: []string{"01_base.sql"},
+		},
+		{
+			name:    "environment",
+			options: []dmorph.MorphOption{dmorph.WithEnvironment("test")},
+			want:    []string{"01_base.sql", "02_fixture.sql"},
+		},
+		{
+			name:    "multiple tags",
+			options: []dmorph.MorphOption{dmorph.WithTags("dev", "prod")},
+			want:    []string{"01_base.sql", "02_fixture.sql", "03_prod.sql"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			morpher, err := dmorph.NewMorpher(append([]dmorph.MorphOption{
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrationsFromFS(migrations)},
+				test.options...)...)
+
+			require.NoError(t, err, "morpher could not be created")
+
+			keys := make([]string, 0, len(morpher.Migrations))
+
+			for _, m := range morpher.Migrations {
+				keys = append(keys, m.Key())
+			}
+
+			assert.Equal(t, test.want, keys, "unexpected migrations")
+			assert.NoError(t, morpher.Run(t.Context(), openTempSQLite(t)), "migrations could not be run")
+		})
+	}
+}
+
+// TestWithTagsFileError verifies that an unreadable file migration is reported while filtering by tags.
+func TestWithTagsFileError(t *testing.T) {
+	t.Parallel()
+
+	_, err := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFiles("testData/00_non_existent.sql"))
+
+	var pathErr *fs.PathError
+	assert.ErrorAs(t, err, &pathErr, "unexpected error")
+}
+
+// TestWithMigrationsFromMultipleFS verifies that migrations from multiple sources are merged into one ordered
+// sequence, and that colliding keys are detected.
+func TestWithMigrationsFromMultipleFS(t *testing.T) {
+	t.Parallel()
+
+	core := fstest.MapFS{
+		"01_core.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+		"03_core.sql": {Data: []byte("CREATE TABLE t2 (id INTEGER REFERENCES t1 (id))")},
+	}
+
+	plugin := fstest.MapFS{
+		"02_plugin.sql": {Data: []byte("CREATE TABLE t1 (id INTEGER REFERENCES t0 (id))")},
+	}
+
+	morpher, err := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(core),
+		dmorph.WithMigrationsFromFS(plugin))
+
+	require.NoError(t, err, "morpher could not be created")
+
+	db := openTempSQLite(t)
+
+	require.NoError(t, morpher.Run(t.Context(), db), "migrations could not be run")
+
+	applied, appliedErr := dmorph.DialectSQLite().AppliedMigrations(t.Context(),
+		db, dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+	require.NoError(t, appliedErr, "applied migrations could not be read")
+	assert.Equal(t, []string{"01_core.sql", "02_plugin.sql", "03_core.sql"}, applied, "wrong order")
+
+	_, err = dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(core),
+		dmorph.WithMigrationsFromFS(plugin),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{"01_core.sql": {Data: []byte("SELECT 1")}}))
+
+	require.ErrorIs(t, err, dmorph.ErrMigrationKeyDuplicate, "expected duplicate key error")
+	assert.ErrorContains(t, err, "01_core.sql", "duplicate key not named")
+}
+
+// TestDuplicateSemVerKeys verifies that semantic version keys with the same version are rejected, as
+// their order is ambiguous.
+func TestDuplicateSemVerKeys(t *testing.T) {
+	t.Parallel()
+
+	_, err := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationKeyProperties(dmorph.MigrationKeySemVerPrefix()),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{
+			"v1.0.0_a.sql": {Data: []byte("SELECT 1")},
+			"v1.0.0_b.sql": {Data: []byte("SELECT 2")},
+		}))
+
+	assert.ErrorIs(t, err, dmorph.ErrMigrationKeyDuplicate, "expected duplicate key error")
+}
+
+// TestApplyStepsStreamStrictTermination verifies that unterminated final statements are rejected in strict mode,
+// while trailing blank lines and comments are accepted.
+func TestApplyStepsStreamStrictTermination(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		script  string
+		wantErr bool
+	}{
+		{script: "CREATE TABLE t0 (id INTEGER)\n;\n", wantErr: false},
+		{script: "CREATE TABLE t0 (id INTEGER)\n;\n\n-- the end\n\n", wantErr: false},
+		{script: "CREATE TABLE t0 (id INTEGER)\n", wantErr: true},
+		{script: "CREATE TABLE t0 (id INTEGER)\n;\nCREATE TABLE t1 (\n", wantErr: true},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestApplyStepsStreamStrictTermination-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			tx, txErr := db.BeginTx(t.Context(), nil)
+
+			require.NoError(t, txErr, "expected no tx error")
+
+			defer func() { _ = tx.Rollback() }()
+
+			morpher := dmorph.Morpher{Log: slog.New(slog.DiscardHandler), StrictTermination: true}
+
+			err := morpher.TapplyStepsStream(t.Context(), tx, bytes.NewBufferString(test.script), "test")
+
+			if test.wantErr {
+				assert.ErrorIs(t, err, dmorph.ErrStatementUnterminated, "expected unterminated statement")
+			} else {
+				assert.NoError(t, err, "expected no error")
+			}
+		})
+	}
+}
+
+// TestApplyStepsStreamBatchSeparator verifies that a batch separator of the dialect separates steps.
+func TestApplyStepsStreamBatchSeparator(t *testing.T) {
+	t.Parallel()
+
+	dialect := dmorph.DialectSQLite()
+	dialect.BatchSeparator = "GO"
+
+	db := openTempSQLite(t)
+
+	migrations := fstest.MapFS{
+		"01_base.sql": {Data: []byte(
+			"CREATE TABLE t0 (id INTEGER)\nGO\n" +
+				"INSERT INTO t0 VALUES (1)\n  go  \n" +
+				"INSERT INTO t0 VALUES (2)\n;\n" +
+				"INSERT INTO t0 VALUES (3)\n")},
+	}
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dialect),
+		dmorph.WithMigrationsFromFS(migrations))
+
+	require.NoError(t, runErr, "expected no error")
+
+	var count int
+
+	require.NoError(t, db.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM t0").Scan(&count))
+	assert.Equal(t, 3, count, "expected all steps to be executed")
+
+	runErr = dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations))
+
+	assert.Error(t, runErr, "expected GO to be executed without batch separator")
+}
+
+// TestWithStrictTermination verifies that the strict termination option applies to file migrations.
+func TestWithStrictTermination(t *testing.T) {
+	t.Parallel()
+
+	migrations := fstest.MapFS{"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")}}
+
+	runErr := dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations),
+		dmorph.WithStrictTermination(true))
+
+	assert.ErrorIs(t, runErr, dmorph.ErrStatementUnterminated, "expected unterminated statement")
+}
+
+// TestWithStripComments verifies that leading comments are passed on to the database if stripping is disabled.
+func TestWithStripComments(t *testing.T) {
+	t.Parallel()
+
+	script := "-- SPDX-License-Identifier: MPL-2.0\n\nCREATE TABLE t0 (id INTEGER)\n;\n" +
+		"-- the view\nCREATE VIEW v0 AS SELECT id FROM t0\n"
+
+	tests := []struct {
+		strip bool
+		want  []string
+	}{
+		{strip: true, want: []string{"CREATE TABLE t0 (id INTEGER)", "CREATE VIEW v0 AS SELECT id FROM t0"}},
+		{strip: false, want: []string{
+			"-- SPDX-License-Identifier: MPL-2.0\n\nCREATE TABLE t0 (id INTEGER)",
+			"-- the view\nCREATE VIEW v0 AS SELECT id FROM t0",
+		}},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestWithStripComments-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			morpher, morpherErr := dmorph.NewMorpher(
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrationsFromFS(fstest.MapFS{"01_base.sql": {Data: []byte(script)}}),
+				dmorph.WithStripComments(test.strip))
+
+			require.NoError(t, morpherErr, "expected no error creating the morpher")
+
+			got, err := morpher.TscanSteps(bytes.NewBufferString(script))
+
+			require.NoError(t, err, "expected no error")
+			assert.Equal(t, test.want, got, "expected statements as executed")
+
+			assert.NoError(t, morpher.Run(t.Context(), openTempSQLite(t)), "expected statements to be accepted")
+		})
+	}
+}
+
+// TestScanStepsTrailingComments verifies that comments trailing the last statement are never executed, neither
+// as part of the final statement nor as a statement of their own, regardless of keeping comments.
+func TestScanStepsTrailingComments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		script  string
+		options []dmorph.MorphOption
+		want    []string
+		wantErr error
+	}{
+		{ // final statement followed by a trailing comment
+			script: "CREATE TABLE t0 (\n    id INTEGER\n);\n\n-- done\n",
+			want:   []string{"CREATE TABLE t0 (\n    id INTEGER\n);"},
+		},
+		{ // final statement followed by a trailing comment, comments kept
+			script:  "CREATE TABLE t0 (\n    id INTEGER\n);\n\n-- done\n",
+			options: []dmorph.MorphOption{dmorph.WithStripComments(false)},
+			want:    []string{"CREATE TABLE t0 (\n    id INTEGER\n);"},
+		},
+		{ // separator followed by a trailing comment, comments kept
+			script:  "CREATE TABLE t0 (id INTEGER)\n;\n\n-- done\n-- really\n",
+			options: []dmorph.MorphOption{dmorph.WithStripComments(false)},
+			want:    []string{"CREATE TABLE t0 (id INTEGER)"},
+		},
+		{ // separator followed by a trailing comment, strict termination
+			script: "CREATE TABLE t0 (id INTEGER)\n;\n\n-- done\n",
+			options: []dmorph.MorphOption{
+				dmorph.WithStripComments(false),
+				dmorph.WithStrictTermination(true),
+			},
+			want: []string{"CREATE TABLE t0 (id INTEGER)"},
+		},
+		{ // final statement followed by a trailing comment, strict termination
+			script:  "CREATE TABLE t0 (id INTEGER);\n-- done\n",
+			options: []dmorph.MorphOption{dmorph.WithStrictTermination(true)},
+			wantErr: dmorph.ErrStatementUnterminated,
+		},
+		{ // comments inside the final statement are kept
+			script: "CREATE TABLE t0 (\n-- the id\n    id INTEGER\n);\n-- done",
+			want:   []string{"CREATE TABLE t0 (\n-- the id\n    id INTEGER\n);"},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestScanStepsTrailingComments-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			morpher, morpherErr := dmorph.NewMorpher(append([]dmorph.MorphOption{
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrationsFromFS(fstest.MapFS{"01_base.sql": {Data: []byte(test.script)}}),
+			}, test.options...)...)
+
+			require.NoError(t, morpherErr, "expected no error creating the morpher")
+
+			got, err := morpher.TscanSteps(bytes.NewBufferString(test.script))
+
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr, "expected error")
+
+				return
+			}
+
+			require.NoError(t, err, "expected no error")
+			assert.Equal(t, test.want, got, "expected trailing comments to be ignored")
+
+			assert.NoError(t, morpher.Run(t.Context(), openTempSQLite(t)), "expected statements to be accepted")
+		})
+	}
+}
+
+// TestWithMigrationsFromFilesFSSubset verifies that only the named files are applied, ordered by their keys.
+func TestWithMigrationsFromFilesFSSubset(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	migrations := fstest.MapFS{
+		"01_base.sql":  {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+		"02_addon.sql": {Data: []byte("CREATE TABLE t1 (id INTEGER)")},
+		"03_more.sql":  {Data: []byte("CREATE TABLE t2 (id INTEGER)")},
+	}
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFilesFS(migrations, "03_more.sql", "01_base.sql"))
+
+	require.NoError(t, runErr, "expected no error")
+
+	applied, appliedErr := dmorph.DialectSQLite().AppliedMigrations(t.Context(), db,
+		dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+	require.NoError(t, appliedErr, "expected no error getting applied migrations")
+	assert.Equal(t, []string{"01_base.sql", "03_more.sql"}, applied, "expected the subset applied in order")
+}
+
+// TestWithPreflight verifies that no migration is applied if a later migration file is missing or malformed.
+func TestWithPreflight(t *testing.T) {
+	t.Parallel()
+
+	migrations := fstest.MapFS{
+		"01_base.sql":         {Data: []byte("CREATE TABLE t0 (id INTEGER)\n;\n")},
+		"02_unterminated.sql": {Data: []byte("CREATE TABLE t1 (id INTEGER)\n;\nCREATE TABLE t2 (\n")},
+	}
+
+	tests := []struct {
+		options []dmorph.MorphOption
+		wantErr error
+	}{
+		{
+			options: []dmorph.MorphOption{
+				dmorph.WithMigrationsFromFS(migrations),
+				dmorph.WithStrictTermination(true),
+			},
+			wantErr: dmorph.ErrStatementUnterminated,
+		},
+		{
+			options: []dmorph.MorphOption{
+				dmorph.WithMigrationsFromFilesFS(migrations, "01_base.sql", "02_missing.sql"),
+			},
+			wantErr: fs.ErrNotExist,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestWithPreflight-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			options := append([]dmorph.MorphOption{
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithPreflight(true),
+			}, test.options...)
+
+			runErr := dmorph.Run(t.Context(), db, options...)
+
+			require.ErrorIs(t, runErr, test.wantErr, "expected preflight to fail")
+
+			var count int
+
+			require.NoError(t, db.QueryRowContext(t.Context(),
+				"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 't0'").Scan(&count))
+			assert.Zero(t, count, "expected no migration to be applied")
+		})
+	}
+}
+
+// TestMigrationsFromFS verifies that the migrations of a filesystem can be listed without a Morpher, and be
+// applied using one.
+func TestMigrationsFromFS(t *testing.T) {
+	t.Parallel()
+
+	migrationsDir, migrationsDirErr := fs.Sub(testMigrationsDir, "testData")
+
+	require.NoError(t, migrationsDirErr, "migrations directory could not be opened")
+
+	migrations, err := dmorph.MigrationsFromFS(migrationsDir)
+
+	require.NoError(t, err, "migrations could not be listed")
+	require.Len(t, migrations, 2, "unexpected number of migrations")
+	assert.Equal(t, "01_base_table.sql", migrations[0].Key(), "unexpected first migration")
+	assert.Equal(t, "02_addon_table.sql", migrations[1].Key(), "unexpected second migration")
+
+	runErr := dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(migrations...))
+
+	require.NoError(t, runErr, "migrations could not be run")
+
+	_, err = dmorph.MigrationsFromFS(os.DirFS("testData/nonexistent"))
+
+	assert.Error(t, err, "expected error on nonexistent directory")
+}
+
+// TestWithCacheParsedMigrations verifies that the cached steps match the file at its first use and are reused
+// by later runs.
+func TestWithCacheParsedMigrations(t *testing.T) {
+	t.Parallel()
+
+	script := "CREATE TABLE t0 (id INTEGER)\n;\nINSERT INTO t0 VALUES (1)\n;\nINSERT INTO t0 VALUES (2)\n"
+
+	migrations := fstest.MapFS{"01_base.sql": {Data: []byte(script)}}
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations),
+		dmorph.WithCacheParsedMigrations(true))
+
+	require.NoError(t, morpherErr, "expected no error creating the morpher")
+
+	for run := range 2 {
+		db := openTempSQLite(t)
+
+		require.NoError(t, morpher.Run(t.Context(), db), "expected no error in run %d", run)
+
+		var count int
+
+		require.NoError(t, db.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM t0").Scan(&count))
+		assert.Equal(t, 2, count, "expected all steps of the file to be executed in run %d", run)
+
+		// later runs use the cached steps, not noticing the change of the file
+		migrations["01_base.sql"].Data = []byte("utter nonsense")
+	}
+}
+
+// BenchmarkRunFileMigrations measures repeated runs of many file migrations, with and without caching the
+// parsed steps.
+func BenchmarkRunFileMigrations(b *testing.B) {
+	migrations := fstest.MapFS{}
+
+	for i := range 100 {
+		migrations[fmt.Sprintf("%03d_benchmark.sql", i)] = &fstest.MapFile{Data: []byte(
+			"-- benchmark migration\n" +
+				fmt.Sprintf("CREATE TABLE t%d (id INTEGER)\n;\n", i) +
+				fmt.Sprintf("INSERT INTO t%d VALUES (1)\n;\n", i))}
+	}
+
+	for _, cache := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cache-%v", cache), func(b *testing.B) {
+			morpher, err := dmorph.NewMorpher(
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithLog(slog.New(slog.DiscardHandler)),
+				dmorph.WithMigrationsFromFS(migrations),
+				dmorph.WithCacheParsedMigrations(cache))
+
+			require.NoError(b, err, "morpher could not be created")
+
+			for b.Loop() {
+				db, err := sql.Open("sqlite3", ":memory:")
+				require.NoError(b, err, "DB could not be opened")
+
+				db.SetMaxOpenConns(1)
+
+				require.NoError(b, morpher.Run(b.Context(), db))
+
+				_ = db.Close()
+			}
+		})
+	}
+}
+
+// TestWithStatementTransform verifies that the statements are rewritten before their execution.
+func TestWithStatementTransform(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	migrations := fstest.MapFS{
+		"01_base.sql": {Data: []byte("CREATE TABLE t0 (id SERIAL PRIMARY KEY, name TEXT)\n;\n" +
+			"INSERT INTO t0 (name) VALUES ('a')\n")},
+	}
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations),
+		dmorph.WithStatementTransform(func(statement string) string {
+			return strings.ReplaceAll(statement, "id SERIAL PRIMARY KEY", "id INTEGER PRIMARY KEY AUTOINCREMENT")
+		}))
+
+	require.NoError(t, runErr, "expected no error")
+
+	var schema string
+
+	require.NoError(t, db.QueryRowContext(t.Context(),
+		"SELECT sql FROM sqlite_master WHERE name = 't0'").Scan(&schema))
+	assert.Contains(t, schema, "AUTOINCREMENT", "expected the transformed statement to be executed")
+}
+
+// TestWithTransactionPerStep verifies that each step is committed separately, so that the steps before a
+// failing one stay applied, while the migration is not registered.
+func TestWithTransactionPerStep(t *testing.T) {
+	t.Parallel()
+
+	migrations := fstest.MapFS{
+		"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)\n;\n" +
+			"CREATE TABLE t1 (id INTEGER)\n;\nutter nonsense\n")},
+	}
+
+	tests := []struct {
+		perStep    bool
+		wantTables int
+	}{
+		{perStep: false, wantTables: 0},
+		{perStep: true, wantTables: 2},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestWithTransactionPerStep-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			runErr := dmorph.Run(t.Context(),
+				db,
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrationsFromFS(migrations),
+				dmorph.WithTransactionPerStep(test.perStep))
+
+			require.Error(t, runErr, "expected the last step to fail")
+
+			var count int
+
+			require.NoError(t, db.QueryRowContext(t.Context(),
+				"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('t0', 't1')").Scan(&count))
+			assert.Equal(t, test.wantTables, count, "expected committed steps")
+
+			applied, appliedErr := dmorph.DialectSQLite().AppliedMigrations(t.Context(), db,
+				dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+			require.NoError(t, appliedErr, "expected no error getting applied migrations")
+			assert.Empty(t, applied, "expected the migration not to be registered")
+		})
+	}
+
+	db := openTempSQLite(t)
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFilesFS(fstest.MapFS{"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")}},
+			"01_base.sql"),
+		dmorph.WithTransactionPerStep(true))
+
+	require.NoError(t, runErr, "expected no error")
+
+	applied, appliedErr := dmorph.DialectSQLite().AppliedMigrations(t.Context(), db,
+		dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+	require.NoError(t, appliedErr, "expected no error getting applied migrations")
+	assert.Equal(t, []string{"01_base.sql"}, applied, "expected the migration to be registered")
+}
+
+// TestMigrationRequires verifies that the requirements declared by migration files are validated before any
+// migration is applied.
+func TestMigrationRequires(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		migrations fstest.MapFS
+		wantErr    bool
+	}{
+		{ // required migration ordered before
+			migrations: fstest.MapFS{
+				"01_base.sql":  {Data: []byte("CREATE TABLE t0 (id INTEGER);")},
+				"02_other.sql": {Data: []byte("CREATE TABLE t1 (id INTEGER);")},
+				"03_data.sql":  {Data: []byte("-- dmorph:requires 01_base.sql\nINSERT INTO t0 (id) VALUES (1);")},
+			},
+		},
+		{ // required migration ordered after
+			migrations: fstest.MapFS{
+				"01_data.sql": {Data: []byte("-- dmorph:requires 02_base.sql\nINSERT INTO t0 (id) VALUES (1);")},
+				"02_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER);")},
+			},
+			wantErr: true,
+		},
+		{ // required migration missing
+			migrations: fstest.MapFS{
+				"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER);")},
+				"02_data.sql": {Data: []byte("-- dmorph:requires 01_base.sql, 00_init.sql\nSELECT 1;")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestMigrationRequires-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			runErr := dmorph.Run(t.Context(),
+				db,
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrationsFromFS(test.migrations))
+
+			if !test.wantErr {
+				require.NoError(t, runErr, "expected satisfied requirements")
+
+				return
+			}
+
+			require.ErrorIs(t, runErr, dmorph.ErrMigrationRequirement, "expected unmet requirement")
+
+			applied, appliedErr := dmorph.DialectSQLite().AppliedMigrations(t.Context(), db,
+				dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+			require.NoError(t, appliedErr, "could not get applied migrations")
+			assert.Empty(t, applied, "expected no migration to be applied")
+		})
+	}
+}
+
+// TestMigrationRequiresApplied verifies that requirements already applied in an earlier run are satisfied.
+func TestMigrationRequiresApplied(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	migrations := fstest.MapFS{
+		"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER);")},
+	}
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations)), "first run failed")
+
+	migrations["02_data.sql"] = &fstest.MapFile{Data: []byte("-- dmorph:requires 01_base.sql\nINSERT INTO t0 VALUES (1);")}
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations)), "expected applied requirement to be satisfied")
+}
+
+// TestMigrationOrigin verifies that the origin of each migration is logged, distinguishing the sources of
+// merged migrations.
+func TestMigrationOrigin(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "04_file.sql")
+
+	require.NoError(t, os.WriteFile(file, []byte("CREATE TABLE t1 (id INTEGER)"), 0o600), "could not write file")
+
+	buf := bytes.Buffer{}
+
+	runErr := dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithLog(slog.New(slog.NewTextHandler(&buf, nil))),
+		dmorph.WithMigrationsFromSubFS(testMigrationsDir, "testData"),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{"03_plugin.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")}}),
+		dmorph.WithMigrationsFromFiles(file),
+		dmorph.WithMigrations(dmorph.LegacyMigration("05_func", func(_ *sql.Tx) error { return nil })))
+
+	require.NoError(t, runErr, "migrations could not be run")
+
+	for _, want := range []string{
+		"file=01_base_table.sql origin=embed:testData/01_base_table.sql",
+		"file=03_plugin.sql origin=fs:03_plugin.sql",
+		"file=" + file + " origin=file:" + file,
+		"file=05_func origin=func",
+	} {
+		assert.Contains(t, buf.String(), "msg=\"migration applied\" dialect=sqlite "+want, "origin not logged")
+	}
+}
+
+// TestMigrationIsolation verifies that the isolation level declared by a migration overrides the configured
+// transaction options. The SQLite driver rejects isolation levels it does not support.
+func TestMigrationIsolation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		options   []dmorph.MorphOption
+		directive string
+		wantErr   string
+	}{
+		{ // declared isolation level supported
+			directive: "-- dmorph:isolation serializable\n",
+		},
+		{ // declared isolation level overrides the unsupported configured one
+			options:   []dmorph.MorphOption{dmorph.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead})},
+			directive: "-- dmorph:isolation Serializable\n",
+		},
+		{ // configured isolation level applies without directive
+			options: []dmorph.MorphOption{dmorph.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead})},
+			wantErr: "isolation",
+		},
+		{ // declared isolation level unsupported
+			directive: "-- dmorph:isolation read_committed\n",
+			wantErr:   "isolation",
+		},
+		{ // declared isolation level unknown
+			directive: "-- dmorph:isolation careful\n",
+			wantErr:   dmorph.ErrIsolationLevelUnknown.Error(),
+		},
+		{ // declared isolation level applies to each step
+			options:   []dmorph.MorphOption{dmorph.WithTransactionPerStep(true)},
+			directive: "-- dmorph:isolation read-committed\n",
+			wantErr:   "isolation",
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestMigrationIsolation-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			runErr := dmorph.Run(t.Context(),
+				openTempSQLite(t),
+				append([]dmorph.MorphOption{
+					dmorph.WithDialect(dmorph.DialectSQLite()),
+					dmorph.WithMigrationsFromFS(fstest.MapFS{
+						"01_data.sql": {Data: []byte(test.directive + "CREATE TABLE t0 (id INTEGER);")},
+					}),
+				}, test.options...)...)
+
+			if test.wantErr == "" {
+				assert.NoError(t, runErr, "expected migration to be applied")
+			} else {
+				assert.ErrorContains(t, runErr, test.wantErr, "expected isolation level to be applied")
+			}
+		})
+	}
+}
+
+// TestMigrationDiagnostics verifies that the results of diagnostic queries are logged, and that failing ones do
+// not affect the migration.
+func TestMigrationDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	for _, perStep := range []bool{false, true} {
+		t.Run(fmt.Sprintf("TestMigrationDiagnostics-%t", perStep), func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.Buffer{}
+			db := openTempSQLite(t)
+
+			runErr := dmorph.Run(t.Context(),
+				db,
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithLog(slog.New(slog.NewTextHandler(&buf, nil))),
+				dmorph.WithTransactionPerStep(perStep),
+				dmorph.WithMigrationsFromFS(fstest.MapFS{
+					"01_data.sql": {Data: []byte("-- dmorph:log SELECT count(*) FROM t0\n" +
+						"-- dmorph:log SELECT nothing FROM missing\n" +
+						"CREATE TABLE t0 (id INTEGER);\nINSERT INTO t0 (id) VALUES (1), (2);")},
+				}))
+
+			require.NoError(t, runErr, "failing diagnostic affected the migration")
+			assert.Equal(t, []string{"01_data.sql"}, appliedSQLite(t, db), "migration not applied")
+			assert.Contains(t, buf.String(),
+				`msg="migration diagnostic" dialect=sqlite file=01_data.sql query="SELECT count(*) FROM t0" value=2`,
+				"diagnostic not logged")
+			assert.Contains(t, buf.String(),
+				`msg="migration diagnostic failed" dialect=sqlite file=01_data.sql query="SELECT nothing FROM missing"`,
+				"failing diagnostic not logged")
+		})
+	}
+}
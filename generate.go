@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SQLRenderer is an optional interface for a Dialect to render its statements without executing them.
+type SQLRenderer interface {
+	RenderCreate(tableName string) (string, error)
+	RenderRegisterMigration(tableName string, id string, groupName string) (string, error)
+}
+
+// TableProber is an optional interface for a Dialect to check the existence of the migration table.
+type TableProber interface {
+	MigrationTableExists(ctx context.Context, db *sql.DB, tableName string) (bool, error)
+}
+
+// sqlSource is implemented by migrations consisting of SQL only, giving access to it.
+type sqlSource interface {
+	open() (io.ReadCloser, error)
+}
+
+// GenerateSQL writes the SQL script that Run would execute on the given database to w, without executing it.
+// The script contains the creation of the migration table and, for each pending migration, its steps followed
+// by the statement registering it. Each statement is terminated by a separator line, as used in migration
+// files. The database is only read, to determine the pending migrations. The dialect has to implement the
+// SQLRenderer interface and all pending migrations have to consist of SQL only, like the FileMigration,
+// otherwise ErrRenderUnsupported is returned.
+func (m *Morpher) GenerateSQL(ctx context.Context, db *sql.DB, w io.Writer) error {
+	if db == nil {
+		return ErrNilDB
+	}
+
+	if validErr := m.IsValid(); validErr != nil {
+		return validErr
+	}
+
+	renderer, isRenderer := m.Dialect.(SQLRenderer)
+
+	if !isRenderer {
+		return ErrRenderUnsupported
+	}
+
+	m, lastMigration, lastErr := m.readLastMigration(ctx, db)
+
+	if lastErr != nil {
+		return lastErr
+	}
+
+	create, createErr := renderer.RenderCreate(m.TableName)
+
+	if createErr != nil {
+		return fmt.Errorf("could not render migration table creation: %w", createErr)
+	}
+
+	if err := writeStatement(w, "-- migration table", create); err != nil {
+		return err
+	}
+
+	for _, migration := range m.pendingMigrations(lastMigration) {
+		if err := m.generateMigrationSQL(ctx, w, renderer, migration); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readAppliedMigrations reads the applied migrations without creating the migration table. If the dialect can
+// determine that the migration table does not exist, no migrations are applied.
+func (m *Morpher) readAppliedMigrations(ctx context.Context, db *sql.DB) ([]string, error) {
+	if missing, err := m.migrationTableMissing(ctx, db); missing || err != nil {
+		return nil, err
+	}
+
+	appliedMigrations, err := m.Dialect.AppliedMigrations(ctx, db, m.TableName, m.GroupName)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get applied migrations: %w", err)
+	}
+
+	return appliedMigrations, nil
+}
+
+// migrationTableMissing checks if the dialect can determine that the migration table does not exist.
+func (m *Morpher) migrationTableMissing(ctx context.Context, db *sql.DB) (bool, error) {
+	prober, isProber := m.Dialect.(TableProber)
+
+	if !isProber {
+		return false, nil
+	}
+
+	exists, err := prober.MigrationTableExists(ctx, db, m.TableName)
+
+	if errors.Is(err, ErrIntegrityCheckUnsupported) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("could not check migration table: %w", err)
+	}
+
+	return !exists, nil
+}
+
+// generateMigrationSQL writes the steps of the given migration and the statement registering it to w.
+func (m *Morpher) generateMigrationSQL(
+	ctx context.Context,
+	w io.Writer,
+	renderer SQLRenderer,
+	migration Migration) error {
+
+	source, isSource := migration.(sqlSource)
+
+	if !isSource {
+		return fmt.Errorf("%w: migration %s is not SQL based", ErrRenderUnsupported, migration.Key())
+	}
+
+	r, openErr := source.open()
+
+	if openErr != nil {
+		return openErr
+	}
+
+	defer func() { _ = r.Close() }()
+
+	cfg := m.stepsConfig()
+
+	scanErr := scanSteps(ctx, r, migration.Key(), cfg, func(step int, statement string, _ bool) error {
+		return writeStatement(w, fmt.Sprintf("-- migration %s step %d", migration.Key(), step),
+			cfg.transform(statement))
+	})
+
+	if scanErr != nil {
+		return scanErr
+	}
+
+	register, registerErr := renderer.RenderRegisterMigration(m.TableName, migration.Key(), m.GroupName)
+
+	if registerErr != nil {
+		return fmt.Errorf("could not render registration of migration %s: %w", migration.Key(), registerErr)
+	}
+
+	return writeStatement(w, "-- register migration "+migration.Key(), register)
+}
+
+// writeStatement writes the given comment and statement to w, terminated by a separator line.
+func writeStatement(w io.Writer, comment string, statement string) error {
+	_, err := fmt.Fprintf(w, "%s\n%s\n;\n", comment, statement)
+
+	return wrapIfError("could not write statement", err)
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// TestGenerateSQL verifies that the generated SQL script is equivalent to running the migrations.
+func TestGenerateSQL(t *testing.T) {
+	t.Parallel()
+
+	migrationsDir, migrationsDirErr := fs.Sub(testMigrationsDir, "testData")
+
+	require.NoError(t, migrationsDirErr, "migrations directory could not be opened")
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrationsDir))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	db := openTempSQLite(t)
+	script := bytes.Buffer{}
+
+	require.NoError(t, morpher.GenerateSQL(t.Context(), db, &script), "script could not be generated")
+
+	assert.Contains(t, script.String(), `CREATE TABLE IF NOT EXISTS "migrations"`, "creation missing")
+	assert.Contains(t, script.String(), `VALUES('02_addon_table.sql', 'default')`, "registration missing")
+
+	var tables int
+
+	require.NoError(t, db.QueryRowContext(t.Context(),
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&tables))
+	assert.Zero(t, tables, "generating the script wrote to the database")
+
+	for statement := range strings.SplitSeq(script.String(), "\n;\n") {
+		if strings.TrimSpace(statement) != "" {
+			_, execErr := db.ExecContext(t.Context(), statement)
+
+			require.NoError(t, execErr, "statement %q could not be executed", statement)
+		}
+	}
+
+	changed, runErr := morpher.RunChanged(t.Context(), db)
+
+	require.NoError(t, runErr, "migrations could not be run")
+	assert.False(t, changed, "script did not apply all migrations")
+
+	script.Reset()
+
+	require.NoError(t, morpher.GenerateSQL(t.Context(), db, &script), "script could not be generated")
+	assert.NotContains(t, script.String(), "-- register migration", "applied migrations in script")
+}
+
+// TestGenerateSQLUnsupported verifies that migrations that are not SQL based are reported.
+func TestGenerateSQLUnsupported(t *testing.T) {
+	t.Parallel()
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	assert.ErrorIs(t,
+		morpher.GenerateSQL(t.Context(), openTempSQLite(t), &bytes.Buffer{}),
+		dmorph.ErrRenderUnsupported,
+		"programmatic migration not reported")
+}
+
+// TestRenderRegisterMigration verifies that the parameters of the registration are replaced by literals.
+func TestRenderRegisterMigration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		renderer dmorph.SQLRenderer
+		want     string
+	}{
+		{name: "MSSQL", renderer: dmorph.DialectMSSQL(), want: "VALUES ('01_it''s', 'default')"},
+		{name: "MySQL", renderer: dmorph.DialectMySQL(), want: "VALUES('01_it''s', 'default')"},
+		{name: "SQLite", renderer: dmorph.DialectSQLite(), want: "VALUES('01_it''s', 'default')"},
+	}
+
+	for _, test := range tests {
+		statement, err := test.renderer.RenderRegisterMigration("migrations", "01_it's", "default")
+
+		require.NoError(t, err, "statement could not be rendered for %v", test.name)
+		assert.Contains(t, statement, test.want, "wrong statement for %v", test.name)
+	}
+}
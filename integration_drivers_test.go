@@ -0,0 +1,16 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build integration
+
+package dmorph_test
+
+// The drivers of the database management systems run by the integration tests, registered as `pgx` and
+// `postgres` for PostgreSQL, `mysql` for MySQL and `sqlserver` for Microsoft SQL Server. SQLite uses the driver
+// of the regular test suite, registered as `sqlite3`.
+import (
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/lib/pq"
+	_ "github.com/microsoft/go-mssqldb"
+)
@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build integration
+
+package dmorph_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/AlphaOne1/dmorph"
+	"github.com/AlphaOne1/dmorph/dmorphtest"
+)
+
+// TestIntegration runs the integration scenarios against all configured database management systems.
+func TestIntegration(t *testing.T) {
+	dmorphtest.RunAll(t)
+}
+
+// benchmarkSeedRows is the number of rows loaded per iteration of the seed benchmarks.
+const benchmarkSeedRows = 10000
+
+// benchmarkSeed runs the given seed function in a transaction for each iteration, against the configured
+// Postgres database.
+func benchmarkSeed(b *testing.B, seed func(ctx context.Context, tx *sql.Tx, rows [][]any) error) {
+	b.Helper()
+
+	db := dmorphtest.Open(b, dmorphtest.Target{Name: "Postgres", Dialect: dmorph.DialectPostgres()})
+
+	rows := make([][]any, 0, benchmarkSeedRows)
+
+	for i := range benchmarkSeedRows {
+		rows = append(rows, []any{i, "seed"})
+	}
+
+	for b.Loop() {
+		tx, txErr := db.BeginTx(b.Context(), nil)
+
+		if txErr != nil {
+			b.Fatalf("could not begin tx: %v", txErr)
+		}
+
+		if _, err := tx.ExecContext(b.Context(), "CREATE TEMPORARY TABLE seed (id INTEGER, name TEXT)"); err != nil {
+			b.Fatalf("could not create table: %v", err)
+		}
+
+		if err := seed(b.Context(), tx, rows); err != nil {
+			b.Fatalf("could not seed: %v", err)
+		}
+
+		_ = tx.Rollback()
+	}
+}
+
+// BenchmarkSeedCopyFrom loads the seed rows using the COPY protocol.
+func BenchmarkSeedCopyFrom(b *testing.B) {
+	benchmarkSeed(b, func(ctx context.Context, tx *sql.Tx, rows [][]any) error {
+		return dmorph.CopyFrom(ctx, tx, "seed", []string{"id", "name"}, rows)
+	})
+}
+
+// BenchmarkSeedInsert loads the seed rows using one INSERT statement per row.
+func BenchmarkSeedInsert(b *testing.B) {
+	benchmarkSeed(b, func(ctx context.Context, tx *sql.Tx, rows [][]any) error {
+		for _, row := range rows {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO seed (id, name) VALUES ($1, $2)", row...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
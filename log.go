@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogFunc is a logging function of a logging stack other than log/slog. It receives the context of the
+// operation logging, e.g. to extract correlation IDs, the name of the level, e.g. `INFO`, the message and the
+// attributes as slog.Attr values.
+type LogFunc func(ctx context.Context, level string, msg string, attrs ...any)
+
+// WithLogFunc sets a logging function, adapting DMorph to logging stacks other than log/slog. All messages are
+// passed to the function, that is responsible for filtering them by level. To use a slog.Handler extracting
+// values from the context instead, pass a logger using WithLog. In both cases, messages logged while running
+// the migrations get the context given to Run, while messages logged when applying the options, e.g. reading
+// migration directories, get the background context. A nil function discards all messages, like WithLog(nil).
+func WithLogFunc(fn LogFunc) MorphOption {
+	return func(m *Morpher) error {
+		if fn == nil {
+			m.Log = slog.New(slog.DiscardHandler)
+
+			return nil
+		}
+
+		m.Log = slog.New(logFuncHandler{fn: fn})
+
+		return nil
+	}
+}
+
+// logFuncHandler is a slog.Handler passing the records to a LogFunc.
+type logFuncHandler struct {
+	fn     LogFunc
+	attrs  []any    // attributes added using WithAttrs, already enclosed in their groups
+	groups []string // groups of the attributes of the records
+}
+
+// Enabled reports all levels as enabled, the LogFunc does the filtering.
+func (h logFuncHandler) Enabled(_ context.Context, _ slog.Level) bool {
+	return true
+}
+
+// Handle passes the record to the LogFunc.
+func (h logFuncHandler) Handle(ctx context.Context, record slog.Record) error {
+	recordAttrs := make([]slog.Attr, 0, record.NumAttrs())
+
+	record.Attrs(func(attr slog.Attr) bool {
+		recordAttrs = append(recordAttrs, attr)
+
+		return true
+	})
+
+	attrs := append(make([]any, 0, len(h.attrs)+len(recordAttrs)), h.attrs...)
+	attrs = append(attrs, h.grouped(recordAttrs)...)
+
+	h.fn(ctx, record.Level.String(), record.Message, attrs...)
+
+	return nil
+}
+
+// WithAttrs returns a handler adding the given attributes to all records.
+func (h logFuncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h.attrs = append(append(make([]any, 0, len(h.attrs)+len(attrs)), h.attrs...), h.grouped(attrs)...)
+
+	return h
+}
+
+// WithGroup returns a handler enclosing the attributes added later in the given group.
+func (h logFuncHandler) WithGroup(name string) slog.Handler {
+	if name != "" {
+		h.groups = append(append(make([]string, 0, len(h.groups)+1), h.groups...), name)
+	}
+
+	return h
+}
+
+// grouped encloses the given attributes in the groups of the handler.
+func (h logFuncHandler) grouped(attrs []slog.Attr) []any {
+	result := make([]any, 0, len(attrs))
+
+	for _, attr := range attrs {
+		result = append(result, attr)
+	}
+
+	for i := len(h.groups) - 1; i >= 0 && len(result) > 0; i-- {
+		result = []any{slog.Group(h.groups[i], result...)}
+	}
+
+	return result
+}
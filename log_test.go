@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// correlationKey is the context key of the correlation ID used in the logging tests.
+type correlationKey struct{}
+
+// correlationHandler adds the correlation ID of the context to each record.
+type correlationHandler struct {
+	slog.Handler
+}
+
+func (h correlationHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id, isID := ctx.Value(correlationKey{}).(string); isID {
+		record.AddAttrs(slog.String("correlation", id))
+	}
+
+	return h.Handler.Handle(ctx, record) //nolint:wrapcheck
+}
+
+func (h correlationHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return correlationHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// TestWithLogContext verifies that the context given to Run is passed to the log handler.
+func TestWithLogContext(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.Buffer{}
+
+	runErr := dmorph.Run(context.WithValue(t.Context(), correlationKey{}, "c0ffee"),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithLog(slog.New(correlationHandler{Handler: slog.NewTextHandler(&buf, nil)})),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, runErr, "migrations could not be run")
+	assert.Contains(t, buf.String(),
+		`msg="migration applied" dialect=sqlite file=01_test origin=func`, "migration not logged")
+	assert.Regexp(t, `msg="migration applied" .* correlation=c0ffee`, buf.String(), "correlation not logged")
+}
+
+// TestWithLogFunc verifies that all messages are passed to the logging function with their context.
+func TestWithLogFunc(t *testing.T) {
+	t.Parallel()
+
+	var lines []string
+
+	mutex := sync.Mutex{}
+
+	logFunc := func(ctx context.Context, level string, msg string, attrs ...any) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		lines = append(lines, fmt.Sprintf("%v %s %s %v", ctx.Value(correlationKey{}), level, msg, attrs))
+	}
+
+	db := openTempSQLite(t)
+
+	for range 2 {
+		runErr := dmorph.Run(context.WithValue(t.Context(), correlationKey{}, "c0ffee"),
+			db,
+			dmorph.WithDialect(dmorph.DialectSQLite()),
+			dmorph.WithMigrationsFromFS(fstest.MapFS{
+				"01_test.sql": {Data: []byte("-- dmorph:tags test\nCREATE TABLE t0 (id INTEGER)\n-- trailing")},
+			}),
+			dmorph.WithLogFunc(logFunc),
+			dmorph.WithPreflight(true))
+
+		require.NoError(t, runErr, "migrations could not be run")
+	}
+
+	logged := strings.Join(lines, "\n")
+
+	assert.Contains(t, logged, "c0ffee INFO applying migration [dialect=sqlite file=01_test.sql origin=",
+		"migration not logged")
+	assert.Contains(t, logged, "c0ffee DEBUG no previous migrations", "debug messages filtered")
+	assert.Contains(t, logged, "c0ffee DEBUG server version [dialect=sqlite version=", "server version not logged")
+	assert.Contains(t, logged, "c0ffee DEBUG last migration", "last migration not logged")
+	assert.Contains(t, logged, "c0ffee DEBUG preflight migration", "preflight not logged")
+	assert.Contains(t, logged, "c0ffee DEBUG migration directive", "directive not logged")
+	assert.Contains(t, logged, "c0ffee DEBUG trailing comments ignored", "trailing comments not logged")
+
+	for _, line := range lines {
+		assert.True(t, strings.HasPrefix(line, "c0ffee "), "context not passed for %q", line)
+	}
+}
+
+// TestWithLogFuncNil verifies that a nil logging function discards all messages.
+func TestWithLogFuncNil(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithLogFunc(nil),
+		dmorph.WithMigrations(oneMigration{key: "01_test"})),
+		"migrations could not be run")
+}
+
+// TestWithLogFuncGroups verifies that attributes are passed enclosed in their groups.
+func TestWithLogFuncGroups(t *testing.T) {
+	t.Parallel()
+
+	var got []any
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithLogFunc(func(_ context.Context, _ string, _ string, attrs ...any) { got = attrs }),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	morpher.Log.WithGroup("outer").With("a", 1).WithGroup("inner").Info("test", "b", 2)
+
+	assert.Equal(t, "[outer=[a=1] outer=[inner=[b=2]]]", fmt.Sprint(got), "wrong attributes")
+}
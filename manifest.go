@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// WithOrderManifest orders the migrations read from file systems by the manifest with the given name, e.g.
+// `dmorph.order`, instead of by their file names. The manifest lists one file name per line in the intended
+// order, empty lines and lines starting with `#` are ignored. This supports migration files whose names do not
+// sort correctly and cannot be renamed. Files listed in the manifest have to exist. Files not listed are
+// rejected with ErrManifestIncomplete, unless appendUnlisted is set, appending them ordered by their names.
+// Migrations not read from a file system with manifest are ordered after all listed ones. The option has to be
+// given before the options reading the migrations, as the manifest is read along with them.
+func WithOrderManifest(name string, appendUnlisted bool) MorphOption {
+	return func(m *Morpher) error {
+		if name == "" {
+			return fmt.Errorf("%w: no name", ErrManifestInvalid)
+		}
+
+		if slices.ContainsFunc(m.Migrations, func(mi Migration) bool {
+			file, isFile := mi.(FileMigration)
+
+			return isFile && file.FS != nil
+		}) {
+			return fmt.Errorf("%w: migrations read before the manifest was configured", ErrManifestInvalid)
+		}
+
+		m.Manifest = name
+		m.manifestAppend = appendUnlisted
+
+		return nil
+	}
+}
+
+// orderByManifest orders the given migrations read from the file system by its manifest and records their order
+// for later comparisons of their keys.
+func (m *Morpher) orderByManifest(d fs.FS, migrations []Migration) ([]Migration, error) {
+	content, err := fs.ReadFile(d, m.Manifest)
+
+	if err != nil {
+		return nil, wrapIfError("could not read manifest "+m.Manifest, err)
+	}
+
+	byKey := make(map[string]Migration, len(migrations))
+
+	for _, mi := range migrations {
+		byKey[mi.Key()] = mi
+	}
+
+	result := make([]Migration, 0, len(migrations))
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+
+		mi, found := byKey[name]
+
+		if !found {
+			return nil, fmt.Errorf("%w: %s listed, but no migration file", ErrManifestInvalid, name)
+		}
+
+		delete(byKey, name)
+
+		result = append(result, mi)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, wrapIfError("could not read manifest "+m.Manifest, err)
+	}
+
+	unlisted := slices.Sorted(func(yield func(string) bool) {
+		for key := range byKey {
+			if !yield(key) {
+				return
+			}
+		}
+	})
+
+	if len(unlisted) > 0 && !m.manifestAppend {
+		return nil, fmt.Errorf("%w: %s", ErrManifestIncomplete, strings.Join(unlisted, ", "))
+	}
+
+	for _, key := range unlisted {
+		result = append(result, byKey[key])
+	}
+
+	m.manifestOrder = append(m.manifestOrder, migrationKeys(result)...)
+
+	return result, nil
+}
+
+// applyManifestOrder replaces the key properties of the Morpher by ones ordering the keys recorded from manifests
+// by their position, and all other keys after them using the original order.
+func (m *Morpher) applyManifestOrder() {
+	if len(m.manifestOrder) == 0 {
+		return
+	}
+
+	position := make(map[string]int, len(m.manifestOrder))
+
+	for i, key := range m.manifestOrder {
+		position[key] = i
+	}
+
+	base := m.KeyProp.MigrationKeyOrder
+
+	keyOrder := func(a, b string) int {
+		posA, listedA := position[a]
+		posB, listedB := position[b]
+
+		switch {
+		case listedA && listedB:
+			return posA - posB
+		case listedA:
+			return -1
+		case listedB:
+			return 1
+		default:
+			return base(a, b)
+		}
+	}
+
+	m.KeyProp.MigrationKeyOrder = keyOrder
+	m.KeyProp.MigrationOrder = func(a, b Migration) int { return keyOrder(a.Key(), b.Key()) }
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// TestWithOrderManifest verifies that the manifest overrides the order of the file names. The insert migration
+// sorts before the migration creating its table and only succeeds if applied after it.
+func TestWithOrderManifest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		manifest       string
+		appendUnlisted bool
+		want           []string
+		wantErr        error
+	}{
+		{ // reordering manifest
+			manifest: "# apply order\nb_create.sql\n\n  a_insert.sql  \nc_update.sql\n",
+			want:     []string{"b_create.sql", "a_insert.sql", "c_update.sql"},
+		},
+		{ // unlisted file rejected
+			manifest: "b_create.sql\na_insert.sql\n",
+			wantErr:  dmorph.ErrManifestIncomplete,
+		},
+		{ // unlisted file appended
+			manifest:       "b_create.sql\na_insert.sql\n",
+			appendUnlisted: true,
+			want:           []string{"b_create.sql", "a_insert.sql", "c_update.sql"},
+		},
+		{ // listed file missing
+			manifest:       "b_create.sql\nd_missing.sql\na_insert.sql\n",
+			appendUnlisted: true,
+			wantErr:        dmorph.ErrManifestInvalid,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestWithOrderManifest-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			runErr := dmorph.Run(t.Context(),
+				db,
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithOrderManifest("dmorph.order", test.appendUnlisted),
+				dmorph.WithMigrationsFromFS(fstest.MapFS{
+					"dmorph.order": {Data: []byte(test.manifest)},
+					"a_insert.sql": {Data: []byte("INSERT INTO t0 (id) VALUES (1)")},
+					"b_create.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+					"c_update.sql": {Data: []byte("UPDATE t0 SET id = 2")},
+				}))
+
+			if test.wantErr != nil {
+				require.ErrorIs(t, runErr, test.wantErr, "expected manifest error")
+
+				return
+			}
+
+			require.NoError(t, runErr, "migrations could not be run")
+			assert.Equal(t, test.want, appliedSQLite(t, db), "wrong migrations applied")
+		})
+	}
+}
+
+// TestWithOrderManifestLate verifies that the manifest cannot be configured after reading the migrations.
+func TestWithOrderManifestLate(t *testing.T) {
+	t.Parallel()
+
+	_, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")}}),
+		dmorph.WithOrderManifest("dmorph.order", false))
+
+	assert.ErrorIs(t, morpherErr, dmorph.ErrManifestInvalid, "expected late manifest to be rejected")
+}
@@ -0,0 +1,24 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"io/fs"
+	"path"
+	"testing/fstest"
+)
+
+// MapFS returns an in-memory filesystem containing the given files, mapping their slash-separated paths to their
+// content. It allows testing migrations, e.g. their ordering, without embedded or on-disk files. The directories
+// of nested paths are created implicitly, so that WithMigrationsFromFS and WithMigrationsFromSubFS work on the
+// root and the subdirectories alike.
+func MapFS(files map[string]string) fs.ReadDirFS {
+	result := make(fstest.MapFS, len(files))
+
+	for name, content := range files {
+		result[path.Clean(name)] = &fstest.MapFile{Data: []byte(content), Mode: 0o444}
+	}
+
+	return result
+}
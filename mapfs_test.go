@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// TestMapFS verifies that the migrations of the root and of subdirectories of a MapFS are found and applied.
+func TestMapFS(t *testing.T) {
+	t.Parallel()
+
+	dir := dmorph.MapFS(map[string]string{
+		"01_base.sql":          "CREATE TABLE t0 (id INTEGER)",
+		"02_data.sql":          "INSERT INTO t0 (id) VALUES (1)",
+		"README.md":            "not a migration",
+		"tenant/01_tenant.sql": "CREATE TABLE t1 (id INTEGER)",
+		"tenant/02_data.sql":   "INSERT INTO t1 (id) VALUES (1)",
+	})
+
+	require.NoError(t, fstest.TestFS(dir, "01_base.sql", "tenant/02_data.sql"), "invalid filesystem")
+
+	db := openTempSQLite(t)
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(dir))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+	require.NoError(t, morpher.Run(t.Context(), db), "migrations could not be run")
+	assert.Equal(t, []string{"01_base.sql", "02_data.sql"}, appliedSQLite(t, db), "wrong root migrations")
+
+	tenantDB := openTempSQLite(t)
+
+	runErr := dmorph.Run(t.Context(),
+		tenantDB,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromSubFS(dir, "tenant"))
+
+	require.NoError(t, runErr, "sub directory migrations could not be run")
+	assert.Equal(t, []string{"01_tenant.sql", "02_data.sql"}, appliedSQLite(t, tenantDB),
+		"wrong sub directory migrations")
+}
@@ -10,9 +10,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 	"regexp"
 	"slices"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -21,15 +25,26 @@ const (
 
 	// MigrationGroupName is the default name for the migration group.
 	MigrationGroupName = "default"
+
+	// MigrationTableNameEnv is the environment variable overriding the default migration table name.
+	MigrationTableNameEnv = "DMORPH_TABLE"
 )
 
 var (
 	// ValidTableNameRex is the regular expression used to check if a given migration table name is valid.
 	ValidTableNameRex = regexp.MustCompile("^[a-zA-Z0-9_]+$")
 
+	// ValidQualifiedTableNameRex is the regular expression used to check if a given migration table name is a valid
+	// schema-qualified one, accepted for dialects implementing TableNameQualifier.
+	ValidQualifiedTableNameRex = regexp.MustCompile(`^[a-zA-Z0-9_]+\.[a-zA-Z0-9_]+$`)
+
 	// ErrMigrationKeyFormat is returned when a migration key does not match the expected format.
 	ErrMigrationKeyFormat = errors.New("migration key format invalid")
 
+	// ErrMigrationKeyDuplicate is returned when multiple migrations share the same key or cannot be ordered
+	// relative to each other, e.g. when merging migrations from multiple sources.
+	ErrMigrationKeyDuplicate = errors.New("migration key duplicate")
+
 	// ErrMigrationsUnrelated signals that the set of migrations to apply and the already applied set do not have the
 	// same (order of) applied migrations. Applying unrelated migrations could severely harm the database.
 	ErrMigrationsUnrelated = errors.New("migrations unrelated")
@@ -41,6 +56,12 @@ var (
 	// ErrNoDialect signals that no dialect for the database operations was chosen.
 	ErrNoDialect = errors.New("no dialect")
 
+	// ErrNilDB signals that no database was given to run the migrations on.
+	ErrNilDB = errors.New("nil database")
+
+	// ErrNilTx signals that no transaction was given to run the migrations in.
+	ErrNilTx = errors.New("nil transaction")
+
 	// ErrNoMigrations signals that no migrations were chosen to be applied.
 	ErrNoMigrations = errors.New("no migrations")
 
@@ -59,6 +80,138 @@ var (
 	// ErrParamNameInvalid occurs if the param name is invalid.
 	ErrParamNameInvalid = errors.New("invalid param name")
 
+	// ErrMigrationsPending signals that there are migrations not yet applied to the database while running in
+	// read-only mode.
+	ErrMigrationsPending = errors.New("migrations pending")
+
+	// ErrMigrationTableCorrupt signals that the structure of the migration table does not match the expected one.
+	ErrMigrationTableCorrupt = errors.New("migration table corrupt")
+
+	// ErrIntegrityCheckUnsupported occurs if an integrity check is requested, but the dialect does not support it.
+	ErrIntegrityCheckUnsupported = errors.New("integrity check unsupported")
+
+	// ErrTableListingUnsupported occurs if the migration tables are to be listed, but the dialect does not
+	// support it.
+	ErrTableListingUnsupported = errors.New("table listing unsupported")
+
+	// ErrVersionUnsupported occurs if the server version is requested, but the dialect does not support it.
+	ErrVersionUnsupported = errors.New("server version unsupported")
+
+	// ErrStatementUnterminated occurs in strict termination mode, if the last statement of a migration is not
+	// terminated by a separator.
+	ErrStatementUnterminated = errors.New("statement unterminated")
+
+	// ErrMigrationRegistered signals that a migration could not be registered, as it is already registered. This
+	// may happen if the same migrations are applied concurrently.
+	ErrMigrationRegistered = errors.New("migration already registered")
+
+	// ErrIdempotentRegisterUnsupported occurs if idempotent registration is requested, but the dialect does not
+	// support it.
+	ErrIdempotentRegisterUnsupported = errors.New("idempotent register unsupported")
+
+	// ErrHistoryUnsupported occurs if the migration history is requested, but the dialect does not support it.
+	ErrHistoryUnsupported = errors.New("history unsupported")
+
+	// ErrRegisterValuesUnsupported occurs if additional values are to be registered, but the dialect does not
+	// support it or idempotent registration is requested as well.
+	ErrRegisterValuesUnsupported = errors.New("register values unsupported")
+
+	// ErrCopyUnsupported occurs if CopyFrom is used with a database or driver not supporting the COPY protocol.
+	ErrCopyUnsupported = errors.New("copy unsupported")
+
+	// ErrRenderUnsupported occurs if the SQL of the migrations is to be generated, but the dialect or a
+	// migration does not support rendering it.
+	ErrRenderUnsupported = errors.New("render unsupported")
+
+	// ErrMigrationUnknown occurs if a single migration is to be applied, but no migration with its key is
+	// configured.
+	ErrMigrationUnknown = errors.New("migration unknown")
+
+	// ErrMigrationNotApplied occurs if a migration is to be re-applied, but it is not applied yet.
+	ErrMigrationNotApplied = errors.New("migration not applied")
+
+	// ErrMigrationKeyTooLong occurs if a migration key does not fit into the id column of the migration table.
+	ErrMigrationKeyTooLong = errors.New("migration key too long")
+
+	// ErrIDLengthInvalid occurs if the id column length is not positive or exceeds the limits of the dialect.
+	ErrIDLengthInvalid = errors.New("invalid id length")
+
+	// ErrDryRunUnsupported occurs if the migrations are to be validated first, but the dialect does not support
+	// rolling back DDL statements.
+	ErrDryRunUnsupported = errors.New("dry run unsupported")
+
+	// ErrMigrationValidation occurs if a migration fails while being validated, see WithValidateFirst.
+	ErrMigrationValidation = errors.New("migration validation failed")
+
+	// ErrDialectUnknown occurs if no dialect is registered for the scheme of a data source name.
+	ErrDialectUnknown = errors.New("dialect unknown")
+
+	// ErrDialectRegistered occurs if a dialect is to be registered for a scheme that is already registered.
+	ErrDialectRegistered = errors.New("dialect already registered")
+
+	// ErrDialectSchemeInvalid occurs if a dialect is to be registered for an empty or invalid scheme, or
+	// without factory.
+	ErrDialectSchemeInvalid = errors.New("invalid dialect scheme")
+
+	// ErrMigrationRequirement signals that a migration requires another migration, that is neither applied nor
+	// ordered before it.
+	ErrMigrationRequirement = errors.New("migration requirement unmet")
+
+	// ErrAutoBaselineUnsupported is returned if auto-baselining is requested, but the dialect cannot probe tables.
+	ErrAutoBaselineUnsupported = errors.New("auto baseline unsupported")
+
+	// ErrAutoBaselineInconsistent signals that only a part of the tables created by a migration exists, so that
+	// it can neither be baselined nor applied.
+	ErrAutoBaselineInconsistent = errors.New("auto baseline inconsistent")
+
+	// ErrIsolationLevelUnknown is returned if a migration declares an isolation level not known to database/sql.
+	ErrIsolationLevelUnknown = errors.New("isolation level unknown")
+
+	// ErrVerificationFailed signals that the migrations applied to the database do not equal the configured ones
+	// after running them, e.g. as another process modified the migration table concurrently.
+	ErrVerificationFailed = errors.New("migration verification failed")
+
+	// ErrManifestInvalid is returned if the order manifest cannot be used, e.g. as it lists missing files.
+	ErrManifestInvalid = errors.New("order manifest invalid")
+
+	// ErrManifestIncomplete signals that migration files are not listed in the order manifest.
+	ErrManifestIncomplete = errors.New("migrations not listed in order manifest")
+
+	// ErrVersionInvalid is returned if a version bound of a migration or the server version cannot be parsed.
+	ErrVersionInvalid = errors.New("version invalid")
+
+	// ErrVersionMismatch signals that a migration does not support the version of the database server, using
+	// VersionPolicyError.
+	ErrVersionMismatch = errors.New("server version not supported by migration")
+
+	// ErrRunTxUnsupported occurs if the migrations are to be run in a transaction of the caller, but the dialect
+	// cannot roll back DDL statements or the configured options require separate transactions.
+	ErrRunTxUnsupported = errors.New("run in transaction unsupported")
+
+	// ErrSchemaDumpUnsupported occurs if the schema is to be dumped, but the dialect does not support it.
+	ErrSchemaDumpUnsupported = errors.New("schema dump unsupported")
+
+	// ErrMigrationTableMissing occurs if the migration table is assumed to exist, but it does not, see Bootstrap.
+	ErrMigrationTableMissing = errors.New("migration table missing")
+
+	// ErrOrderColumnInvalid occurs if the order column of a dialect is not a plain identifier.
+	ErrOrderColumnInvalid = errors.New("invalid order column")
+
+	// ErrBatchSizeInvalid occurs if the batch size of BatchUpdate is less than 1.
+	ErrBatchSizeInvalid = errors.New("invalid batch size")
+
+	// ErrCategoryInvalid occurs if a migration declares more than one category.
+	ErrCategoryInvalid = errors.New("invalid migration category")
+
+	// ErrSavepointUnsupported occurs if savepoints are requested, but the dialect does not support them.
+	ErrSavepointUnsupported = errors.New("savepoints unsupported")
+
+	// ErrMigrationNotNext occurs if a migration is to be applied by ApplyOne, that is not the next pending one.
+	ErrMigrationNotNext = errors.New("migration not next")
+
+	// ErrBaselineUnknown occurs if migrations are to be squashed up to a migration that is not configured.
+	ErrBaselineUnknown = errors.New("baseline key unknown")
+
 	// ErrMigrationsTooOld signals that the migrations to be applied are older than the migrations that are already
 	// present in the database. This error can occur when an older version of the application is started using a database
 	// used already by a newer version of the application.
@@ -72,12 +225,227 @@ type Dialect interface {
 	RegisterMigration(ctx context.Context, tx *sql.Tx, id string, tableName string, groupName string) error
 }
 
+// NamedDialect is an optional interface for a Dialect to provide its name. The name is added to all log
+// messages of a Morpher.
+type NamedDialect interface {
+	Name() string
+}
+
+// StepSeparator is an optional interface for a Dialect to define a line separating the steps of file
+// migrations in addition to `;`, like the batch separator GO of Microsoft SQL Server. The separator is matched
+// case-insensitively and not executed.
+type StepSeparator interface {
+	StepSeparator() string
+}
+
+// KeyLengthLimiter is an optional interface for a Dialect to declare the maximum length of the migration keys,
+// as given by the id column of the migration table. A length of 0 declares no limit.
+type KeyLengthLimiter interface {
+	KeyLength() int
+}
+
+// DryRunner is an optional interface for a Dialect to declare that DDL statements can be rolled back, so that
+// migrations can be validated by executing them in a transaction that is rolled back afterward.
+type DryRunner interface {
+	DryRunSupported() bool
+}
+
+// Savepointer is an optional interface for a Dialect to enclose each migration of RunTx in a savepoint, see
+// WithSavepoints.
+type Savepointer interface {
+	SavepointsSupported() bool
+	Savepoint(ctx context.Context, tx *sql.Tx, name string) error
+	RollbackSavepoint(ctx context.Context, tx *sql.Tx, name string) error
+	ReleaseSavepoint(ctx context.Context, tx *sql.Tx, name string) error
+}
+
+// TxAnnotator is an optional interface for a Dialect to annotate the transactions of migrations with an
+// application name, see WithApplicationName.
+type TxAnnotator interface {
+	AnnotateTx(ctx context.Context, tx *sql.Tx, name string) error
+}
+
+// TableNameValidator is an optional interface for a Dialect to check the migration table name against the
+// constraints of the database, e.g. its maximum length, before running.
+type TableNameValidator interface {
+	ValidateTableName(name string) error
+}
+
+// TableNameQualifier is an optional interface for a Dialect to accept migration table names qualified by a
+// schema, matching ValidQualifiedTableNameRex, e.g. as it quotes their parts separately.
+type TableNameQualifier interface {
+	QualifiesTableName() bool
+}
+
+// IdentifierQuoter is an optional interface for a Dialect to quote possibly schema-qualified identifiers like it
+// does in its statements, see Morpher.EffectiveTableName.
+type IdentifierQuoter interface {
+	QuoteQualified(name string) string
+}
+
+// IdentifierFolder is an optional interface for a Dialect to normalize the case of identifiers as the database
+// does for unquoted ones, see WithFoldTableName.
+type IdentifierFolder interface {
+	FoldIdentifier(name string) string
+}
+
+// IntegrityChecker is an optional interface for a Dialect to verify the structure of the migration table.
+type IntegrityChecker interface {
+	IntegrityCheck(ctx context.Context, db *sql.DB, tableName string) error
+}
+
+// PreparedRegisterer is an optional interface for a Dialect to prepare the statement registering migrations once
+// per run, binding it to the transaction of each migration, instead of preparing it for every migration.
+type PreparedRegisterer interface {
+	PrepareRegister(ctx context.Context, db *sql.DB, tableName string) (*sql.Stmt, error)
+	RegisterMigrationStmt(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, id string, tableName string,
+		groupName string) error
+}
+
+// IdempotentRegisterer is an optional interface for a Dialect to register migrations, ignoring migrations
+// that are registered already.
+type IdempotentRegisterer interface {
+	RegisterMigrationIdempotent(ctx context.Context, tx *sql.Tx, id string, tableName string, groupName string) error
+}
+
+// AppliedMigration describes a migration applied to the database.
+type AppliedMigration struct {
+	ID      string    // key of the migration
+	Applied time.Time // time of the application
+}
+
+// HistoryPager is an optional interface for a Dialect to get the applied migrations page by page.
+type HistoryPager interface {
+	HistoryPage(ctx context.Context, db *sql.DB, tableName string, groupName string,
+		limit int, offset int) ([]AppliedMigration, error)
+}
+
+// LatestReader is an optional interface for a Dialect to get the migrations applied last, i.e. sharing the
+// latest timestamp, without reading all applied migrations.
+type LatestReader interface {
+	LatestMigrations(ctx context.Context, db *sql.DB, tableName string, groupName string) ([]string, error)
+}
+
+// ValueRegisterer is an optional interface for a Dialect to register migrations with additional values, e.g.
+// for governance columns of the migration table.
+type ValueRegisterer interface {
+	RegisterMigrationWithValues(ctx context.Context, tx *sql.Tx, id string, tableName string, groupName string,
+		values map[string]any) error
+}
+
+// TableCommenter is an optional interface for a Dialect to set a comment on the migration table when
+// ensuring its existence.
+type TableCommenter interface {
+	EnsureMigrationTableExistsWithComment(ctx context.Context, db *sql.DB, tableName string, comment string) error
+}
+
+// TableLister is an optional interface for a Dialect to discover all migration tables in a database.
+type TableLister interface {
+	ListMigrationTables(ctx context.Context, db *sql.DB) ([]string, error)
+}
+
+// VersionReporter is an optional interface for a Dialect to report the version of the database server, e.g. for
+// logging.
+type VersionReporter interface {
+	ServerVersion(ctx context.Context, db *sql.DB) (string, error)
+}
+
+// SchemaDumper is an optional interface for a Dialect to dump the DDL of all tables and indexes of the database,
+// see DumpSchema.
+type SchemaDumper interface {
+	DumpSchema(ctx context.Context, db *sql.DB) (string, error)
+}
+
+// TxDialect is an optional interface for a Dialect to ensure and read the migration table within a transaction
+// of the caller, see Morpher.RunTx.
+type TxDialect interface {
+	EnsureMigrationTableExistsTx(ctx context.Context, tx *sql.Tx, tableName string) error
+	AppliedMigrationsTx(ctx context.Context, tx *sql.Tx, tableName string, groupName string) ([]string, error)
+}
+
 // Migration is an interface to provide abstract information about the migration at hand.
 type Migration interface {
 	Key() string                                   // identifier, used for ordering
 	Migrate(ctx context.Context, tx *sql.Tx) error // migration functionality
 }
 
+// TaggedMigration is a Migration that is only applied if one of its tags is active. A TaggedMigration
+// without tags is always applied.
+type TaggedMigration interface {
+	Migration
+	Tags() ([]string, error) // tags restricting the migration to certain environments
+}
+
+// DependentMigration is a Migration that requires other migrations, not necessarily adjacent ones, to be applied
+// before it. The requirements are validated before any migration is applied, so that renaming or reordering
+// migrations does not silently break their dependencies.
+type DependentMigration interface {
+	Migration
+	Requires() ([]string, error) // keys of the migrations to be applied before
+}
+
+// VersionedMigration is a Migration that only supports a range of database server versions, e.g. as it uses
+// features introduced by a certain version. The bounds are inclusive, an empty bound does not restrict the
+// range. Migrations not supporting the server version are handled as configured using WithVersionPolicy.
+type VersionedMigration interface {
+	Migration
+	VersionRange() (minVersion string, maxVersion string, err error) // supported server versions
+}
+
+// CategorizedMigration is an optional interface for a Migration to declare its category, e.g. `schema` or
+// `data`, so that runs can be restricted to some categories, see WithCategories.
+type CategorizedMigration interface {
+	Migration
+	Category() (string, error) // category of the migration, empty if uncategorized
+}
+
+// IsolatedMigration is a Migration that requires specific options for its transaction, e.g. the isolation level
+// SERIALIZABLE for a data migration. If TxOptions returns nil, the options configured using WithTxOptions apply.
+type IsolatedMigration interface {
+	Migration
+	TxOptions() (*sql.TxOptions, error) // options of the transaction of the migration
+}
+
+// keyMigration is a Migration consisting of its key only, allowing to order keys by an order of migrations.
+type keyMigration string
+
+// Key returns the key of the migration.
+func (k keyMigration) Key() string {
+	return string(k)
+}
+
+// Migrate does nothing, as the migration only serves to compare keys.
+func (k keyMigration) Migrate(_ context.Context, _ *sql.Tx) error {
+	return nil
+}
+
+// legacyMigration adapts a migration function without context to the Migration interface.
+type legacyMigration struct {
+	key string
+	fn  func(tx *sql.Tx) error
+}
+
+// LegacyMigration returns a Migration with the given key executing the given function, that does not take a
+// context. It eases upgrading migrations written for the former, context-less, Migration interface.
+func LegacyMigration(key string, fn func(tx *sql.Tx) error) Migration {
+	return legacyMigration{key: key, fn: fn}
+}
+
+// Key returns the key of the migration.
+func (l legacyMigration) Key() string {
+	return l.key
+}
+
+// Migrate executes the migration function on the given transaction. The context is not passed on, but checked
+// before execution.
+func (l legacyMigration) Migrate(ctx context.Context, tx *sql.Tx) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context cancelled before migration %s: %w", l.key, err)
+	}
+
+	return l.fn(tx)
+}
+
 // migrationOrderAlphabetical is used to order Migration instances.
 func migrationOrderAlphabetical(m, n Migration) int {
 	return alphabeticalSortPredicate(m.Key(), n.Key())
@@ -119,13 +487,87 @@ func MigrationKeySemVerPrefix() MigrationKeyProperties {
 }
 
 // Morpher contains all the required information to run a given set of database migrations on a database.
+//
+// A configured Morpher is not modified by running it: Run, Pending, Status and the other methods applying or
+// inspecting migrations work on copies, e.g. of the sorted migrations, so they may be called concurrently, also
+// on different databases. Methods changing the configuration, like Squash, and the modification of the
+// exported fields must not happen concurrently with them. Concurrent runs on the same database are not
+// serialized, see WithIdempotentRegister.
 type Morpher struct {
-	Dialect    Dialect                // database vendor specific dialect
-	Migrations []Migration            // migrations to be applied
-	TableName  string                 // table name for migration management
-	GroupName  string                 // name of the migration group
-	KeyProp    MigrationKeyProperties // migration comparison mode
-	Log        *slog.Logger           // logger to be used
+	Dialect     Dialect                // database vendor specific dialect
+	Migrations  []Migration            // migrations to be applied
+	TableName   string                 // table name for migration management
+	GroupName   string                 // name of the migration group
+	KeyProp     MigrationKeyProperties // migration comparison mode
+	Log         *slog.Logger           // logger to be used
+	ReadOnly    bool                   // only check the database, never write to it
+	Tags        []string               // active tags selecting the TaggedMigration instances to apply
+	Integrity   bool                   // check the structure of the migration table before use
+	Comment     string                 // comment of the migration table, if supported by the dialect
+	Parallel    int                    // number of databases migrated concurrently by RunAll
+	FastPath    bool                   // skip the detailed checks if the database is already up to date
+	Baseline    Migration              // baseline replacing all migrations up to its key, see WithBaseline
+	Setup       []string               // statements executed before ensuring the migration table
+	AssumeTable bool                   // the migration table is created externally and is not ensured
+	Idempotent  bool                   // ignore migrations registered already, e.g. by a concurrent instance
+	AllowOlder  bool                   // only warn instead of failing if the database is newer than the migrations
+	Preflight   bool                   // read and split all pending file migrations before applying any
+	CreateTries int                    // attempts to create the migration table, see WithTableCreateRetry
+	CreateDelay time.Duration          // delay before the first retry of creating the migration table
+	DryRun      bool                   // execute all pending migrations in a rolled back transaction before applying
+	StepTx      bool                   // commit each step of file migrations separately, see WithTransactionPerStep
+	AutoBase    bool                   // register existing tables as applied migrations, see WithAutoBaselineExisting
+	TxOptions   *sql.TxOptions         // options of the migration transactions, unless given by an IsolatedMigration
+	Manifest    string                 // file ordering the file migrations, see WithOrderManifest
+	VerPolicy   VersionPolicy          // handling of migrations not supporting the server version
+	StepLog     int                    // log every nth step of file migrations, see WithStepLogEvery
+	Categories  []string               // selected categories of CategorizedMigration instances, all if empty
+	FoldName    bool                   // fold the table name to the case of the dialect, see WithFoldTableName
+	Savepoints  SavepointPolicy        // handling of failed migrations of RunTx, see WithSavepoints
+	AppName     string                 // application name annotating the migration transactions, optional
+
+	// StrictTermination requires every statement of a file migration to be terminated by a separator.
+	StrictTermination bool
+
+	// KeepComments passes the leading comments of the statements of a file migration on to the database.
+	KeepComments bool
+
+	// Transform rewrites each statement of a file migration before its execution.
+	Transform func(statement string) string
+
+	// OnError decides whether to continue after a failed migration.
+	OnError func(key string, err error) error
+
+	// OnSkip is called for each migration skipped by Run, as it is already applied.
+	OnSkip func(key string)
+
+	// AfterEnsureTable is called right after ensuring the existence of the migration table.
+	AfterEnsureTable func(ctx context.Context, db *sql.DB, tableName string) error
+
+	// RegisterValues returns additional values to be registered with the migration of the given key.
+	RegisterValues func(key string) map[string]any
+
+	// parsedSteps caches the steps of file migrations by their key, see WithCacheParsedMigrations.
+	parsedSteps *sync.Map
+
+	// manifestAppend appends the file migrations not listed in the manifest instead of rejecting them.
+	manifestAppend bool
+
+	// manifestOrder contains the keys of the file migrations in the order given by their manifests.
+	manifestOrder []string
+
+	// categories contains the categories of the categorized migrations by their keys, see readCategories.
+	categories map[string]string
+
+	// versionRanges contains the server version ranges of the migrations restricted to some server versions by
+	// their keys, see readVersionRanges.
+	versionRanges map[string]versionRange
+
+	// appliedKeys contains the keys of the migrations applied before the current run, see lastMigration.
+	appliedKeys map[string]struct{}
+
+	// registerStmt is the statement registering the migrations, prepared for the current run, see prepareRegister.
+	registerStmt *sql.Stmt
 }
 
 // MorphOption is the type used for functional options.
@@ -149,207 +591,1362 @@ func WithMigrations(migrations ...Migration) MorphOption {
 	}
 }
 
-// WithLog sets the logger that is to be used. If none is supplied, the default logger
-// is used instead.
+// WithLog sets the logger that is to be used. If none or nil is supplied, nothing is logged, so that DMorph stays
+// quiet in programs not configuring it. To log to the default logger, pass slog.Default(). Messages are logged
+// with the context of the running operation where available, so that handlers can extract values from it, e.g.
+// correlation IDs.
 func WithLog(log *slog.Logger) MorphOption {
 	return func(m *Morpher) error {
+		if log == nil {
+			log = slog.New(slog.DiscardHandler)
+		}
+
 		m.Log = log
 
 		return nil
 	}
 }
 
-// WithTableName sets the migration table name to the given one. If not supplied, the
-// default MigrationTableName is used instead.
-func WithTableName(tableName string) func(*Morpher) error {
+// WithReadOnly sets the read-only mode. In read-only mode Run performs all consistency checks, but does not
+// write to the database, not even to create the migration table. If there are migrations pending,
+// ErrMigrationsPending is returned.
+func WithReadOnly(readOnly bool) MorphOption {
 	return func(m *Morpher) error {
-		if len(tableName) < 1 {
-			return ErrMigrationTableNameInvalid
-		}
+		m.ReadOnly = readOnly
 
-		if !ValidTableNameRex.MatchString(tableName) {
-			return ErrMigrationTableNameInvalid
-		}
+		return nil
+	}
+}
 
-		m.TableName = tableName
+// WithIntegrityCheck enables the check of the migration table structure before any migrations are applied.
+// The dialect has to implement the IntegrityChecker interface, otherwise ErrIntegrityCheckUnsupported is returned.
+func WithIntegrityCheck(check bool) MorphOption {
+	return func(m *Morpher) error {
+		m.Integrity = check
 
 		return nil
 	}
 }
 
-// WithGroupName sets the migration group name on the provided Morpher instance. If not supplied, the
-// default MigrationGroupName is used instead.
-func WithGroupName(groupName string) func(*Morpher) error {
+// WithOnError sets a function that is called if a migration fails. If it returns nil, the failed migration is
+// not registered and the next migration is applied. If it returns an error, Run aborts with this error.
+// Without it, Run aborts on the first failed migration.
+//
+// Warning: this is only intended for idempotent data migrations, e.g. seed data in development environments.
+// The failed migration leaves a gap in the applied migrations, so that subsequent runs with the same set of
+// migrations will report ErrMigrationsUnrelated. Never use it for schema migrations.
+func WithOnError(onError func(key string, err error) error) MorphOption {
 	return func(m *Morpher) error {
-		if groupName == "" {
-			return ErrMigrationGroupNameInvalid
-		}
-
-		m.GroupName = groupName
+		m.OnError = onError
 
 		return nil
 	}
 }
 
-// WithMigrationKeyProperties sets the migration key comparison properties for a Morpher instance.
-func WithMigrationKeyProperties(keyProp MigrationKeyProperties) func(*Morpher) error {
+// WithOnSkip sets a function that is called for each migration that Run skips, as it is already applied, e.g.
+// for progress accounting and metrics. It is called in the order of the migrations.
+func WithOnSkip(onSkip func(key string)) MorphOption {
 	return func(m *Morpher) error {
-		m.KeyProp = keyProp
+		m.OnSkip = onSkip
 
 		return nil
 	}
 }
 
-// NewMorpher creates a new Morpher configuring it with the given options.
-// It ensures that the newly created Morpher has migrations and a database dialect configured.
-// If no migration table name is given, the default MigrationTableName is used instead.
-func NewMorpher(options ...MorphOption) (*Morpher, error) {
-	morpher := &Morpher{
-		TableName: MigrationTableName,
-		GroupName: MigrationGroupName,
-		KeyProp:   MigrationKeyAlphabetical(),
-		Log:       slog.Default(),
-	}
+// WithAfterEnsureTable sets a function that is called right after the existence of the migration table was
+// ensured, and before the applied migrations are read. It can be used to set up permissions on the
+// migration table. If it returns an error, Run aborts. In read-only mode it is not called.
+func WithAfterEnsureTable(afterEnsureTable func(ctx context.Context, db *sql.DB, tableName string) error) MorphOption {
+	return func(m *Morpher) error {
+		m.AfterEnsureTable = afterEnsureTable
 
-	for _, option := range options {
-		if err := option(morpher); err != nil {
-			return nil, err
-		}
+		return nil
 	}
+}
 
-	if validErr := morpher.IsValid(); validErr != nil {
-		return nil, validErr
-	}
+// WithRegisterValues sets a function returning additional values that are registered with each migration,
+// e.g. the ticket that approved it. This requires a migration table with the additional columns, i.e. custom
+// CreateTemplate and RegisterTemplate of the dialect. The values are passed as named parameters, for the
+// NumberedParamsDialect at the positions of their names in the RegisterMigrationParamsOrder. The dialect has to
+// implement the ValueRegisterer interface. Additional values cannot be combined with WithIdempotentRegister.
+func WithRegisterValues(registerValues func(key string) map[string]any) MorphOption {
+	return func(m *Morpher) error {
+		m.RegisterValues = registerValues
 
-	return morpher, nil
+		return nil
+	}
 }
 
-// IsValid checks if the Morpher contains all the required information to run.
-func (m *Morpher) IsValid() error {
-	if m.Dialect == nil {
-		return ErrNoDialect
-	}
+// WithParallel sets the number of databases that RunAll migrates concurrently. Values less than 2 result in
+// the databases being migrated sequentially.
+func WithParallel(parallel int) MorphOption {
+	return func(m *Morpher) error {
+		m.Parallel = parallel
 
-	if len(m.Migrations) < 1 {
-		return ErrNoMigrations
+		return nil
 	}
+}
 
-	if m.TableName == "" {
-		return ErrNoMigrationTable
-	}
+// WithStepLogEvery only logs every nth step of file migrations, besides their first and last step, bounding
+// the debug output of migrations with thousands of steps. Values less than 1 disable the logging of steps. By
+// default, every step is logged.
+func WithStepLogEvery(n int) MorphOption {
+	return func(m *Morpher) error {
+		m.StepLog = n
 
-	if m.GroupName == "" {
-		return ErrNoMigrationGroup
+		return nil
 	}
+}
 
-	if !ValidTableNameRex.MatchString(m.TableName) {
-		return ErrMigrationTableNameInvalid
-	}
+// WithValidateFirst executes all pending migrations in a single transaction that is rolled back, before
+// applying them for real. So errors like typos in later migrations are detected before anything is written.
+// Unlike WithPreflight, this also detects errors reported by the database. The dialect has to implement the
+// DryRunner interface and support rolling back DDL statements, otherwise ErrDryRunUnsupported is returned. As
+// the migrations are executed twice, they must only affect the database.
+func WithValidateFirst(validate bool) MorphOption {
+	return func(m *Morpher) error {
+		m.DryRun = validate
 
-	for _, mi := range m.Migrations {
-		if !m.KeyProp.MigrationKeyValid(mi.Key()) {
-			return ErrMigrationKeyFormat
-		}
+		return nil
 	}
-
-	return nil
 }
 
-// Run runs the configured Morpher on the given database. If the migrations already applied
-// to the database are a superset of the migrations the Morpher would apply, ErrMigrationsTooOld is
-// returned.
-// Run will run each migration in a separate transaction, with the last step to register the
-// migration in the migration table.
-func (m *Morpher) Run(ctx context.Context, db *sql.DB) error {
-	if validErr := m.IsValid(); validErr != nil {
-		return validErr
-	}
+// WithTransactionPerStep commits each step of file migrations in a separate transaction, instead of one
+// transaction per migration, e.g. for engines that cannot hold long transactions. The migration is registered
+// in a further transaction after its last step.
+//
+// Warning: if a step fails, the previous steps of the migration stay committed, while the migration is not
+// registered. The last successful step is logged, and the database has to be repaired manually before the
+// migration can be run again. Other migrations are applied in a single transaction as usual.
+func WithTransactionPerStep(perStep bool) MorphOption {
+	return func(m *Morpher) error {
+		m.StepTx = perStep
 
-	if err := m.Dialect.EnsureMigrationTableExists(ctx, db, m.TableName); err != nil {
-		return fmt.Errorf("could not create migration table: %w", err)
+		return nil
 	}
+}
 
-	appliedMigrations, appliedMigrationsErr := m.Dialect.AppliedMigrations(ctx, db, m.TableName, m.GroupName)
+// WithTxOptions sets the options of the transactions the migrations are applied in, e.g. their isolation level.
+// An IsolatedMigration may override them for itself. By default, the options of the driver are used.
+func WithTxOptions(opts *sql.TxOptions) MorphOption {
+	return func(m *Morpher) error {
+		m.TxOptions = opts
 
-	if appliedMigrationsErr != nil {
-		return fmt.Errorf("could not get applied migrations: %w", appliedMigrationsErr)
+		return nil
 	}
+}
 
-	slices.SortFunc(m.Migrations, m.KeyProp.MigrationOrder)
-
-	lastMigration := ""
+// WithTableCreateRetry retries the creation of the migration table up to the given number of attempts, e.g. for
+// freshly started databases accepting connections before being ready to run DDL. The delay before the first
+// retry is doubled for every further one. Only the creation of the migration table is retried, never the
+// migrations themselves. By default, no retry is done.
+func WithTableCreateRetry(attempts int, delay time.Duration) MorphOption {
+	return func(m *Morpher) error {
+		m.CreateTries = attempts
+		m.CreateDelay = delay
 
-	if len(appliedMigrations) == 0 {
-		m.Log.Debug("no previous migrations")
-	} else {
-		m.Log.Debug("last migration",
-			slog.String("file", appliedMigrations[len(appliedMigrations)-1]))
+		return nil
+	}
+}
 
-		err := m.checkAppliedMigrations(appliedMigrations)
-		if err != nil {
-			return err
-		}
+// WithFastPath enables a shortcut for databases that are already up to date. If the number of applied
+// migrations and the last applied migration match the configured ones, Run returns without sorting the
+// migrations and checking the applied ones. This speeds up the start of programs on fully migrated databases with many
+// migrations, at the cost of not detecting inconsistencies in the middle of the applied migrations.
+func WithFastPath(fastPath bool) MorphOption {
+	return func(m *Morpher) error {
+		m.FastPath = fastPath
 
-		lastMigration = appliedMigrations[len(appliedMigrations)-1]
+		return nil
 	}
-
-	return m.applyMigrations(ctx, db, lastMigration)
 }
 
-// applyMigrations applies the given migrations to the database.
-// This method does not check for the validity or consistency of the database.
-func (m *Morpher) applyMigrations(ctx context.Context, db *sql.DB, lastMigration string) error {
-	var startMigration time.Time
+// WithStrictTermination requires every statement of file migrations to be terminated by a separator, i.e. a
+// line only containing `;`. By default, a final statement without separator is executed nevertheless. In
+// strict mode, such a statement results in ErrStatementUnterminated, preventing the accidental execution of
+// half-written statements.
+func WithStrictTermination(strict bool) MorphOption {
+	return func(m *Morpher) error {
+		m.StrictTermination = strict
 
-	for _, migration := range m.Migrations {
-		if lastMigration != "" && m.KeyProp.MigrationKeyOrder(lastMigration, migration.Key()) >= 0 {
-			m.Log.Info("migration already applied", slog.String("file", migration.Key()))
+		return nil
+	}
+}
 
-			continue
-		}
+// WithStripComments controls the removal of the leading comments of the statements of file migrations, which
+// is enabled by default, as some drivers do not support comments. Disabling it passes the statements on
+// verbatim, e.g. to preserve license headers in the logs of the database. Directives are still evaluated.
+func WithStripComments(strip bool) MorphOption {
+	return func(m *Morpher) error {
+		m.KeepComments = !strip
 
-		m.Log.Info("applying migration", slog.String("file", migration.Key()))
+		return nil
+	}
+}
 
-		startMigration = time.Now()
+// WithStatementTransform sets a function rewriting each statement of file migrations right before its
+// execution, e.g. to adapt a single set of migration files to a compatible database engine with slightly
+// different syntax. The function gets the statement as split from the file, without leading comments, and
+// returns the statement to execute. It is also applied by GenerateSQL.
+//
+// Warning: the transformation applies to all statements, including data. Blunt substitutions like replacing a
+// keyword everywhere will also change string literals and identifiers containing it. Keep the transformations
+// as specific as possible, and verify the resulting schema.
+func WithStatementTransform(transform func(statement string) string) MorphOption {
+	return func(m *Morpher) error {
+		m.Transform = transform
 
-		// Check context before starting a transaction
-		if err := ctx.Err(); err != nil {
-			return fmt.Errorf("context cancelled before migration %s: %w", migration.Key(), err)
-		}
+		return nil
+	}
+}
 
-		if err := m.runOneMigration(ctx, db, migration); err != nil {
-			return err
-		}
+// WithSetupStatements adds statements that are executed once per Run, before the migration table is ensured,
+// e.g. `PRAGMA journal_mode=WAL` or `PRAGMA foreign_keys=ON` for SQLite. As statements specific to a connection
+// only take effect on the connection that executed them, they are also executed on the connection of each
+// migration, before its transaction begins. In read-only mode, the setup statements are not executed.
+func WithSetupStatements(statements ...string) MorphOption {
+	return func(m *Morpher) error {
+		m.Setup = append(m.Setup, statements...)
 
-		m.Log.Info("migration applied",
-			slog.String("file", migration.Key()),
-			slog.Duration("duration", time.Since(startMigration)),
-		)
+		return nil
 	}
-
-	return nil
 }
 
-// runOneMigration executes a single migration within a database transaction and logs its completion.
-func (m *Morpher) runOneMigration(ctx context.Context, db *sql.DB, mig Migration) error {
-	tx, err := db.BeginTx(ctx, nil)
+// WithAssumeTableExists skips ensuring the existence of the migration table, including the AfterEnsureTable
+// callback. This separates the privileges for DDL and DML, if the migration table is created in a separate
+// step by a privileged user. If the table does not exist, Run fails when reading the applied migrations.
+func WithAssumeTableExists(assume bool) MorphOption {
+	return func(m *Morpher) error {
+		m.AssumeTable = assume
 
-	if err != nil {
-		return fmt.Errorf("begin tx: %w", err)
+		return nil
 	}
+}
 
-	// Even if we are sure to catch all possibilities, we use this as a safeguard that also with later
-	// modifications. When a successful commit cannot be done, at least the rollback is executed, freeing
+// WithApplicationName annotates the transaction of each migration with the given application name and the key
+// of the migration, e.g. `deploy-42:01_base.sql`, so that database administrators can correlate long-running
+// statements to the deployment, e.g. using `application_name` in `pg_stat_activity` of PostgreSQL. On MySQL the
+// name is stored in the user variable `@dmorph_application`, which does not show up in the processlist but only in
+// `performance_schema.user_variables_by_thread`. Dialects not implementing the TxAnnotator interface or lacking an
+// ApplicationTemplate leave the transactions as they are.
+func WithApplicationName(name string) MorphOption {
+	return func(m *Morpher) error {
+		m.AppName = name
+
+		return nil
+	}
+}
+
+// WithFoldTableName folds the migration table name to the case the database uses for unquoted identifiers, e.g.
+// `Migrations` to `migrations` for PostgreSQL and to `MIGRATIONS` for Oracle. The dialects quote the table name,
+// preserving its case, so without folding the table cannot be accessed by SQL not quoting its name. Dialects not
+// implementing the IdentifierFolder interface keep the name as it is.
+func WithFoldTableName(fold bool) MorphOption {
+	return func(m *Morpher) error {
+		m.FoldName = fold
+
+		return nil
+	}
+}
+
+// WithIdempotentRegister registers migrations using upsert semantics, so that registering a migration that is
+// registered already, e.g. by a concurrently running instance, does not fail. The dialect has to implement
+// the IdempotentRegisterer interface, otherwise ErrIdempotentRegisterUnsupported is returned. Note that the
+// migrations themselves may still be executed concurrently.
+func WithIdempotentRegister(idempotent bool) MorphOption {
+	return func(m *Morpher) error {
+		m.Idempotent = idempotent
+
+		return nil
+	}
+}
+
+// WithAllowOlder allows running migrations that are older than the ones already applied to the database, e.g.
+// after an intentional rollback of the application. Instead of returning ErrMigrationsTooOld, a warning is
+// logged and nothing is applied. The configured migrations still have to match the applied ones.
+func WithAllowOlder(allowOlder bool) MorphOption {
+	return func(m *Morpher) error {
+		m.AllowOlder = allowOlder
+
+		return nil
+	}
+}
+
+// WithPreflight reads and splits all pending file migrations into their steps before applying any of them. So
+// a missing or malformed file, e.g. an unterminated statement in strict termination mode, fails fast, without
+// leaving the database half-migrated. Each file is read completely into memory once for the check. Migrations
+// not based on SQL files are not checked.
+func WithPreflight(preflight bool) MorphOption {
+	return func(m *Morpher) error {
+		m.Preflight = preflight
+
+		return nil
+	}
+}
+
+// WithTags adds the given tags to the set of active tags. A TaggedMigration is only applied if one of its
+// tags is active. Migrations without tags are always applied.
+func WithTags(tags ...string) MorphOption {
+	return func(m *Morpher) error {
+		m.Tags = append(m.Tags, tags...)
+
+		return nil
+	}
+}
+
+// WithEnvironment activates the tag of the given environment, e.g. "test". It is a shorthand for WithTags
+// with a single tag.
+func WithEnvironment(environment string) MorphOption {
+	return WithTags(environment)
+}
+
+// WithTableComment sets the comment of the migration table, e.g. documenting its purpose and owner. It is
+// only applied if the dialect implements the TableCommenter interface and supports comments.
+func WithTableComment(comment string) MorphOption {
+	return func(m *Morpher) error {
+		m.Comment = comment
+
+		return nil
+	}
+}
+
+// WithTableName sets the migration table name to the given one. If not supplied, the
+// default MigrationTableName is used instead.
+func WithTableName(tableName string) func(*Morpher) error {
+	return func(m *Morpher) error {
+		if len(tableName) < 1 {
+			return ErrMigrationTableNameInvalid
+		}
+
+		// qualified names are checked against the dialect by IsValid, as it might not be set yet
+		if !validTableName(tableName, true) {
+			return ErrMigrationTableNameInvalid
+		}
+
+		m.TableName = tableName
+
+		return nil
+	}
+}
+
+// WithGroupName sets the migration group name on the provided Morpher instance. If not supplied, the
+// default MigrationGroupName is used instead.
+func WithGroupName(groupName string) func(*Morpher) error {
+	return func(m *Morpher) error {
+		if groupName == "" {
+			return ErrMigrationGroupNameInvalid
+		}
+
+		m.GroupName = groupName
+
+		return nil
+	}
+}
+
+// WithMigrationKeyProperties sets the migration key comparison properties for a Morpher instance.
+func WithMigrationKeyProperties(keyProp MigrationKeyProperties) func(*Morpher) error {
+	return func(m *Morpher) error {
+		m.KeyProp = keyProp
+
+		return nil
+	}
+}
+
+// NewMorpher creates a new Morpher configuring it with the given options.
+// It ensures that the newly created Morpher has migrations and a database dialect configured.
+// If no migration table name is given, the one of the environment variable MigrationTableNameEnv is used, if
+// it is valid. Otherwise, the default MigrationTableName is used instead.
+func NewMorpher(options ...MorphOption) (*Morpher, error) {
+	morpher := &Morpher{
+		TableName: defaultTableName(),
+		GroupName: MigrationGroupName,
+		KeyProp:   MigrationKeyAlphabetical(),
+		Log:       slog.New(slog.DiscardHandler),
+		StepLog:   1,
+	}
+
+	for _, option := range options {
+		if err := option(morpher); err != nil {
+			return nil, err
+		}
+	}
+
+	if folder, isFolder := morpher.Dialect.(IdentifierFolder); morpher.FoldName && isFolder {
+		morpher.TableName = folder.FoldIdentifier(morpher.TableName)
+	}
+
+	morpher.applyManifestOrder()
+
+	if filterErr := morpher.filterTagged(); filterErr != nil {
+		return nil, filterErr
+	}
+
+	morpher.squashBaseline()
+
+	if categoryErr := morpher.readCategories(); categoryErr != nil {
+		return nil, categoryErr
+	}
+
+	if versionErr := morpher.readVersionRanges(); versionErr != nil {
+		return nil, versionErr
+	}
+
+	if validErr := morpher.IsValid(); validErr != nil {
+		return nil, validErr
+	}
+
+	if duplicateErr := morpher.checkDuplicateKeys(); duplicateErr != nil {
+		return nil, duplicateErr
+	}
+
+	return morpher, nil
+}
+
+// HistoryPage returns a page of at most limit applied migrations of the migration group, skipping the first
+// offset ones. The migrations are ordered by application date. If the dialect does not implement the
+// HistoryPager interface, ErrHistoryUnsupported is returned.
+func (m *Morpher) HistoryPage(ctx context.Context, db *sql.DB, limit int, offset int) ([]AppliedMigration, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+
+	pager, isPager := m.Dialect.(HistoryPager)
+
+	if !isPager {
+		return nil, ErrHistoryUnsupported
+	}
+
+	return pager.HistoryPage(ctx, db, m.TableName, m.GroupName, limit, offset) //nolint:wrapcheck
+}
+
+// Pending returns the keys of the configured migrations that are not yet applied to the database, in the order
+// Run would apply them. The database is only read, a missing migration table is treated as empty if the
+// dialect can determine its absence. The applied migrations are checked for consistency as in Run, returning
+// the corresponding error if they are incompatible with the configured ones.
+func (m *Morpher) Pending(ctx context.Context, db *sql.DB) ([]string, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+
+	m, lastMigration, lastErr := m.readLastMigration(ctx, db)
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+
+	return migrationKeys(m.pendingMigrations(lastMigration)), nil
+}
+
+// appliedMigrations reads the applied migrations. In read-only mode the migration table was not created, so
+// that a missing one is read as no migrations being applied.
+func (m *Morpher) appliedMigrations(ctx context.Context, db *sql.DB) ([]string, error) {
+	if m.ReadOnly {
+		return m.readAppliedMigrations(ctx, db)
+	}
+
+	appliedMigrations, err := m.Dialect.AppliedMigrations(ctx, db, m.TableName, m.GroupName)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get applied migrations: %w", err)
+	}
+
+	return appliedMigrations, nil
+}
+
+// readLastMigration reads the applied migrations without modifying the database and checks them for
+// consistency. It returns the Morpher to use for the server version of the database and the last applied
+// migration.
+func (m *Morpher) readLastMigration(ctx context.Context, db *sql.DB) (*Morpher, string, error) {
+	if validErr := m.IsValid(); validErr != nil {
+		return nil, "", validErr
+	}
+
+	appliedMigrations, appliedErr := m.readAppliedMigrations(ctx, db)
+
+	if appliedErr != nil {
+		return nil, "", appliedErr
+	}
+
+	m, versionErr := m.forServerVersion(ctx, db, appliedMigrations)
+
+	if versionErr != nil {
+		return nil, "", versionErr
+	}
+
+	return m.lastMigration(ctx, appliedMigrations)
+}
+
+// MigrationState is the state of a configured migration in a database, see Morpher.Status.
+type MigrationState string
+
+const (
+	// MigrationStateApplied marks migrations already applied to the database.
+	MigrationStateApplied MigrationState = "applied"
+
+	// MigrationStatePending marks migrations Run would apply.
+	MigrationStatePending MigrationState = "pending"
+
+	// MigrationStateDeferred marks migrations not applied yet, that Run skips as their category is not
+	// selected, see WithCategories.
+	MigrationStateDeferred MigrationState = "deferred"
+)
+
+// MigrationStatus describes the state of a configured migration in a database. The JSON encoding is stable, so
+// that it can be processed by other tools, e.g. deployment gates.
+type MigrationStatus struct {
+	Key    string         `json:"key"`    // key of the migration
+	State  MigrationState `json:"state"`  // state of the migration in the database
+	Origin string         `json:"origin"` // origin of the migration, e.g. the file it was read from
+}
+
+// Status returns the state of each configured migration in the database, in the order Run applies them.
+// Migrations skipped for the server version are omitted. The database is only read and the applied migrations
+// are checked for consistency, as described for Pending.
+func (m *Morpher) Status(ctx context.Context, db *sql.DB) ([]MigrationStatus, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+
+	// migrations skipped for the server version are neither applied nor pending
+	m, lastMigration, lastErr := m.readLastMigration(ctx, db)
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+
+	pending := keySet(migrationKeys(m.pendingMigrations(lastMigration)))
+	deferred := keySet(migrationKeys(m.deferredMigrations(lastMigration)))
+
+	sorted := slices.Clone(m.Migrations)
+	slices.SortFunc(sorted, m.KeyProp.MigrationOrder)
+
+	result := make([]MigrationStatus, 0, len(sorted))
+
+	for _, mi := range sorted {
+		state := MigrationStateApplied
+
+		_, isPending := pending[mi.Key()]
+		_, isDeferred := deferred[mi.Key()]
+
+		switch {
+		case isPending:
+			state = MigrationStatePending
+		case isDeferred:
+			state = MigrationStateDeferred
+		}
+
+		result = append(result, MigrationStatus{Key: mi.Key(), State: state, Origin: migrationOrigin(mi)})
+	}
+
+	return result, nil
+}
+
+// CurrentVersion returns the key of the last applied migration, e.g. to report the version of the schema, or
+// the empty string if no migration is applied. The applied migrations are not checked for consistency. If the
+// dialect implements the LatestReader interface, only the migrations applied last are read. Of the read
+// migrations, the latest according to the key order is returned.
+func (m *Morpher) CurrentVersion(ctx context.Context, db *sql.DB) (string, error) {
+	if db == nil {
+		return "", ErrNilDB
+	}
+
+	missing, missingErr := m.migrationTableMissing(ctx, db)
+
+	if missing || missingErr != nil {
+		return "", missingErr
+	}
+
+	var latest []string
+	var err error
+
+	if reader, isReader := m.Dialect.(LatestReader); isReader {
+		latest, err = reader.LatestMigrations(ctx, db, m.TableName, m.GroupName)
+	}
+
+	// migrations registered without timestamp are not found by their timestamp, so all are considered
+	if len(latest) == 0 && err == nil {
+		latest, err = m.Dialect.AppliedMigrations(ctx, db, m.TableName, m.GroupName)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("could not get latest migrations: %w", err)
+	}
+
+	if len(latest) == 0 {
+		return "", nil
+	}
+
+	return slices.MaxFunc(latest, m.KeyProp.MigrationKeyOrder), nil
+}
+
+// validTableName checks the given migration table name against ValidTableNameRex, or, if qualified names are
+// accepted, also against ValidQualifiedTableNameRex.
+func validTableName(tableName string, qualified bool) bool {
+	return ValidTableNameRex.MatchString(tableName) || qualified && ValidQualifiedTableNameRex.MatchString(tableName)
+}
+
+// defaultTableName returns the migration table name of the environment variable MigrationTableNameEnv, if it
+// is set and valid, and MigrationTableName otherwise.
+func defaultTableName() string {
+	if tableName := os.Getenv(MigrationTableNameEnv); ValidTableNameRex.MatchString(tableName) {
+		return tableName
+	}
+
+	return MigrationTableName
+}
+
+// logger returns the logger of the Morpher, adding the name of the dialect if it implements NamedDialect.
+func (m *Morpher) logger() *slog.Logger {
+	if named, isNamed := m.Dialect.(NamedDialect); isNamed && named.Name() != "" {
+		return m.Log.With(slog.String("dialect", named.Name()))
+	}
+
+	return m.Log
+}
+
+// EffectiveTableName returns the name of the migration table as the dialect uses it in its statements, resolved
+// from the default, the environment variable MigrationTableNameEnv or WithTableName. Callers running their own
+// queries on the migration table stay consistent with DMorph using it. The name, possibly qualified by a schema,
+// is quoted if the dialect implements IdentifierQuoter, and returned as it is otherwise.
+func (m *Morpher) EffectiveTableName() string {
+	if quoter, isQuoter := m.Dialect.(IdentifierQuoter); isQuoter {
+		return quoter.QuoteQualified(m.TableName)
+	}
+
+	return m.TableName
+}
+
+// Count returns the number of configured migrations.
+func (m *Morpher) Count() int {
+	return len(m.Migrations)
+}
+
+// LatestKey returns the key of the migration that would be applied last, using the same ordering as Run.
+// If there are no migrations, the empty string is returned.
+func (m *Morpher) LatestKey() string {
+	if len(m.Migrations) == 0 {
+		return ""
+	}
+
+	return slices.MaxFunc(m.Migrations, m.KeyProp.MigrationOrder).Key()
+}
+
+// filterTagged removes all TaggedMigration instances that do not have at least one active tag.
+func (m *Morpher) filterTagged() error {
+	result := make([]Migration, 0, len(m.Migrations))
+
+	for _, mi := range m.Migrations {
+		tagged, isTagged := mi.(TaggedMigration)
+
+		if !isTagged {
+			result = append(result, mi)
+
+			continue
+		}
+
+		tags, tagsErr := tagged.Tags()
+
+		if tagsErr != nil {
+			return fmt.Errorf("could not get tags of migration %s: %w", mi.Key(), tagsErr)
+		}
+
+		if len(tags) == 0 || slices.ContainsFunc(tags, func(t string) bool { return slices.Contains(m.Tags, t) }) {
+			result = append(result, mi)
+		} else {
+			m.logger().Debug("migration excluded by tags", slog.String("file", mi.Key()))
+		}
+	}
+
+	m.Migrations = result
+
+	return nil
+}
+
+// IsValid checks if the Morpher contains all the required information to run.
+func (m *Morpher) IsValid() error {
+	if m.Dialect == nil {
+		return ErrNoDialect
+	}
+
+	if len(m.Migrations) < 1 {
+		return ErrNoMigrations
+	}
+
+	if m.TableName == "" {
+		return ErrNoMigrationTable
+	}
+
+	if m.GroupName == "" {
+		return ErrNoMigrationGroup
+	}
+
+	qualifier, isQualifier := m.Dialect.(TableNameQualifier)
+
+	if !validTableName(m.TableName, isQualifier && qualifier.QualifiesTableName()) {
+		return ErrMigrationTableNameInvalid
+	}
+
+	if validator, isValidator := m.Dialect.(TableNameValidator); isValidator {
+		if err := validator.ValidateTableName(m.TableName); err != nil {
+			return err //nolint:wrapcheck // errors are wrapped by the dialects
+		}
+	}
+
+	if _, isRegisterer := m.Dialect.(IdempotentRegisterer); m.Idempotent && !isRegisterer {
+		return ErrIdempotentRegisterUnsupported
+	}
+
+	if _, isRegisterer := m.Dialect.(ValueRegisterer); m.RegisterValues != nil && (!isRegisterer || m.Idempotent) {
+		return ErrRegisterValuesUnsupported
+	}
+
+	var keyLength int
+
+	if limiter, isLimiter := m.Dialect.(KeyLengthLimiter); isLimiter {
+		keyLength = limiter.KeyLength()
+	}
+
+	for _, mi := range m.Migrations {
+		if !m.KeyProp.MigrationKeyValid(mi.Key()) {
+			return &KeyFormatError{Key: mi.Key()}
+		}
+
+		if keyLength > 0 && utf8.RuneCountInString(mi.Key()) > keyLength {
+			return fmt.Errorf("%w: %s exceeds %d characters", ErrMigrationKeyTooLong, mi.Key(), keyLength)
+		}
+	}
+
+	return nil
+}
+
+// checkDuplicateKeys verifies that the keys of the migrations, possibly merged from multiple sources, form one
+// strictly ordered sequence. It is checked once when creating the Morpher, as it requires sorting all
+// migrations.
+func (m *Morpher) checkDuplicateKeys() error {
+	sorted := slices.Clone(m.Migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int { return m.KeyProp.MigrationKeyOrder(a.Key(), b.Key()) })
+
+	for i := 1; i < len(sorted); i++ {
+		if m.KeyProp.MigrationKeyOrder(sorted[i-1].Key(), sorted[i].Key()) == 0 {
+			return fmt.Errorf("%w: %s (%s) and %s (%s)", ErrMigrationKeyDuplicate,
+				sorted[i-1].Key(), migrationOrigin(sorted[i-1]), sorted[i].Key(), migrationOrigin(sorted[i]))
+		}
+	}
+
+	return nil
+}
+
+// Run runs the configured Morpher on the given database. If the migrations already applied
+// to the database are a superset of the migrations the Morpher would apply, ErrMigrationsTooOld is
+// returned.
+// Run will run each migration in a separate transaction, with the last step to register the
+// migration in the migration table. In read-only mode, Run returns ErrMigrationsPending instead of
+// applying the migrations. The given context also bounds the time waiting for a connection of the pool.
+func (m *Morpher) Run(ctx context.Context, db *sql.DB) error {
+	_, err := m.run(ctx, db)
+
+	return err
+}
+
+// RunChanged runs the configured Morpher like Run, additionally reporting if at least one migration was
+// applied. This allows, e.g., deployment pipelines to only run further steps if the schema changed.
+func (m *Morpher) RunChanged(ctx context.Context, db *sql.DB) (bool, error) {
+	applied, err := m.run(ctx, db)
+
+	return len(applied) > 0, err
+}
+
+// RunAndVerify runs the configured Morpher like Run and then verifies that the migrations applied to the database
+// equal the configured ones in order, i.e. the database is exactly at the latest configured migration. This
+// detects other processes modifying the migration table during the run, e.g. for readiness checks. If the
+// applied migrations differ, ErrVerificationFailed is returned.
+func (m *Morpher) RunAndVerify(ctx context.Context, db *sql.DB) error {
+	if err := m.Run(ctx, db); err != nil {
+		return err
+	}
+
+	appliedMigrations, err := m.Dialect.AppliedMigrations(ctx, db, m.TableName, m.GroupName)
+
+	if err != nil {
+		return fmt.Errorf("could not get applied migrations: %w", err)
+	}
+
+	m, versionErr := m.forServerVersion(ctx, db, appliedMigrations)
+
+	if versionErr != nil {
+		return versionErr
+	}
+
+	sorted := slices.Clone(m.Migrations)
+	slices.SortFunc(sorted, m.KeyProp.MigrationOrder)
+
+	appliedMigrations = m.collapseBaseline(appliedMigrations)
+	configured := migrationKeys(sorted)
+
+	if !slices.Equal(appliedMigrations, configured) {
+		return fmt.Errorf("%w: applied %s, configured %s", ErrVerificationFailed,
+			strings.Join(appliedMigrations, ", "), strings.Join(configured, ", "))
+	}
+
+	return nil
+}
+
+// run runs the configured Morpher on the given database, returning the keys of the applied migrations.
+func (m *Morpher) run(ctx context.Context, db *sql.DB) ([]string, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+
+	if validErr := m.IsValid(); validErr != nil {
+		return nil, validErr
+	}
+
+	log := m.logger()
+
+	var baselineProber TableProber
+
+	if !m.ReadOnly {
+		if err := m.runSetup(ctx, db); err != nil {
+			return nil, err
+		}
+
+		var proberErr error
+
+		if baselineProber, proberErr = m.autoBaselineProber(ctx, db); proberErr != nil {
+			return nil, proberErr
+		}
+
+		if err := m.prepareMigrationTable(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+
+	m.logServerVersion(ctx, db)
+
+	if m.Integrity {
+		checker, isChecker := m.Dialect.(IntegrityChecker)
+
+		if !isChecker {
+			return nil, ErrIntegrityCheckUnsupported
+		}
+
+		if err := checker.IntegrityCheck(ctx, db, m.TableName); err != nil {
+			return nil, fmt.Errorf("could not verify migration table: %w", err)
+		}
+	}
+
+	appliedMigrations, appliedMigrationsErr := m.appliedMigrations(ctx, db)
+
+	if appliedMigrationsErr != nil {
+		return nil, appliedMigrationsErr
+	}
+
+	m, versionErr := m.forServerVersion(ctx, db, appliedMigrations)
+
+	if versionErr != nil {
+		return nil, versionErr
+	}
+
+	if baselineProber != nil && len(appliedMigrations) == 0 {
+		if appliedMigrations, appliedMigrationsErr = m.autoBaseline(ctx, db, baselineProber); appliedMigrationsErr != nil {
+			return nil, appliedMigrationsErr
+		}
+	}
+
+	if m.FastPath &&
+		len(appliedMigrations) > 0 &&
+		len(appliedMigrations) == len(m.Migrations) &&
+		appliedMigrations[len(appliedMigrations)-1] == m.LatestKey() {
+
+		log.DebugContext(ctx, "migrations up to date")
+
+		return nil, nil
+	}
+
+	m, lastMigration, lastErr := m.lastMigration(ctx, appliedMigrations)
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+
+	if m.ReadOnly {
+		if pending := m.pendingMigrations(lastMigration); len(pending) > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrMigrationsPending, strings.Join(migrationKeys(pending), ", "))
+		}
+
+		return nil, nil
+	}
+
+	if err := m.checkRequirements(appliedMigrations, lastMigration); err != nil {
+		return nil, err
+	}
+
+	if m.Preflight {
+		if err := m.preflight(ctx, m.pendingMigrations(lastMigration)); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.DryRun {
+		if err := m.dryRun(ctx, db, m.pendingMigrations(lastMigration)); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.applyMigrations(ctx, db, lastMigration)
+}
+
+// ForceReapply executes the already applied migration with the given key again, e.g. while iterating on a
+// migration file during development. The migration has to be idempotent. It is executed in a transaction, but
+// without any ordering or consistency checks, and its registration is kept unchanged, so that the order of the
+// applied migrations stays intact. This is a development convenience only and must never be used in production.
+// If the migration is not applied, ErrMigrationNotApplied is returned. In read-only mode, ErrMigrationsPending
+// is returned.
+func (m *Morpher) ForceReapply(ctx context.Context, db *sql.DB, key string) error {
+	if db == nil {
+		return ErrNilDB
+	}
+
+	if validErr := m.IsValid(); validErr != nil {
+		return validErr
+	}
+
+	index := slices.IndexFunc(m.Migrations, func(mi Migration) bool { return mi.Key() == key })
+
+	if index < 0 {
+		return fmt.Errorf("%w: %s", ErrMigrationUnknown, key)
+	}
+
+	if m.ReadOnly {
+		return fmt.Errorf("%w: %s", ErrMigrationsPending, key)
+	}
+
+	appliedMigrations, appliedErr := m.readAppliedMigrations(ctx, db)
+
+	if appliedErr != nil {
+		return appliedErr
+	}
+
+	if !slices.Contains(appliedMigrations, key) {
+		return fmt.Errorf("%w: %s", ErrMigrationNotApplied, key)
+	}
+
+	opts, optsErr := m.txOptions(m.Migrations[index])
+
+	if optsErr != nil {
+		return optsErr
+	}
+
+	m.logger().WarnContext(ctx, "forcibly re-applying migration, all safety checks are bypassed, never use this "+
+		"in production", slog.String("file", key))
+
+	if err := inTransaction(ctx, db, opts, func(tx *sql.Tx) error {
+		return m.Migrations[index].Migrate(ctx, tx)
+	}); err != nil {
+		return fmt.Errorf("could not re-apply migration %s: %w", key, err)
+	}
+
+	m.logger().WarnContext(ctx, "migration forcibly re-applied", slog.String("file", key))
+
+	return nil
+}
+
+// logServerVersion logs the version of the database server at debug level, if the dialect can report it. The
+// version is only queried if debug messages are enabled and failures are ignored, as it is informational only.
+func (m *Morpher) logServerVersion(ctx context.Context, db *sql.DB) {
+	reporter, isReporter := m.Dialect.(VersionReporter)
+	log := m.logger()
+
+	if !isReporter || !log.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	if version, err := reporter.ServerVersion(ctx, db); err != nil {
+		log.DebugContext(ctx, "could not get server version", slog.Any("error", err))
+	} else {
+		log.DebugContext(ctx, "server version", slog.String("version", version))
+	}
+}
+
+// ApplyOne applies the configured migration with the given key on its own, e.g. to roll out a hotfix before
+// the migrations following it. The migration is executed and registered in a transaction like in Run, after
+// the same consistency checks. As later runs require the applied migrations to be in order, only the next
+// pending migration can be applied, otherwise ErrMigrationNotNext is returned. If the migration is already
+// applied, ErrMigrationRegistered is returned. In read-only mode, ErrMigrationsPending is returned.
+func (m *Morpher) ApplyOne(ctx context.Context, db *sql.DB, key string) error {
+	if db == nil {
+		return ErrNilDB
+	}
+
+	if validErr := m.IsValid(); validErr != nil {
+		return validErr
+	}
+
+	if !slices.ContainsFunc(m.Migrations, func(mi Migration) bool { return mi.Key() == key }) {
+		return fmt.Errorf("%w: %s", ErrMigrationUnknown, key)
+	}
+
+	if m.ReadOnly {
+		return fmt.Errorf("%w: %s", ErrMigrationsPending, key)
+	}
+
+	if err := m.prepareMigrationTable(ctx, db); err != nil {
+		return err
+	}
+
+	appliedMigrations, appliedErr := m.Dialect.AppliedMigrations(ctx, db, m.TableName, m.GroupName)
+
+	if appliedErr != nil {
+		return fmt.Errorf("could not get applied migrations: %w", appliedErr)
+	}
+
+	if slices.Contains(appliedMigrations, key) {
+		return fmt.Errorf("%w: %s", ErrMigrationRegistered, key)
+	}
+
+	m, lastMigration, lastErr := m.lastMigration(ctx, appliedMigrations)
+
+	if lastErr != nil {
+		return lastErr
+	}
+
+	pending := m.pendingMigrations(lastMigration)
+
+	if len(pending) == 0 || pending[0].Key() != key {
+		return fmt.Errorf("%w: %s", ErrMigrationNotNext, key)
+	}
+
+	if err := m.runOneMigration(ctx, db, pending[0]); err != nil {
+		return err
+	}
+
+	m.logger().InfoContext(ctx, "migration applied on its own", slog.String("file", key))
+
+	return nil
+}
+
+// prepareMigrationTable ensures the existence of the migration table and calls the AfterEnsureTable callback,
+// unless the table is assumed to exist.
+func (m *Morpher) prepareMigrationTable(ctx context.Context, db *sql.DB) error {
+	if m.AssumeTable {
+		m.logger().DebugContext(ctx, "migration table assumed to exist", slog.String("table", m.TableName))
+
+		return nil
+	}
+
+	return m.createMigrationTable(ctx, db)
+}
+
+// createMigrationTable ensures the existence of the migration table and calls the AfterEnsureTable callback.
+func (m *Morpher) createMigrationTable(ctx context.Context, db *sql.DB) error {
+	if err := m.ensureMigrationTableRetrying(ctx, db); err != nil {
+		return fmt.Errorf("could not create migration table: %w", err)
+	}
+
+	if m.AfterEnsureTable != nil {
+		if err := m.AfterEnsureTable(ctx, db, m.TableName); err != nil {
+			return fmt.Errorf("could not prepare migration table: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ensureMigrationTableRetrying ensures the existence of the migration table, retrying as configured using
+// WithTableCreateRetry.
+func (m *Morpher) ensureMigrationTableRetrying(ctx context.Context, db *sql.DB) error {
+	delay := m.CreateDelay
+	err := m.ensureMigrationTable(ctx, db)
+
+	for attempt := 1; err != nil && attempt < m.CreateTries; attempt++ {
+		m.logger().WarnContext(ctx, "could not create migration table, retrying",
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.Any("error", err),
+		)
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+
+		delay *= 2
+		err = m.ensureMigrationTable(ctx, db)
+	}
+
+	return err
+}
+
+// ensureMigrationTable ensures the existence of the migration table, setting its comment if configured.
+func (m *Morpher) ensureMigrationTable(ctx context.Context, db *sql.DB) error {
+	if commenter, isCommenter := m.Dialect.(TableCommenter); isCommenter && m.Comment != "" {
+		return commenter.EnsureMigrationTableExistsWithComment(ctx, db, m.TableName, m.Comment) //nolint:wrapcheck
+	}
+
+	return m.Dialect.EnsureMigrationTableExists(ctx, db, m.TableName) //nolint:wrapcheck
+}
+
+// RunAll runs the configured Morpher on each of the given databases, e.g. the shards of a sharded setup. Each
+// database is checked for consistency on its own. The databases are migrated sequentially, or concurrently if
+// configured using WithParallel. The errors of all failed databases are joined, each stating the index of its
+// database.
+func (m *Morpher) RunAll(ctx context.Context, dbs []*sql.DB) error {
+	if validErr := m.IsValid(); validErr != nil {
+		return validErr
+	}
+
+	errs := make([]error, len(dbs))
+	sem := make(chan struct{}, max(m.Parallel, 1))
+	wg := sync.WaitGroup{}
+
+	for i, db := range dbs {
+		sem <- struct{}{}
+
+		wg.Go(func() {
+			defer func() { <-sem }()
+
+			if err := m.Run(ctx, db); err != nil {
+				errs[i] = fmt.Errorf("database %d: %w", i, err)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// lastMigration sorts the migrations, checks the applied migrations for consistency with them and returns the
+// Morpher to use for the run and the last applied migration. If there are no applied migrations, the empty
+// string is returned.
+func (m *Morpher) lastMigration(ctx context.Context, appliedMigrations []string) (*Morpher, string, error) {
+	// the migrations are sorted on a copy, so that concurrent runs do not modify the shared ones
+	sorted := *m
+	sorted.Migrations = slices.Clone(m.Migrations)
+	slices.SortFunc(sorted.Migrations, m.KeyProp.MigrationOrder)
+
+	m = &sorted
+
+	if len(appliedMigrations) == 0 {
+		m.logger().DebugContext(ctx, "no previous migrations")
+
+		return m, "", nil
+	}
+
+	if len(m.categories) > 0 {
+		return m.lastCategorized(ctx, appliedMigrations)
+	}
+
+	m.logger().DebugContext(ctx, "last migration",
+		slog.String("file", appliedMigrations[len(appliedMigrations)-1]))
+
+	if err := m.checkAppliedMigrations(ctx, appliedMigrations); err != nil {
+		return nil, "", err
+	}
+
+	m.appliedKeys = keySet(appliedMigrations)
+
+	return m, appliedMigrations[len(appliedMigrations)-1], nil
+}
+
+// pendingMigrations returns the migrations that are newer than the last applied migration, and the categorized
+// ones not applied yet, if their category is selected.
+func (m *Morpher) pendingMigrations(lastMigration string) []Migration {
+	var result []Migration
+
+	for _, migration := range m.Migrations {
+		if m.notApplied(lastMigration, migration.Key()) && m.categorySelected(migration.Key()) {
+			result = append(result, migration)
+		}
+	}
+
+	return result
+}
+
+// checkRequirements verifies that the requirements of all pending DependentMigration instances are either
+// already applied or ordered before the requiring migration.
+func (m *Morpher) checkRequirements(appliedMigrations []string, lastMigration string) error {
+	applied := keySet(appliedMigrations)
+	configured := keySet(migrationKeys(m.Migrations))
+
+	for _, migration := range m.pendingMigrations(lastMigration) {
+		dependent, isDependent := migration.(DependentMigration)
+
+		if !isDependent {
+			continue
+		}
+
+		requires, requiresErr := dependent.Requires()
+
+		if requiresErr != nil {
+			return fmt.Errorf("could not get requirements of migration %s: %w", migration.Key(), requiresErr)
+		}
+
+		for _, required := range requires {
+			if _, isApplied := applied[required]; isApplied {
+				continue
+			}
+
+			if _, isConfigured := configured[required]; !isConfigured ||
+				m.KeyProp.MigrationKeyOrder(required, migration.Key()) >= 0 {
+
+				return fmt.Errorf("%w: %s requires %s", ErrMigrationRequirement, migration.Key(), required)
+			}
+		}
+	}
+
+	return nil
+}
+
+// migrationOrigin returns the origin of the given migration for diagnostics. Migrations not read from files are
+// reported as programmatic ones.
+func migrationOrigin(migration Migration) string {
+	if file, isFile := migration.(FileMigration); isFile && file.Origin != "" {
+		return file.Origin
+	}
+
+	return "func"
+}
+
+// migrationKeys returns the keys of the given migrations.
+func migrationKeys(migrations []Migration) []string {
+	result := make([]string, 0, len(migrations))
+
+	for _, mi := range migrations {
+		result = append(result, mi.Key())
+	}
+
+	return result
+}
+
+// applyMigrations applies the given migrations to the database, returning the keys of the applied migrations.
+// This method does not check for the validity or consistency of the database.
+func (m *Morpher) applyMigrations(ctx context.Context, db *sql.DB, lastMigration string) ([]string, error) {
+	var startMigration time.Time
+	var skipped int
+	var applied []string
+
+	m, closeRegister := m.prepareRegister(ctx, db, lastMigration)
+
+	defer closeRegister()
+
+	log := m.logger()
+	pending := keySet(migrationKeys(m.pendingMigrations(lastMigration)))
+
+	for _, migration := range m.Migrations {
+		// the applied migrations are matched by their keys, independent of the configured order
+		if _, isApplied := m.appliedKeys[migration.Key()]; isApplied {
+			log.DebugContext(ctx, "migration already applied", slog.String("file", migration.Key()))
+
+			if m.OnSkip != nil {
+				m.OnSkip(migration.Key())
+			}
+
+			skipped++
+
+			continue
+		}
+
+		if _, isPending := pending[migration.Key()]; !isPending {
+			log.DebugContext(ctx, "migration deferred by category", slog.String("file", migration.Key()))
+
+			continue
+		}
+
+		log.InfoContext(ctx, "applying migration",
+			slog.String("file", migration.Key()),
+			slog.String("origin", migrationOrigin(migration)),
+		)
+
+		startMigration = time.Now()
+
+		// Check context before starting a transaction
+		if err := ctx.Err(); err != nil {
+			return applied, fmt.Errorf("context cancelled before migration %s: %w", migration.Key(), err)
+		}
+
+		if err := m.runOneMigration(ctx, db, migration); err != nil {
+			// drivers do not necessarily report an exceeded deadline as such, and it cannot be continued anyway
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return applied, fmt.Errorf("context cancelled during migration %s: %w", migration.Key(),
+					errors.Join(ctxErr, err))
+			}
+
+			if m.OnError == nil {
+				return applied, err
+			}
+
+			if onErr := m.OnError(migration.Key(), err); onErr != nil {
+				return applied, onErr
+			}
+
+			log.WarnContext(ctx, "migration failed, continuing",
+				slog.String("file", migration.Key()),
+				slog.String("origin", migrationOrigin(migration)),
+				slog.Any("error", err),
+			)
+
+			continue
+		}
+
+		applied = append(applied, migration.Key())
+
+		log.InfoContext(ctx, "migration applied",
+			slog.String("file", migration.Key()),
+			slog.String("origin", migrationOrigin(migration)),
+			slog.Duration("duration", time.Since(startMigration)),
+		)
+	}
+
+	log.InfoContext(ctx, "migrations done",
+		slog.Int("total", len(m.Migrations)),
+		slog.Int("alreadyApplied", skipped),
+	)
+
+	return applied, nil
+}
+
+// runOneMigration executes a single migration within a database transaction and logs its completion.
+func (m *Morpher) runOneMigration(ctx context.Context, db *sql.DB, mig Migration) error {
+	opts, err := m.txOptions(mig)
+
+	if err != nil {
+		return err
+	}
+
+	if source, isSource := mig.(sqlSource); m.StepTx && isSource {
+		return m.runStepwise(ctx, db, opts, mig.Key(), source)
+	}
+
+	tx, release, err := m.beginTx(ctx, db, opts)
+
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	// Even if we are sure to catch all possibilities, we use this as a safeguard that also with later
+	// modifications. When a successful commit cannot be done, at least the rollback is executed, freeing
 	// allocated resources of the transaction.
 	defer func() { _ = tx.Rollback() }()
 
+	if err = m.annotateTx(ctx, tx, mig.Key()); err != nil {
+		return err
+	}
+
 	if err = mig.Migrate(ctx, tx); err != nil {
 		rollbackErr := tx.Rollback()
 
 		return errors.Join(err, rollbackErr)
 	}
 
-	if err = m.Dialect.RegisterMigration(ctx, tx, mig.Key(), m.TableName, m.GroupName); err != nil {
+	if err = m.registerMigration(ctx, tx, mig.Key()); err != nil {
 		rollbackErr := tx.Rollback()
 
 		return errors.Join(err, rollbackErr)
@@ -364,47 +1961,209 @@ func (m *Morpher) runOneMigration(ctx context.Context, db *sql.DB, mig Migration
 	return nil
 }
 
+// annotateTx annotates the given transaction of the migration with the given key using the configured application
+// name, if supported by the dialect.
+func (m *Morpher) annotateTx(ctx context.Context, tx *sql.Tx, key string) error {
+	annotator, isAnnotator := m.Dialect.(TxAnnotator)
+
+	if m.AppName == "" || !isAnnotator {
+		return nil
+	}
+
+	return annotator.AnnotateTx(ctx, tx, m.AppName+":"+key) //nolint:wrapcheck
+}
+
+// txOptions returns the options of the transaction of the given migration, preferring the ones of an
+// IsolatedMigration over the configured ones.
+func (m *Morpher) txOptions(mig Migration) (*sql.TxOptions, error) {
+	isolated, isIsolated := mig.(IsolatedMigration)
+
+	if !isIsolated {
+		return m.TxOptions, nil
+	}
+
+	opts, err := isolated.TxOptions()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get transaction options of migration %s: %w", mig.Key(), err)
+	}
+
+	if opts == nil {
+		return m.TxOptions, nil
+	}
+
+	return opts, nil
+}
+
+// dryRun executes the given migrations in a single transaction that is rolled back afterward.
+func (m *Morpher) dryRun(ctx context.Context, db *sql.DB, migrations []Migration) error {
+	if runner, isRunner := m.Dialect.(DryRunner); !isRunner || !runner.DryRunSupported() {
+		return ErrDryRunUnsupported
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	for _, mig := range migrations {
+		m.logger().DebugContext(ctx, "validating migration", slog.String("file", mig.Key()))
+
+		if err := mig.Migrate(ctx, tx); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrMigrationValidation, mig.Key(), err)
+		}
+	}
+
+	return wrapIfError("could not roll back validation", tx.Rollback())
+}
+
+// registerMigration registers the migration with the given key, idempotently or with additional values if
+// configured.
+func (m *Morpher) registerMigration(ctx context.Context, tx *sql.Tx, key string) error {
+	if registerer, isRegisterer := m.Dialect.(ValueRegisterer); m.RegisterValues != nil && isRegisterer {
+		return registerer.RegisterMigrationWithValues(ctx, tx, key, m.TableName, m.GroupName, //nolint:wrapcheck
+			m.RegisterValues(key))
+	}
+
+	if registerer, isRegisterer := m.Dialect.(IdempotentRegisterer); m.Idempotent && isRegisterer {
+		return registerer.RegisterMigrationIdempotent(ctx, tx, key, m.TableName, m.GroupName) //nolint:wrapcheck
+	}
+
+	if registerer, isRegisterer := m.Dialect.(PreparedRegisterer); m.registerStmt != nil && isRegisterer {
+		return registerer.RegisterMigrationStmt(ctx, tx, m.registerStmt, key, m.TableName, //nolint:wrapcheck
+			m.GroupName)
+	}
+
+	return m.Dialect.RegisterMigration(ctx, tx, key, m.TableName, m.GroupName) //nolint:wrapcheck
+}
+
+// prepareRegister returns a copy of the Morpher registering the pending migrations using a statement prepared
+// once, if supported by the dialect, and a function closing the statement. If the statement cannot be prepared,
+// the migrations are registered without it, as it is an optimization only.
+func (m *Morpher) prepareRegister(ctx context.Context, db *sql.DB, lastMigration string) (*Morpher, func()) {
+	registerer, isRegisterer := m.Dialect.(PreparedRegisterer)
+
+	if !isRegisterer || m.Idempotent || m.RegisterValues != nil || len(m.pendingMigrations(lastMigration)) == 0 {
+		return m, func() {}
+	}
+
+	stmt, err := registerer.PrepareRegister(ctx, db, m.TableName)
+
+	if err != nil {
+		m.logger().DebugContext(ctx, "could not prepare register statement", slog.Any("error", err))
+
+		return m, func() {}
+	}
+
+	prepared := *m
+	prepared.registerStmt = stmt
+
+	return &prepared, func() { _ = stmt.Close() }
+}
+
 // checkAppliedMigrations checks if the already applied migrations in the database are consistent.
 // This means inherently in them and also regarding the migrations that are to be applied.
-func (m *Morpher) checkAppliedMigrations(appliedMigrations []string) error {
+func (m *Morpher) checkAppliedMigrations(ctx context.Context, appliedMigrations []string) error {
 	for _, mi := range appliedMigrations {
 		if !m.KeyProp.MigrationKeyValid(mi) {
-			return ErrMigrationKeyFormat
+			return &KeyFormatError{Key: mi, Applied: true}
 		}
 	}
 
 	if !slices.IsSortedFunc(appliedMigrations, m.KeyProp.MigrationKeyOrder) {
-		m.Log.Error("migrations not applied in order")
+		m.logger().ErrorContext(ctx, "migrations not applied in order")
 
-		return ErrMigrationsUnsorted
+		return &UnsortedError{Applied: appliedMigrations}
+	}
+
+	appliedMigrations = m.collapseBaseline(appliedMigrations)
+
+	switch {
+	case len(appliedMigrations) == 0:
+		return nil
+	case len(m.Migrations) == 0:
+		return &UnrelatedError{Position: 0, Applied: appliedMigrations[0]}
 	}
 
 	if m.KeyProp.MigrationKeyOrder(
 		m.Migrations[len(m.Migrations)-1].Key(),
 		appliedMigrations[len(appliedMigrations)-1]) < 0 {
 
-		return ErrMigrationsTooOld
-	}
+		if !m.AllowOlder {
+			return &TooOldError{
+				ConfiguredLast: m.Migrations[len(m.Migrations)-1].Key(),
+				AppliedLast:    appliedMigrations[len(appliedMigrations)-1],
+			}
+		}
 
-	if len(m.Migrations) < len(appliedMigrations) {
-		// it is impossible to have a migration newer than the one already applied
-		// without having at least the same number of previous migrations
-		return ErrMigrationsUnrelated
+		m.logger().WarnContext(ctx, "migrations older than the database",
+			slog.String("latest", m.Migrations[len(m.Migrations)-1].Key()),
+			slog.String("applied", appliedMigrations[len(appliedMigrations)-1]))
+
+		// the newer migrations of the database are unknown, only the known ones can be checked
+		appliedMigrations = appliedMigrations[:min(len(appliedMigrations), len(m.Migrations))]
 	}
 
-	// we know here that there are at least as many migrations applied as we got to apply
 	for i := range appliedMigrations {
+		if i >= len(m.Migrations) {
+			// it is impossible to have a migration newer than the one already applied
+			// without having at least the same number of previous migrations
+			return &UnrelatedError{Position: i, Applied: appliedMigrations[i]}
+		}
+
 		if appliedMigrations[i] != m.Migrations[i].Key() {
-			return ErrMigrationsUnrelated
+			return &UnrelatedError{Position: i, Applied: appliedMigrations[i], Configured: m.Migrations[i].Key()}
 		}
 	}
 
 	return nil
 }
 
+// CheckConsistency checks the given applied migrations, in the order of their application, for consistency with
+// the configured migrations ordered by cmp, the same way Run does before applying migrations. This allows tools
+// to validate the applied migrations without a live database, e.g. taken from a dump. If the applied migrations
+// are not ordered, an *UnsortedError is returned, if they are newer than the configured ones a *TooOldError and
+// if they differ from the configured ones an *UnrelatedError. If cmp is nil, the keys are ordered
+// alphabetically.
+func CheckConsistency(applied []string, configured []Migration, cmp func(a, b Migration) int) error {
+	if len(applied) == 0 {
+		return nil
+	}
+
+	if len(configured) == 0 {
+		return &UnrelatedError{Position: 0, Applied: applied[0]}
+	}
+
+	if cmp == nil {
+		cmp = migrationOrderAlphabetical
+	}
+
+	m := &Morpher{
+		Migrations: slices.Clone(configured),
+		KeyProp: MigrationKeyProperties{
+			MigrationOrder:    cmp,
+			MigrationKeyOrder: func(a, b string) int { return cmp(keyMigration(a), keyMigration(b)) },
+			MigrationKeyValid: func(key string) bool { return key != "" },
+		},
+		Log: slog.New(slog.DiscardHandler),
+	}
+
+	slices.SortFunc(m.Migrations, cmp)
+
+	// nothing is logged, as the logger discards all messages
+	return m.checkAppliedMigrations(context.Background(), applied)
+}
+
 // Run is a convenience function to easily get the migration job done. For more control use the
 // Morpher directly.
 func Run(ctx context.Context, db *sql.DB, options ...MorphOption) error {
+	if db == nil {
+		return ErrNilDB
+	}
+
 	m, morphErr := NewMorpher(options...)
 
 	if morphErr != nil {
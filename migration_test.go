@@ -4,14 +4,26 @@
 package dmorph_test
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"database/sql"
 	"embed"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	_ "github.com/ncruces/go-sqlite3/driver"
 	"github.com/stretchr/testify/assert"
@@ -183,6 +195,78 @@ func TestMigrationTooOld(t *testing.T) {
 	assert.ErrorIs(t, runErr, dmorph.ErrMigrationsTooOld, "migrations did not give expected error")
 }
 
+// TestMigrationTooOldAllowed tests that migrations older than the applied ones are accepted if allowed.
+func TestMigrationTooOldAllowed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		allowOlder bool
+		want       error
+	}{
+		{allowOlder: false, want: dmorph.ErrMigrationsTooOld},
+		{allowOlder: true, want: nil},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestMigrationTooOldAllowed-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			migrationsDir, migrationsDirErr := fs.Sub(testMigrationsDir, "testData")
+
+			require.NoError(t, migrationsDirErr, "migrations directory could not be opened")
+
+			runErr := dmorph.Run(t.Context(),
+				db,
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrationsFromFS(migrationsDir))
+
+			require.NoError(t, runErr, "preparation migrations could not be run")
+
+			runErr = dmorph.Run(t.Context(),
+				db,
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithAllowOlder(test.allowOlder),
+				dmorph.WithMigrationsFromFilesFS(migrationsDir, "01_base_table.sql"))
+
+			assert.ErrorIs(t, runErr, test.want, "migrations did not give expected result")
+
+			var tooOldErr *dmorph.TooOldError
+
+			if test.want != nil {
+				require.ErrorAs(t, runErr, &tooOldErr, "expected detailed error")
+				assert.Equal(t,
+					dmorph.TooOldError{ConfiguredLast: "01_base_table.sql", AppliedLast: "02_addon_table.sql"},
+					*tooOldErr,
+					"wrong details")
+			}
+		})
+	}
+}
+
+// TestMigrationTooOldAllowedUnrelated tests that migrations older than the applied ones still have to match.
+func TestMigrationTooOldAllowedUnrelated(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "01_a"}, oneMigration{key: "03_c"}))
+
+	require.NoError(t, runErr, "preparation migrations could not be run")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithAllowOlder(true),
+		dmorph.WithMigrations(oneMigration{key: "01_a"}, oneMigration{key: "02_b"}))
+
+	assert.ErrorIs(t, runErr, dmorph.ErrMigrationsUnrelated, "migrations did not give expected error")
+}
+
 // TestMigrationUnrelated0 tests what happens if the applied migrations are unrelated to existing ones.
 func TestMigrationUnrelated0(t *testing.T) {
 	t.Parallel()
@@ -262,6 +346,11 @@ func TestMigrationAppliedUnordered(t *testing.T) {
 		runErr,
 		dmorph.ErrMigrationsUnsorted,
 		"migrations did not give expected error")
+
+	var unsortedErr *dmorph.UnsortedError
+
+	require.ErrorAs(t, runErr, &unsortedErr, "expected detailed error")
+	assert.Equal(t, []string{"02_addon_table", "01_base_table"}, unsortedErr.Applied, "wrong applied migrations")
 }
 
 // TestMigrationOrder checks that the migrations ordering function works as expected.
@@ -819,3 +908,1926 @@ func TestMigrationInvalidAppliedSemVer(t *testing.T) {
 
 	assert.ErrorIs(t, runErr, dmorph.ErrMigrationKeyFormat)
 }
+
+// TestMigrationReadOnlyPending verifies that in read-only mode pending migrations are reported and not applied.
+func TestMigrationReadOnlyPending(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	migrationsDir, migrationsDirErr := fs.Sub(testMigrationsDir, "testData")
+
+	require.NoError(t, migrationsDirErr, "migrations directory could not be opened")
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFilesFS(migrationsDir, "01_base_table.sql"))
+
+	require.NoError(t, runErr, "preparation migrations could not be run")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithReadOnly(true),
+		dmorph.WithMigrationsFromFS(migrationsDir))
+
+	require.ErrorIs(t, runErr, dmorph.ErrMigrationsPending, "migrations did not give expected error")
+	assert.ErrorContains(t, runErr, "02_addon_table.sql", "pending migration not listed")
+
+	applied, appliedErr := dmorph.DialectSQLite().AppliedMigrations(t.Context(),
+		db, dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+	require.NoError(t, appliedErr, "applied migrations could not be read")
+	assert.Equal(t, []string{"01_base_table.sql"}, applied, "read-only run applied migrations")
+}
+
+// TestMigrationReadOnlyUpToDate verifies that read-only mode succeeds on an up-to-date database.
+func TestMigrationReadOnlyUpToDate(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	migrationsDir, migrationsDirErr := fs.Sub(testMigrationsDir, "testData")
+
+	require.NoError(t, migrationsDirErr, "migrations directory could not be opened")
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrationsDir))
+
+	require.NoError(t, runErr, "preparation migrations could not be run")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithReadOnly(true),
+		dmorph.WithMigrationsFromFS(migrationsDir))
+
+	assert.NoError(t, runErr, "up-to-date database should pass in read-only mode")
+}
+
+// TestMigrationReadOnlyNoTable verifies that read-only mode does not create the migration table and reports all
+// migrations as pending on a fresh database.
+func TestMigrationReadOnlyNoTable(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithReadOnly(true),
+		dmorph.WithMigrationsFromFiles("testData/01_base_table.sql"))
+
+	require.ErrorIs(t, runErr, dmorph.ErrMigrationsPending, "read-only run without migration table should fail")
+
+	var count int
+
+	require.NoError(t, db.QueryRowContext(t.Context(),
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&count))
+	assert.Zero(t, count, "read-only run created tables")
+}
+
+// TestMigrationIntegrityCheck verifies that Run checks the migration table structure if requested.
+func TestMigrationIntegrityCheck(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithIntegrityCheck(true),
+		dmorph.WithMigrationsFromFiles("testData/01_base_table.sql"))
+
+	require.NoError(t, runErr, "migrations could not be run")
+
+	_, execErr := db.ExecContext(t.Context(), `CREATE TABLE "tampered" (id VARCHAR(255), mgroup VARCHAR(255))`)
+	require.NoError(t, execErr, "tampered table could not be created")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithTableName("tampered"),
+		dmorph.WithIntegrityCheck(true),
+		dmorph.WithMigrationsFromFiles("testData/01_base_table.sql"))
+
+	require.ErrorIs(t, runErr, dmorph.ErrMigrationTableCorrupt, "expected corrupt migration table")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(okDialect{}),
+		dmorph.WithIntegrityCheck(true),
+		dmorph.WithMigrations(oneMigration{key: "001_test"}))
+
+	assert.ErrorIs(t, runErr, dmorph.ErrIntegrityCheckUnsupported, "expected unsupported integrity check")
+}
+
+type failingMigration struct {
+	key string
+}
+
+func (m failingMigration) Key() string {
+	return m.key
+}
+
+func (m failingMigration) Migrate(_ /* ctx */ context.Context, _ /* tx */ *sql.Tx) error {
+	return errors.New("failing migration")
+}
+
+// TestMigrationOnErrorContinue verifies that a failed migration is skipped, if the OnError function allows it.
+func TestMigrationOnErrorContinue(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	var failed []string
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithLog(slog.New(slog.DiscardHandler)),
+		dmorph.WithMigrations(failingMigration{key: "01_seed"}, oneMigration{key: "02_seed"}),
+		dmorph.WithOnError(func(key string, _ error) error {
+			failed = append(failed, key)
+
+			return nil
+		}))
+
+	require.NoError(t, runErr, "migrations should have continued")
+	assert.Equal(t, []string{"01_seed"}, failed, "unexpected failed migrations")
+
+	applied, appliedErr := dmorph.DialectSQLite().AppliedMigrations(t.Context(),
+		db, dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+	require.NoError(t, appliedErr, "applied migrations could not be read")
+	assert.Equal(t, []string{"02_seed"}, applied, "failed migration was registered")
+}
+
+// TestMigrationOnErrorAbort verifies that the error of the OnError function aborts the run.
+func TestMigrationOnErrorAbort(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	errAbort := errors.New("abort")
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(failingMigration{key: "01_seed"}, oneMigration{key: "02_seed"}),
+		dmorph.WithOnError(func(_ string, err error) error {
+			return errors.Join(errAbort, err)
+		}))
+
+	require.ErrorIs(t, runErr, errAbort, "expected abort error")
+
+	applied, appliedErr := dmorph.DialectSQLite().AppliedMigrations(t.Context(),
+		db, dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+	require.NoError(t, appliedErr, "applied migrations could not be read")
+	assert.Empty(t, applied, "migrations were applied after abort")
+}
+
+// TestMigrationWithTableComment verifies that Run sets the table comment, and ignores it for dialects without
+// comment support.
+func TestMigrationWithTableComment(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	dialect := dmorph.DialectSQLite()
+	dialect.CommentTemplate = `CREATE TABLE "%s_comment" AS SELECT '%s' AS comment`
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dialect),
+		dmorph.WithTableComment("dmorph migrations"),
+		dmorph.WithMigrationsFromFiles("testData/01_base_table.sql"))
+
+	require.NoError(t, runErr, "migrations could not be run")
+
+	var comment string
+
+	require.NoError(t, db.QueryRowContext(t.Context(), `SELECT comment FROM "migrations_comment"`).Scan(&comment))
+	assert.Equal(t, "dmorph migrations", comment, "comment was not set")
+
+	runErr = dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithTableComment("dmorph migrations"),
+		dmorph.WithMigrationsFromFiles("testData/01_base_table.sql"))
+
+	assert.NoError(t, runErr, "comment should be ignored without comment template")
+}
+
+// TestMigrationNilDB verifies that running on a nil database returns ErrNilDB.
+func TestMigrationNilDB(t *testing.T) {
+	t.Parallel()
+
+	morpher, err := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFiles("testData/01_base_table.sql"))
+
+	require.NoError(t, err, "morpher could not be created")
+
+	assert.ErrorIs(t, morpher.Run(t.Context(), nil), dmorph.ErrNilDB, "expected nil database error")
+
+	assert.ErrorIs(t,
+		dmorph.Run(t.Context(),
+			nil,
+			dmorph.WithDialect(dmorph.DialectSQLite()),
+			dmorph.WithMigrationsFromFiles("testData/01_base_table.sql")),
+		dmorph.ErrNilDB,
+		"expected nil database error")
+}
+
+// TestMigrationAfterEnsureTable verifies that the callback is called after the migration table is created and
+// that its error aborts the run.
+func TestMigrationAfterEnsureTable(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	var called string
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFiles("testData/01_base_table.sql"),
+		dmorph.WithAfterEnsureTable(func(ctx context.Context, db *sql.DB, tableName string) error {
+			called = tableName
+
+			_, err := db.ExecContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, tableName))
+
+			return err //nolint:wrapcheck
+		}))
+
+	require.NoError(t, runErr, "migrations could not be run")
+	assert.Equal(t, dmorph.MigrationTableName, called, "callback was not called")
+
+	errPrepare := errors.New("prepare")
+
+	runErr = dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFiles("testData/01_base_table.sql"),
+		dmorph.WithAfterEnsureTable(func(context.Context, *sql.DB, string) error {
+			return errPrepare
+		}))
+
+	assert.ErrorIs(t, runErr, errPrepare, "expected callback error")
+}
+
+// TestMigrationCountLatestKey verifies the count and latest key helpers.
+func TestMigrationCountLatestKey(t *testing.T) {
+	t.Parallel()
+
+	morpher, err := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationKeyProperties(dmorph.MigrationKeySemVerPrefix()),
+		dmorph.WithMigrations(
+			oneMigration{key: "v1.10.0_c"},
+			oneMigration{key: "v1.9.0_b"},
+			oneMigration{key: "v1.2.0_a"}))
+
+	require.NoError(t, err, "morpher could not be created")
+
+	assert.Equal(t, 3, morpher.Count(), "wrong migration count")
+	assert.Equal(t, "v1.10.0_c", morpher.LatestKey(), "wrong latest key")
+
+	empty := dmorph.Morpher{}
+
+	assert.Zero(t, empty.Count(), "wrong migration count")
+	assert.Empty(t, empty.LatestKey(), "wrong latest key")
+}
+
+// TestMigrationLogLevels verifies that per-entry and per-step messages are only logged at debug level.
+func TestMigrationLogLevels(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.Buffer{}
+
+	migrationsDir, migrationsDirErr := fs.Sub(testMigrationsDir, "testData")
+
+	require.NoError(t, migrationsDirErr, "migrations directory could not be opened")
+
+	runErr := dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithLog(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))),
+		dmorph.WithMigrationsFromFS(migrationsDir))
+
+	require.NoError(t, runErr, "migrations could not be run")
+
+	assert.Contains(t, buf.String(), "migration applied", "missing info message")
+	assert.NotContains(t, buf.String(), "migration step", "step logged at info level")
+	assert.NotContains(t, buf.String(), "msg=entry", "entry logged at info level")
+}
+
+// TestMigrationQuietDefault verifies that nothing is logged, not even to the default logger, unless a logger is
+// configured.
+//
+//nolint:paralleltest // the default logger is modified
+func TestMigrationQuietDefault(t *testing.T) {
+	buf := bytes.Buffer{}
+
+	defaultLog := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	t.Cleanup(func() { slog.SetDefault(defaultLog) })
+
+	migrations := fstest.MapFS{"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")}}
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations)),
+		"migrations could not be run")
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithLog(nil),
+		dmorph.WithMigrationsFromFS(migrations)),
+		"migrations could not be run with nil logger")
+
+	assert.Empty(t, buf.String(), "logged without configured logger")
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithLog(slog.Default()),
+		dmorph.WithMigrationsFromFS(migrations)),
+		"migrations could not be run with default logger")
+
+	assert.Contains(t, buf.String(), "migration applied", "not logged to configured default logger")
+}
+
+// TestMigrationStepLogEvery verifies that only the sampled steps of file migrations are logged, besides the first
+// and the last one.
+func TestMigrationStepLogEvery(t *testing.T) {
+	t.Parallel()
+
+	statements := make([]string, 0, 10)
+
+	for i := range 10 {
+		statements = append(statements, fmt.Sprintf("CREATE TABLE t%d (id INTEGER)", i))
+	}
+
+	tests := []struct {
+		options []dmorph.MorphOption
+		script  string
+		want    int
+	}{
+		{ // every step by default
+			script: strings.Join(statements, "\n;\n"),
+			want:   10,
+		},
+		{ // steps 0, 4, 8 and the unterminated last step 9
+			options: []dmorph.MorphOption{dmorph.WithStepLogEvery(4)},
+			script:  strings.Join(statements, "\n;\n"),
+			want:    4,
+		},
+		{ // steps 0, 4, 8 and the terminated last step 9
+			options: []dmorph.MorphOption{dmorph.WithStepLogEvery(4)},
+			script:  strings.Join(statements, "\n;\n") + "\n;\n",
+			want:    4,
+		},
+		{ // steps 0, 4, 8 and the last step 9 of the cached steps
+			options: []dmorph.MorphOption{dmorph.WithStepLogEvery(4), dmorph.WithCacheParsedMigrations(true)},
+			script:  strings.Join(statements, "\n;\n") + "\n;\n",
+			want:    4,
+		},
+		{ // steps 0, 4, 8 and the last step 9, each in its own transaction
+			options: []dmorph.MorphOption{dmorph.WithStepLogEvery(4), dmorph.WithTransactionPerStep(true)},
+			script:  strings.Join(statements, "\n;\n") + "\n;\n",
+			want:    4,
+		},
+		{ // step logging disabled
+			options: []dmorph.MorphOption{dmorph.WithStepLogEvery(0)},
+			script:  strings.Join(statements, "\n;\n"),
+			want:    0,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestMigrationStepLogEvery-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.Buffer{}
+
+			runErr := dmorph.Run(t.Context(),
+				openTempSQLite(t),
+				append(test.options,
+					dmorph.WithDialect(dmorph.DialectSQLite()),
+					dmorph.WithLog(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+					dmorph.WithMigrationsFromFS(fstest.MapFS{"01_tables.sql": {Data: []byte(test.script)}}))...)
+
+			require.NoError(t, runErr, "migrations could not be run")
+			assert.Equal(t, test.want, strings.Count(buf.String(), `msg="migration step"`), "wrong number of steps logged")
+
+			if test.want > 0 {
+				assert.Contains(t, buf.String(), "step=9\n",
+					"last step not logged")
+			}
+		})
+	}
+}
+
+// openTempSQLiteFile opens a temporary file-based SQLite database for testing and ensures it is closed after
+// the test ends.
+func openTempSQLiteFile(t *testing.T, name string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), name))
+	require.NoError(t, err, "DB could not be opened")
+	t.Cleanup(func() { _ = db.Close() })
+
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	return db
+}
+
+// TestMigrationRunAll verifies that the migrations are applied to all given databases.
+func TestMigrationRunAll(t *testing.T) {
+	t.Parallel()
+
+	for _, parallel := range []int{0, 2} {
+		t.Run(fmt.Sprintf("parallel-%d", parallel), func(t *testing.T) {
+			t.Parallel()
+
+			dbs := []*sql.DB{openTempSQLiteFile(t, "shard0.db"), openTempSQLiteFile(t, "shard1.db")}
+
+			morpher, err := dmorph.NewMorpher(
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithParallel(parallel),
+				dmorph.WithMigrationsFromSubFS(testMigrationsDir, "testData"))
+
+			require.NoError(t, err, "morpher could not be created")
+			require.NoError(t, morpher.RunAll(t.Context(), dbs), "migrations could not be run")
+
+			for i, db := range dbs {
+				applied, appliedErr := dmorph.DialectSQLite().AppliedMigrations(t.Context(),
+					db, dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+				require.NoError(t, appliedErr, "applied migrations could not be read")
+				assert.Len(t, applied, 2, "migrations not applied to database %d", i)
+			}
+		})
+	}
+}
+
+// concurrentMigration counts the migrations running at the same time, recording the maximum.
+type concurrentMigration struct {
+	running *atomic.Int32
+	maximum *atomic.Int32
+}
+
+func (m concurrentMigration) Key() string {
+	return "01_concurrent"
+}
+
+func (m concurrentMigration) Migrate(_ context.Context, _ /* tx */ *sql.Tx) error {
+	running := m.running.Add(1)
+	defer m.running.Add(-1)
+
+	for current := m.maximum.Load(); running > current; current = m.maximum.Load() {
+		if m.maximum.CompareAndSwap(current, running) {
+			break
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	return nil
+}
+
+// TestMigrationRunAllParallelLimit verifies that RunAll migrates all databases, but never more of them
+// concurrently than configured.
+func TestMigrationRunAllParallelLimit(t *testing.T) {
+	t.Parallel()
+
+	dbs := make([]*sql.DB, 0, 5)
+
+	for i := range cap(dbs) {
+		dbs = append(dbs, openTempSQLiteFile(t, fmt.Sprintf("shard%d.db", i)))
+	}
+
+	migration := concurrentMigration{running: &atomic.Int32{}, maximum: &atomic.Int32{}}
+
+	morpher, err := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithParallel(2),
+		dmorph.WithMigrations(migration))
+
+	require.NoError(t, err, "morpher could not be created")
+	require.NoError(t, morpher.RunAll(t.Context(), dbs), "migrations could not be run")
+
+	for i, db := range dbs {
+		assert.Equal(t, []string{"01_concurrent"}, appliedSQLite(t, db), "migration not applied to database %d", i)
+	}
+
+	assert.LessOrEqual(t, migration.maximum.Load(), int32(2), "too many databases migrated concurrently")
+}
+
+// TestMigrationRunAllError verifies that the errors of failed databases are reported with their index.
+func TestMigrationRunAllError(t *testing.T) {
+	t.Parallel()
+
+	closed := openTempSQLiteFile(t, "closed.db")
+	require.NoError(t, closed.Close())
+
+	morpher, err := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithParallel(2),
+		dmorph.WithMigrationsFromSubFS(testMigrationsDir, "testData"))
+
+	require.NoError(t, err, "morpher could not be created")
+
+	runErr := morpher.RunAll(t.Context(), []*sql.DB{openTempSQLiteFile(t, "ok.db"), closed, nil})
+
+	require.Error(t, runErr, "expected error")
+	assert.NotContains(t, runErr.Error(), "database 0", "database 0 should not have failed")
+	assert.ErrorContains(t, runErr, "database 1", "database 1 not reported")
+	assert.ErrorContains(t, runErr, "database 2", "database 2 not reported")
+	assert.ErrorIs(t, runErr, dmorph.ErrNilDB, "expected nil database error")
+
+	assert.ErrorIs(t, (&dmorph.Morpher{}).RunAll(t.Context(), nil), dmorph.ErrNoDialect)
+}
+
+// TestMigrationFastPath verifies that the fast path returns early on up-to-date databases, and otherwise
+// behaves like the normal path.
+func TestMigrationFastPath(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	migrationsDir, migrationsDirErr := fs.Sub(testMigrationsDir, "testData")
+
+	require.NoError(t, migrationsDirErr, "migrations directory could not be opened")
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithFastPath(true),
+		dmorph.WithMigrationsFromFilesFS(migrationsDir, "01_base_table.sql"))
+
+	require.NoError(t, runErr, "preparation migrations could not be run")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithFastPath(true),
+		dmorph.WithMigrationsFromFS(migrationsDir))
+
+	require.NoError(t, runErr, "migrations could not be run")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithFastPath(true),
+		dmorph.WithMigrations(failingMigration{key: "01_base_table.sql"}, failingMigration{key: "02_addon_table.sql"}))
+
+	require.NoError(t, runErr, "fast path should not run any migration")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithFastPath(true),
+		dmorph.WithMigrationsFromFilesFS(migrationsDir, "01_base_table.sql"))
+
+	assert.ErrorIs(t, runErr, dmorph.ErrMigrationsTooOld, "migrations did not give expected error")
+}
+
+// benchmarkMigrations generates the given number of migrations doing nothing.
+func benchmarkMigrations(n int) []dmorph.Migration {
+	result := make([]dmorph.Migration, 0, n)
+
+	for i := range n {
+		result = append(result, oneMigration{key: fmt.Sprintf("%05d_benchmark", i)})
+	}
+
+	return result
+}
+
+// BenchmarkRunCold measures the run of many migrations on an empty database.
+func BenchmarkRunCold(b *testing.B) {
+	migrations := benchmarkMigrations(1000)
+
+	for b.Loop() {
+		db, err := sql.Open("sqlite3", ":memory:")
+		require.NoError(b, err, "DB could not be opened")
+
+		db.SetMaxOpenConns(1)
+
+		require.NoError(b, dmorph.Run(b.Context(),
+			db,
+			dmorph.WithDialect(dmorph.DialectSQLite()),
+			dmorph.WithLog(slog.New(slog.DiscardHandler)),
+			dmorph.WithMigrations(migrations...)))
+
+		_ = db.Close()
+	}
+}
+
+// BenchmarkRunMigrated measures the run of many migrations on a fully migrated database, with and without
+// the fast path.
+func BenchmarkRunMigrated(b *testing.B) {
+	for _, fastPath := range []bool{false, true} {
+		b.Run(fmt.Sprintf("fastPath-%v", fastPath), func(b *testing.B) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			require.NoError(b, err, "DB could not be opened")
+			b.Cleanup(func() { _ = db.Close() })
+
+			db.SetMaxOpenConns(1)
+
+			morpher, err := dmorph.NewMorpher(
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithLog(slog.New(slog.DiscardHandler)),
+				dmorph.WithFastPath(fastPath),
+				dmorph.WithMigrations(benchmarkMigrations(1000)...))
+
+			require.NoError(b, err, "morpher could not be created")
+			require.NoError(b, morpher.Run(b.Context(), db), "preparation migrations could not be run")
+
+			for b.Loop() {
+				require.NoError(b, morpher.Run(b.Context(), db))
+			}
+		})
+	}
+}
+
+// TestMigrationUnrelatedDetails verifies that ErrMigrationsUnrelated states the first differing position.
+func TestMigrationUnrelatedDetails(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	migrationsDir, migrationsDirErr := fs.Sub(testMigrationsDir, "testData")
+
+	require.NoError(t, migrationsDirErr, "migrations directory could not be opened")
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrationsDir))
+
+	require.NoError(t, runErr, "preparation migrations could not be run")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "01_base_table.sql"}, oneMigration{key: "02_other_table.sql"},
+			oneMigration{key: "03_new_table.sql"}))
+
+	require.ErrorIs(t, runErr, dmorph.ErrMigrationsUnrelated, "migrations did not give expected error")
+
+	var unrelatedErr *dmorph.UnrelatedError
+
+	require.ErrorAs(t, runErr, &unrelatedErr, "expected detailed error")
+	assert.Equal(t,
+		dmorph.UnrelatedError{Position: 1, Applied: "02_addon_table.sql", Configured: "02_other_table.sql"},
+		*unrelatedErr,
+		"wrong divergence")
+	assert.EqualError(t, runErr,
+		`migrations unrelated: at position 1 database has "02_addon_table.sql" but source has "02_other_table.sql"`)
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "02_addon_table.sql"}))
+
+	require.ErrorAs(t, runErr, &unrelatedErr, "expected detailed error")
+	assert.Equal(t, dmorph.UnrelatedError{Position: 0, Applied: "01_base_table.sql", Configured: "02_addon_table.sql"},
+		*unrelatedErr, "wrong divergence")
+}
+
+// TestMigrationSetupStatements verifies that the setup statements are executed before the migrations.
+func TestMigrationSetupStatements(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLiteFile(t, "setup.db")
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithSetupStatements("PRAGMA journal_mode=WAL", "PRAGMA foreign_keys=ON"),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, runErr, "migrations could not be run")
+
+	var journalMode string
+
+	require.NoError(t, db.QueryRowContext(t.Context(), "PRAGMA journal_mode").Scan(&journalMode),
+		"journal mode could not be read")
+	assert.Equal(t, "wal", journalMode, "WAL not enabled")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithSetupStatements("PRAGMA"),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.ErrorContains(t, runErr, "could not execute setup statement", "invalid setup statement not reported")
+}
+
+// pragmaMigration reads the foreign key enforcement of SQLite within its transaction.
+type pragmaMigration struct {
+	foreignKeys *int // foreign key enforcement read by the migration
+}
+
+func (m pragmaMigration) Key() string {
+	return "01_pragma"
+}
+
+func (m pragmaMigration) Migrate(ctx context.Context, tx *sql.Tx) error {
+	return tx.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(m.foreignKeys) //nolint:wrapcheck // test migration
+}
+
+// TestMigrationSetupStatementsPooled verifies that settings specific to a connection take effect in the
+// migrations, even if they get other connections of the pool than the setup statements executed once per run.
+func TestMigrationSetupStatementsPooled(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLiteFile(t, "pooled.db")
+
+	// every connection is closed after use, so that no connection is used twice
+	db.SetMaxOpenConns(2)
+	db.SetMaxIdleConns(0)
+
+	// the driver enables the foreign keys on each new connection, so they are disabled to detect the setting
+	foreignKeys := 1
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithSetupStatements("PRAGMA foreign_keys=OFF"),
+		dmorph.WithMigrations(pragmaMigration{foreignKeys: &foreignKeys})),
+		"migrations could not be run")
+
+	assert.Equal(t, 0, foreignKeys, "setup statement not active in migration")
+}
+
+// TestMigrationAssumeTableExists verifies that the migration table is not ensured if it is assumed to exist.
+func TestMigrationAssumeTableExists(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	// a dialect unable to create the migration table, like a user without DDL privileges
+	dialect := dmorph.DialectSQLite()
+	dialect.CreateTemplate = "CREATE TABLE %s INVALID"
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dialect),
+		dmorph.WithAssumeTableExists(true),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.ErrorContains(t, runErr, "could not get applied migrations", "missing table not reported")
+
+	require.NoError(t,
+		dmorph.DialectSQLite().EnsureMigrationTableExists(t.Context(), db, dmorph.MigrationTableName),
+		"migration table could not be created")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dialect),
+		dmorph.WithAssumeTableExists(true),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, runErr, "migrations could not be run")
+
+	applied, appliedErr := dialect.AppliedMigrations(t.Context(),
+		db, dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+	require.NoError(t, appliedErr, "applied migrations could not be read")
+	assert.Equal(t, []string{"01_test"}, applied, "migration not applied")
+}
+
+// TestMigrationLogDialect verifies that the name of the dialect is added to the log messages.
+func TestMigrationLogDialect(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.Buffer{}
+
+	runErr := dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithLog(slog.New(slog.NewTextHandler(&buf, nil))),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, runErr, "migrations could not be run")
+	assert.Contains(t, buf.String(), "msg=\"migration applied\" dialect=sqlite", "dialect not logged")
+}
+
+// TestMigrationRunChanged verifies that RunChanged only reports a change if migrations were applied.
+func TestMigrationRunChanged(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	changed, runErr := morpher.RunChanged(t.Context(), db)
+
+	require.NoError(t, runErr, "migrations could not be run")
+	assert.True(t, changed, "first run did not report a change")
+
+	changed, runErr = morpher.RunChanged(t.Context(), db)
+
+	require.NoError(t, runErr, "migrations could not be run again")
+	assert.False(t, changed, "second run reported a change")
+}
+
+// selfRegisteringMigration registers itself, like a concurrently running instance would do.
+type selfRegisteringMigration struct {
+	key string
+}
+
+func (m selfRegisteringMigration) Key() string {
+	return m.key
+}
+
+func (m selfRegisteringMigration) Migrate(ctx context.Context, tx *sql.Tx) error {
+	return dmorph.DialectSQLite().RegisterMigration(ctx, tx, m.key, dmorph.MigrationTableName,
+		dmorph.MigrationGroupName)
+}
+
+// TestMigrationIdempotentRegister verifies that migrations registered already do not fail in idempotent mode.
+func TestMigrationIdempotentRegister(t *testing.T) {
+	t.Parallel()
+
+	runErr := dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(selfRegisteringMigration{key: "01_test"}))
+
+	require.ErrorIs(t, runErr, dmorph.ErrMigrationRegistered, "duplicate registration not reported")
+
+	runErr = dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithIdempotentRegister(true),
+		dmorph.WithMigrations(selfRegisteringMigration{key: "01_test"}))
+
+	require.NoError(t, runErr, "idempotent registration failed")
+
+	runErr = dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(okDialect{}),
+		dmorph.WithIdempotentRegister(true),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.ErrorIs(t, runErr, dmorph.ErrIdempotentRegisterUnsupported, "unsupported dialect not reported")
+
+	_, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(okDialect{}),
+		dmorph.WithIdempotentRegister(true),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.ErrorIs(t, morpherErr, dmorph.ErrIdempotentRegisterUnsupported, "unsupported dialect not rejected early")
+}
+
+// TestMigrationPoolWaitCanceled verifies that waiting for a connection of an exhausted pool is bounded by the
+// context.
+func TestMigrationPoolWaitCanceled(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	// exhaust the pool, it only has a single connection
+	conn, connErr := db.Conn(t.Context())
+
+	require.NoError(t, connErr, "connection could not be acquired")
+
+	defer func() { _ = conn.Close() }()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t,
+		morpher.TapplyMigrations(ctx, db, ""),
+		context.DeadlineExceeded,
+		"waiting for a connection not canceled")
+
+	assert.ErrorIs(t, morpher.Run(ctx, db), context.DeadlineExceeded, "run not canceled")
+}
+
+// TestLegacyMigration verifies that migration functions without context can be applied.
+func TestLegacyMigration(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(dmorph.LegacyMigration("01_legacy", func(tx *sql.Tx) error {
+			_, err := tx.Exec("CREATE TABLE legacy (id INTEGER)")
+
+			return err
+		})))
+
+	require.NoError(t, runErr, "migrations could not be run")
+
+	var count int
+
+	require.NoError(t, db.QueryRowContext(t.Context(),
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'legacy'").Scan(&count))
+	assert.Equal(t, 1, count, "legacy migration not applied")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	assert.ErrorIs(t,
+		dmorph.LegacyMigration("01_legacy", func(_ *sql.Tx) error { return nil }).Migrate(ctx, nil),
+		context.Canceled,
+		"canceled context not reported")
+}
+
+// TestMigrationHistoryPage verifies that the applied migrations can be read page by page.
+func TestMigrationHistoryPage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		dialect dmorph.Dialect
+	}{
+		{name: "SQLite", dialect: dmorph.DialectSQLite()},
+		{name: "SQLiteNumbered", dialect: dmorph.DialectSQLiteNumbered()},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestMigrationHistoryPage-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			morpher, morpherErr := dmorph.NewMorpher(
+				dmorph.WithDialect(test.dialect),
+				dmorph.WithMigrations(oneMigration{key: "01_a"}, oneMigration{key: "02_b"},
+					oneMigration{key: "03_c"}))
+
+			require.NoError(t, morpherErr, "morpher could not be created for %v", test.name)
+			require.NoError(t, morpher.Run(t.Context(), db), "migrations could not be run for %v", test.name)
+
+			page, pageErr := morpher.HistoryPage(t.Context(), db, 2, 1)
+
+			require.NoError(t, pageErr, "history could not be read for %v", test.name)
+			require.Len(t, page, 2, "wrong page size for %v", test.name)
+			assert.Equal(t, "02_b", page[0].ID, "wrong first entry for %v", test.name)
+			assert.Equal(t, "03_c", page[1].ID, "wrong second entry for %v", test.name)
+			assert.False(t, page[0].Applied.IsZero(), "application time missing for %v", test.name)
+
+			page, pageErr = morpher.HistoryPage(t.Context(), db, 2, 3)
+
+			require.NoError(t, pageErr, "history could not be read for %v", test.name)
+			assert.Empty(t, page, "page after the end not empty for %v", test.name)
+		})
+	}
+}
+
+// TestMigrationHistoryPageUnsupported verifies that dialects without history support report it.
+func TestMigrationHistoryPageUnsupported(t *testing.T) {
+	t.Parallel()
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(okDialect{}),
+		dmorph.WithMigrations(oneMigration{key: "01_a"}))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	_, pageErr := morpher.HistoryPage(t.Context(), openTempSQLite(t), 1, 0)
+
+	require.ErrorIs(t, pageErr, dmorph.ErrHistoryUnsupported, "unsupported dialect not reported")
+
+	_, pageErr = dmorph.DialectCSVQ().HistoryPage(t.Context(), openTempSQLite(t), "migrations", "default", 1, 0)
+
+	require.ErrorIs(t, pageErr, dmorph.ErrHistoryUnsupported, "missing template not reported")
+}
+
+// TestMigrationRegisterValues verifies that additional values are registered with the migrations.
+func TestMigrationRegisterValues(t *testing.T) {
+	t.Parallel()
+
+	const createTemplate = `
+		CREATE TABLE IF NOT EXISTS "%s" (
+			id        VARCHAR(255) NOT NULL,
+			mgroup    VARCHAR(255) NOT NULL,
+			create_ts TIMESTAMP DEFAULT current_timestamp,
+			ticket    VARCHAR(255) NOT NULL,
+			PRIMARY KEY (id, mgroup)
+		)`
+
+	named := dmorph.DialectSQLite()
+	named.CreateTemplate = createTemplate
+	named.RegisterTemplate = `INSERT INTO "%s" (id, mgroup, ticket) VALUES(:id, :mgroup, :ticket)`
+
+	numbered := dmorph.DialectSQLiteNumbered()
+	numbered.CreateTemplate = createTemplate
+	numbered.RegisterTemplate = `INSERT INTO "%s" (ticket, id, mgroup) VALUES(?, ?, ?)`
+	numbered.RegisterMigrationParamsOrder = []dmorph.ParamName{"ticket", dmorph.ParamNameID, dmorph.ParamNameMGroup}
+
+	tests := []struct {
+		name    string
+		dialect dmorph.Dialect
+	}{
+		{name: "SQLite", dialect: named},
+		{name: "SQLiteNumbered", dialect: numbered},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestMigrationRegisterValues-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			runErr := dmorph.Run(t.Context(),
+				db,
+				dmorph.WithDialect(test.dialect),
+				dmorph.WithRegisterValues(func(key string) map[string]any {
+					return map[string]any{"ticket": "T-" + key}
+				}),
+				dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+			require.NoError(t, runErr, "migrations could not be run for %v", test.name)
+
+			var ticket string
+
+			require.NoError(t,
+				db.QueryRowContext(t.Context(), "SELECT ticket FROM migrations WHERE id = '01_test'").Scan(&ticket),
+				"ticket could not be read for %v", test.name)
+			assert.Equal(t, "T-01_test", ticket, "wrong ticket for %v", test.name)
+		})
+	}
+}
+
+// TestMigrationRegisterValuesUnsupported verifies that additional values are rejected if they cannot be
+// registered.
+func TestMigrationRegisterValuesUnsupported(t *testing.T) {
+	t.Parallel()
+
+	values := func(_ string) map[string]any { return nil }
+
+	runErr := dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(okDialect{}),
+		dmorph.WithRegisterValues(values),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.ErrorIs(t, runErr, dmorph.ErrRegisterValuesUnsupported, "unsupported dialect not reported")
+
+	db := openTempSQLite(t)
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithIdempotentRegister(true),
+		dmorph.WithRegisterValues(values),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.ErrorIs(t, runErr, dmorph.ErrRegisterValuesUnsupported, "idempotent registration not rejected")
+
+	var count int
+
+	require.NoError(t, db.QueryRowContext(t.Context(),
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'migrations'").Scan(&count))
+	assert.Zero(t, count, "migration table created for rejected configuration")
+}
+
+// TestMigrationTableNameEnv verifies the precedence of the migration table name sources.
+//
+//nolint:paralleltest // the environment is modified
+func TestMigrationTableNameEnv(t *testing.T) {
+	tests := []struct {
+		env     string
+		options []dmorph.MorphOption
+		want    string
+	}{
+		{env: "", want: dmorph.MigrationTableName},
+		{env: "env_migrations", want: "env_migrations"},
+		{env: "invalid-name", want: dmorph.MigrationTableName},
+		{env: "env_migrations", options: []dmorph.MorphOption{dmorph.WithTableName("opt_migrations")},
+			want: "opt_migrations"},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestMigrationTableNameEnv-%d", k), func(t *testing.T) {
+			t.Setenv(dmorph.MigrationTableNameEnv, test.env)
+
+			morpher, morpherErr := dmorph.NewMorpher(append([]dmorph.MorphOption{
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrations(oneMigration{key: "01_test"}),
+			}, test.options...)...)
+
+			require.NoError(t, morpherErr, "morpher could not be created")
+			assert.Equal(t, test.want, morpher.TableName, "wrong table name")
+			assert.Equal(t, `"`+test.want+`"`, morpher.EffectiveTableName(), "wrong effective table name")
+		})
+	}
+}
+
+// TestMigrationEffectiveTableName verifies that the effective table name is the one used by the dialect, quoted
+// like in its statements, and that schema-qualified names are only accepted by dialects quoting them.
+func TestMigrationEffectiveTableName(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithTableName("custom_migrations"),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+	require.NoError(t, morpher.Run(t.Context(), db), "migrations could not be run")
+
+	var id string
+
+	require.NoError(t, db.QueryRowContext(t.Context(),
+		"SELECT id FROM "+morpher.EffectiveTableName()).Scan(&id), "effective table not used")
+	assert.Equal(t, "01_test", id, "wrong migration registered")
+
+	_, morpherErr = dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithTableName("main.custom_migrations"),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.ErrorIs(t, morpherErr, dmorph.ErrMigrationTableNameInvalid, "schema-qualified name not rejected")
+
+	morpher, morpherErr = dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite().WithQuotedTableName()),
+		dmorph.WithTableName("main.qualified_migrations"),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+	require.NoError(t, morpher.Run(t.Context(), db), "migrations could not be run")
+	assert.Equal(t, `"main"."qualified_migrations"`, morpher.EffectiveTableName(), "wrong effective table name")
+
+	require.NoError(t, db.QueryRowContext(t.Context(),
+		"SELECT id FROM "+morpher.EffectiveTableName()).Scan(&id), "effective table not used")
+	assert.Equal(t, "01_test", id, "wrong migration registered")
+}
+
+// TestMigrationApplyOne verifies that the next pending migration can be applied on its own, and that later
+// runs complete the remaining migrations.
+func TestMigrationApplyOne(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "01_a"}, oneMigration{key: "02_b"}, oneMigration{key: "03_c"}))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	require.ErrorIs(t, morpher.ApplyOne(t.Context(), db, "02_b"), dmorph.ErrMigrationNotNext,
+		"out of order migration not rejected")
+	require.NoError(t, morpher.ApplyOne(t.Context(), db, "01_a"), "migration could not be applied")
+	assert.Equal(t, []string{"01_a"}, appliedSQLite(t, db), "wrong migrations applied")
+
+	require.ErrorIs(t, morpher.ApplyOne(t.Context(), db, "01_a"), dmorph.ErrMigrationRegistered,
+		"second application not rejected")
+	require.ErrorIs(t, morpher.ApplyOne(t.Context(), db, "04_d"), dmorph.ErrMigrationUnknown,
+		"unknown migration not rejected")
+
+	require.NoError(t, morpher.ApplyOne(t.Context(), db, "02_b"), "next migration could not be applied")
+	require.NoError(t, morpher.Run(t.Context(), db), "remaining migrations could not be run")
+	assert.Equal(t, []string{"01_a", "02_b", "03_c"}, appliedSQLite(t, db), "wrong migrations applied")
+}
+
+// TestMigrationForceReapply verifies that an applied migration can be executed again, keeping its registration.
+func TestMigrationForceReapply(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	migrations := fstest.MapFS{
+		"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER PRIMARY KEY)")},
+		"02_data.sql": {Data: []byte("INSERT OR IGNORE INTO t0 (id) VALUES (1)")},
+	}
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	require.ErrorIs(t, morpher.ForceReapply(t.Context(), db, "02_data.sql"), dmorph.ErrMigrationNotApplied,
+		"re-application of pending migration not rejected")
+	require.NoError(t, morpher.Run(t.Context(), db), "migrations could not be run")
+
+	// iterating on the migration file during development
+	migrations["02_data.sql"] = &fstest.MapFile{Data: []byte("INSERT OR IGNORE INTO t0 (id) VALUES (1), (2)")}
+
+	require.NoError(t, morpher.ForceReapply(t.Context(), db, "02_data.sql"), "migration could not be re-applied")
+	require.NoError(t, morpher.ForceReapply(t.Context(), db, "02_data.sql"), "migration not idempotent")
+	require.ErrorIs(t, morpher.ForceReapply(t.Context(), db, "03_none.sql"), dmorph.ErrMigrationUnknown,
+		"unknown migration not rejected")
+
+	var rows int
+
+	require.NoError(t, db.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM t0").Scan(&rows), "could not count rows")
+	assert.Equal(t, 2, rows, "migration not re-applied")
+	assert.Equal(t, []string{"01_base.sql", "02_data.sql"}, appliedSQLite(t, db), "registration changed")
+	require.NoError(t, morpher.Run(t.Context(), db), "database inconsistent after re-application")
+}
+
+// TestMigrationKeyTooLong verifies that keys not fitting into the id column are rejected before running.
+func TestMigrationKeyTooLong(t *testing.T) {
+	t.Parallel()
+
+	key := strings.Repeat("k", 300)
+
+	migrations := fstest.MapFS{key + ".sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")}}
+
+	runErr := dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations))
+
+	require.ErrorIs(t, runErr, dmorph.ErrMigrationKeyTooLong, "expected key too long")
+
+	dialect, dialectErr := dmorph.DialectSQLite().WithIDLength(512)
+
+	require.NoError(t, dialectErr, "expected no error resizing the id column")
+
+	runErr = dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dialect),
+		dmorph.WithMigrationsFromFS(migrations))
+
+	assert.NoError(t, runErr, "expected no error with resized id column")
+}
+
+// TestMigrationStatus verifies that the state of the migrations is reported as stable JSON.
+func TestMigrationStatus(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{
+			"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+		})), "expected no error running migrations")
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{
+			"01_base.sql":  {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+			"02_addon.sql": {Data: []byte("CREATE TABLE t1 (id INTEGER)")},
+		}),
+		dmorph.WithMigrations(oneMigration{key: "03_func"}))
+
+	require.NoError(t, morpherErr, "expected no error creating the morpher")
+
+	status, statusErr := morpher.Status(t.Context(), db)
+
+	require.NoError(t, statusErr, "expected no error getting the status")
+
+	encoded, encodeErr := json.Marshal(status)
+
+	require.NoError(t, encodeErr, "expected status to be encodable")
+	assert.JSONEq(t, `[
+			{"key": "01_base.sql", "state": "applied", "origin": "fs:01_base.sql"},
+			{"key": "02_addon.sql", "state": "pending", "origin": "fs:02_addon.sql"},
+			{"key": "03_func", "state": "pending", "origin": "func"}
+		]`, string(encoded), "wrong status")
+
+	_, statusErr = morpher.Status(t.Context(), nil)
+
+	assert.ErrorIs(t, statusErr, dmorph.ErrNilDB, "expected nil database error")
+}
+
+// TestMigrationPending verifies that the pending migrations are reported in order without applying them.
+func TestMigrationPending(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	migrations := fstest.MapFS{
+		"01_base.sql":  {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+		"02_addon.sql": {Data: []byte("CREATE TABLE t1 (id INTEGER)")},
+	}
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations))
+
+	require.NoError(t, morpherErr, "expected no error creating the morpher")
+
+	pending, pendingErr := morpher.Pending(t.Context(), db)
+
+	require.NoError(t, pendingErr, "expected no error on fresh database")
+	assert.Equal(t, []string{"01_base.sql", "02_addon.sql"}, pending, "expected all migrations pending")
+
+	require.NoError(t, morpher.Run(t.Context(), db), "expected no error running migrations")
+
+	pending, pendingErr = morpher.Pending(t.Context(), db)
+
+	require.NoError(t, pendingErr, "expected no error on migrated database")
+	assert.Empty(t, pending, "expected no migrations pending")
+
+	unrelated, unrelatedErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{
+			"01_other.sql": {Data: []byte("CREATE TABLE t2 (id INTEGER)")},
+			"03_more.sql":  {Data: []byte("CREATE TABLE t3 (id INTEGER)")},
+		}))
+
+	require.NoError(t, unrelatedErr, "expected no error creating the morpher")
+
+	_, pendingErr = unrelated.Pending(t.Context(), db)
+
+	assert.ErrorIs(t, pendingErr, dmorph.ErrMigrationsUnrelated, "expected unrelated migrations")
+}
+
+// flakyDialect fails to create the migration table for the given number of times.
+type flakyDialect struct {
+	okDialect
+
+	failures int
+	calls    int
+}
+
+func (f *flakyDialect) EnsureMigrationTableExists(
+	_ /* ctx */ context.Context,
+	_ /* db */ *sql.DB,
+	_ /* tableName */ string) error {
+
+	f.calls++
+
+	if f.calls <= f.failures {
+		return errors.New("database starting up")
+	}
+
+	return nil
+}
+
+// TestMigrationTableCreateRetry verifies that the creation of the migration table is retried as configured.
+func TestMigrationTableCreateRetry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		attempts  int
+		failures  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{attempts: 0, failures: 1, wantCalls: 1, wantErr: true},
+		{attempts: 3, failures: 1, wantCalls: 2, wantErr: false},
+		{attempts: 3, failures: 3, wantCalls: 3, wantErr: true},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestMigrationTableCreateRetry-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			dialect := &flakyDialect{failures: test.failures}
+
+			runErr := dmorph.Run(t.Context(),
+				openTempSQLite(t),
+				dmorph.WithDialect(dialect),
+				dmorph.WithMigrations(oneMigration{key: "01"}),
+				dmorph.WithTableCreateRetry(test.attempts, time.Millisecond))
+
+			if test.wantErr {
+				assert.Error(t, runErr, "expected error creating the migration table")
+			} else {
+				assert.NoError(t, runErr, "expected no error")
+			}
+
+			assert.Equal(t, test.wantCalls, dialect.calls, "expected attempts to create the migration table")
+		})
+	}
+}
+
+// TestMigrationOnSkip verifies that the skip callback is called for each migration already applied.
+func TestMigrationOnSkip(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	migrations := fstest.MapFS{
+		"01_base.sql":  {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+		"02_addon.sql": {Data: []byte("CREATE TABLE t1 (id INTEGER)")},
+	}
+
+	var skipped []string
+
+	onSkip := func(key string) { skipped = append(skipped, key) }
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFilesFS(migrations, "01_base.sql"),
+		dmorph.WithOnSkip(onSkip))
+
+	require.NoError(t, runErr, "expected no error applying the first migration")
+	assert.Empty(t, skipped, "expected no skipped migration")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations),
+		dmorph.WithOnSkip(onSkip))
+
+	require.NoError(t, runErr, "expected no error applying the second migration")
+	assert.Equal(t, []string{"01_base.sql"}, skipped, "expected the first migration skipped")
+}
+
+// numericKeyOrder orders keys like `10.sql` by their numeric prefix.
+func numericKeyOrder(m, n string) int {
+	mNum, _ := strconv.Atoi(strings.TrimSuffix(m, ".sql"))
+	nNum, _ := strconv.Atoi(strings.TrimSuffix(n, ".sql"))
+
+	return cmp.Compare(mNum, nNum)
+}
+
+// TestMigrationNumericOrderPartial verifies that migrations already applied are skipped by their keys, not by
+// their string order, if a numeric order is configured.
+func TestMigrationNumericOrderPartial(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	numeric := dmorph.MigrationKeyProperties{
+		MigrationOrder:    func(m, n dmorph.Migration) int { return numericKeyOrder(m.Key(), n.Key()) },
+		MigrationKeyOrder: numericKeyOrder,
+		MigrationKeyValid: func(m string) bool {
+			_, err := strconv.Atoi(strings.TrimSuffix(m, ".sql"))
+
+			return err == nil
+		},
+	}
+
+	migrations := fstest.MapFS{
+		"9.sql":  {Data: []byte("CREATE TABLE t9 (id INTEGER)")},
+		"10.sql": {Data: []byte("CREATE TABLE t10 (id INTEGER)")},
+		"11.sql": {Data: []byte("CREATE TABLE t11 (id INTEGER)")},
+	}
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationKeyProperties(numeric),
+		dmorph.WithMigrationsFromFilesFS(migrations, "9.sql", "10.sql")),
+		"expected no error applying the first migrations")
+
+	var skipped []string
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationKeyProperties(numeric),
+		dmorph.WithMigrationsFromFS(migrations),
+		dmorph.WithOnSkip(func(key string) { skipped = append(skipped, key) })),
+		"expected no error applying the remaining migration")
+
+	assert.Equal(t, []string{"9.sql", "10.sql"}, skipped, "wrong skipped migrations")
+	assert.Equal(t, []string{"9.sql", "10.sql", "11.sql"}, appliedSQLite(t, db), "wrong applied migrations")
+}
+
+// TestMorpherConcurrentRun verifies that a Morpher can be run concurrently on multiple databases without
+// modifying it, e.g. by sorting its migrations. It is intended to be run with the race detector.
+func TestMorpherConcurrentRun(t *testing.T) {
+	t.Parallel()
+
+	const runs = 8
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "03_func"}),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{
+			"02_addon.sql": {Data: []byte("CREATE TABLE t1 (id INTEGER)")},
+			"01_base.sql":  {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+		}))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	configured := slices.Clone(morpher.Migrations)
+	dbs := make([]*sql.DB, runs)
+
+	for i := range dbs {
+		dbs[i] = openTempSQLite(t)
+	}
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, runs)
+
+	for i, db := range dbs {
+		wg.Go(func() {
+			errs[i] = morpher.Run(t.Context(), db)
+		})
+	}
+
+	wg.Wait()
+
+	for i, db := range dbs {
+		require.NoError(t, errs[i], "run %d failed", i)
+		assert.Equal(t, []string{"01_base.sql", "02_addon.sql", "03_func"}, appliedSQLite(t, db),
+			"wrong applied migrations of run %d", i)
+	}
+
+	assert.Equal(t, migrationKeysOf(configured), migrationKeysOf(morpher.Migrations), "migrations modified by runs")
+}
+
+// migrationKeysOf returns the keys of the given migrations.
+func migrationKeysOf(migrations []dmorph.Migration) []string {
+	result := make([]string, 0, len(migrations))
+
+	for _, mi := range migrations {
+		result = append(result, mi.Key())
+	}
+
+	return result
+}
+
+// TestCheckConsistency verifies that applied migrations are checked against the configured ones without a
+// database, returning the same errors as Run.
+func TestCheckConsistency(t *testing.T) {
+	t.Parallel()
+
+	configured := []dmorph.Migration{
+		oneMigration{key: "02_addon"},
+		oneMigration{key: "01_base"},
+		oneMigration{key: "03_extra"},
+	}
+
+	tests := []struct {
+		applied []string
+		cmp     func(a, b dmorph.Migration) int
+		wantErr error
+	}{
+		{ // nothing applied
+			applied: nil,
+		},
+		{ // partially applied, alphabetical order by default
+			applied: []string{"01_base", "02_addon"},
+		},
+		{ // applied in wrong order
+			applied: []string{"02_addon", "01_base"},
+			wantErr: dmorph.ErrMigrationsUnsorted,
+		},
+		{ // applied migrations newer than the configured ones
+			applied: []string{"01_base", "02_addon", "03_extra", "04_newer"},
+			wantErr: dmorph.ErrMigrationsTooOld,
+		},
+		{ // applied migration not configured
+			applied: []string{"01_base", "02_other"},
+			wantErr: dmorph.ErrMigrationsUnrelated,
+		},
+		{ // configured order given by the comparator
+			applied: []string{"03_extra", "02_addon"},
+			cmp: func(a, b dmorph.Migration) int {
+				return strings.Compare(b.Key(), a.Key())
+			},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestCheckConsistency-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			assert.ErrorIs(t, dmorph.CheckConsistency(test.applied, configured, test.cmp), test.wantErr,
+				"unexpected consistency result")
+		})
+	}
+
+	var unrelated *dmorph.UnrelatedError
+
+	require.ErrorAs(t, dmorph.CheckConsistency([]string{"01_base", "02_other"}, configured, nil), &unrelated,
+		"expected unrelated error details")
+	assert.Equal(t, 1, unrelated.Position, "wrong position of the unrelated migration")
+	assert.Equal(t, "02_addon", configured[0].Key(), "configured migrations modified")
+}
+
+// TestMigrationApplicationName verifies that the transaction of each migration is annotated with the application
+// name and the key of the migration.
+func TestMigrationApplicationName(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	_, err := db.ExecContext(t.Context(), "CREATE TABLE app_log (name TEXT)")
+	require.NoError(t, err, "could not create log table")
+
+	// records the annotation, as SQLite has no application name
+	dialect := dmorph.DialectSQLite()
+	dialect.ApplicationTemplate = "INSERT INTO app_log (name) VALUES (?)"
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dialect),
+		dmorph.WithApplicationName(`deploy\'42`),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{
+			"01_base.sql":  {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+			"02_addon.sql": {Data: []byte("CREATE TABLE t1 (id INTEGER)")},
+		})),
+		"migrations could not be run")
+
+	rows, rowsErr := db.QueryContext(t.Context(), "SELECT name FROM app_log ORDER BY rowid")
+	require.NoError(t, rowsErr, "could not query annotations")
+
+	defer func() { _ = rows.Close() }()
+
+	var names []string
+
+	for rows.Next() {
+		var name string
+
+		require.NoError(t, rows.Scan(&name), "could not scan annotation")
+
+		names = append(names, name)
+	}
+
+	require.NoError(t, rows.Err(), "could not read annotations")
+	assert.Equal(t, []string{`deploy\'42:01_base.sql`, `deploy\'42:02_addon.sql`}, names, "wrong annotations")
+
+	assert.Contains(t, dmorph.DialectPostgres().ApplicationTemplate, "application_name",
+		"PostgreSQL does not set the application name")
+	assert.NotEmpty(t, dmorph.DialectMySQL().ApplicationTemplate, "MySQL does not annotate transactions")
+
+	for _, template := range []string{
+		dmorph.DialectPostgres().ApplicationTemplate,
+		dmorph.DialectMySQL().ApplicationTemplate,
+	} {
+		assert.NotContains(t, template, "%s", "application name not bound as parameter")
+	}
+}
+
+// TestMigrationValidateFirst verifies that no migration is applied if a later migration fails in the
+// validation pass.
+func TestMigrationValidateFirst(t *testing.T) {
+	t.Parallel()
+
+	migrations := fstest.MapFS{
+		"01_base.sql":  {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+		"02_typo.sql":  {Data: []byte("CREAT TABLE t1 (id INTEGER)")},
+		"03_addon.sql": {Data: []byte("CREATE TABLE t2 (id INTEGER)")},
+	}
+
+	tests := []struct {
+		dialect dmorph.Dialect
+		wantErr error
+	}{
+		{dialect: dmorph.DialectSQLite(), wantErr: dmorph.ErrMigrationValidation},
+		{dialect: dmorph.DialectSQLiteNumbered(), wantErr: dmorph.ErrMigrationValidation},
+		{dialect: okDialect{}, wantErr: dmorph.ErrDryRunUnsupported},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestMigrationValidateFirst-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			runErr := dmorph.Run(t.Context(),
+				db,
+				dmorph.WithDialect(test.dialect),
+				dmorph.WithMigrationsFromFS(migrations),
+				dmorph.WithValidateFirst(true))
+
+			require.ErrorIs(t, runErr, test.wantErr, "expected validation to fail")
+
+			var count int
+
+			require.NoError(t, db.QueryRowContext(t.Context(),
+				"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 't0'").Scan(&count))
+			assert.Zero(t, count, "expected no migration to be applied")
+		})
+	}
+}
+
+// TestMigrationNullTimestamps verifies that migrations registered without timestamp, e.g. manually, are ordered
+// deterministically before the others, by their keys.
+func TestMigrationNullTimestamps(t *testing.T) {
+	t.Parallel()
+
+	for k, dialect := range []dmorph.Dialect{dmorph.DialectSQLite(), dmorph.DialectSQLiteNumbered()} {
+		t.Run(fmt.Sprintf("TestMigrationNullTimestamps-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			require.NoError(t, dialect.EnsureMigrationTableExists(t.Context(), db, dmorph.MigrationTableName))
+
+			// inserted in reverse order, with the timestamped migration first
+			_, insertErr := db.ExecContext(t.Context(), `
+				INSERT INTO migrations (id, mgroup, create_ts) VALUES
+				    ('03_c', 'default', CURRENT_TIMESTAMP),
+				    ('02_b', 'default', NULL),
+				    ('01_a', 'default', NULL)`)
+
+			require.NoError(t, insertErr, "expected no error inserting migrations")
+
+			applied, appliedErr := dialect.AppliedMigrations(t.Context(), db,
+				dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+			require.NoError(t, appliedErr, "expected no error getting applied migrations")
+			assert.Equal(t, []string{"01_a", "02_b", "03_c"}, applied, "expected stable ordering")
+
+			history, historyErr := dialect.(dmorph.HistoryPager).HistoryPage(t.Context(), db,
+				dmorph.MigrationTableName, dmorph.MigrationGroupName, 10, 0)
+
+			require.NoError(t, historyErr, "expected no error getting the history")
+			require.Len(t, history, 3, "expected all migrations in the history")
+			assert.True(t, history[0].Applied.IsZero(), "expected zero time for missing timestamps")
+			assert.False(t, history[2].Applied.IsZero(), "expected time for present timestamps")
+
+			runErr := dmorph.Run(t.Context(),
+				db,
+				dmorph.WithDialect(dialect),
+				dmorph.WithMigrations(
+					oneMigration{key: "01_a"}, oneMigration{key: "02_b"},
+					oneMigration{key: "03_c"}, oneMigration{key: "04_d"}))
+
+			assert.NoError(t, runErr, "expected no unsorted error")
+		})
+	}
+}
+
+// TestMigrationCurrentVersion verifies that the key of the last applied migration is reported, according to the
+// key order if multiple migrations share the latest timestamp.
+func TestMigrationCurrentVersion(t *testing.T) {
+	t.Parallel()
+
+	for k, dialect := range []dmorph.Dialect{dmorph.DialectSQLite(), dmorph.DialectSQLiteNumbered()} {
+		t.Run(fmt.Sprintf("TestMigrationCurrentVersion-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			morpher, morpherErr := dmorph.NewMorpher(
+				dmorph.WithDialect(dialect),
+				dmorph.WithMigrationKeyProperties(dmorph.MigrationKeySemVerPrefix()),
+				dmorph.WithMigrations(
+					oneMigration{key: "v1.2.0_b"}, oneMigration{key: "v1.10.0_c"}, oneMigration{key: "v1.1.0_a"}))
+
+			require.NoError(t, morpherErr, "expected no error creating the morpher")
+
+			version, versionErr := morpher.CurrentVersion(t.Context(), db)
+
+			require.NoError(t, versionErr, "expected no error on fresh database")
+			assert.Empty(t, version, "expected no version on fresh database")
+
+			require.NoError(t, morpher.Run(t.Context(), db), "expected no error running migrations")
+
+			version, versionErr = morpher.CurrentVersion(t.Context(), db)
+
+			require.NoError(t, versionErr, "expected no error on migrated database")
+			assert.Equal(t, "v1.10.0_c", version, "expected the latest migration")
+
+			_, updateErr := db.ExecContext(t.Context(), "UPDATE migrations SET create_ts = NULL")
+
+			require.NoError(t, updateErr, "expected no error removing the timestamps")
+
+			version, versionErr = morpher.CurrentVersion(t.Context(), db)
+
+			require.NoError(t, versionErr, "expected no error without timestamps")
+			assert.Equal(t, "v1.10.0_c", version, "expected the latest migration without timestamps")
+		})
+	}
+}
+
+// slowMigration takes the given time, ignoring the context, and then reports an error if the context is done,
+// like drivers that do not report the cause of an interruption.
+type slowMigration struct {
+	key      string
+	duration time.Duration
+}
+
+func (m slowMigration) Key() string {
+	return m.key
+}
+
+func (m slowMigration) Migrate(ctx context.Context, _ /* tx */ *sql.Tx) error {
+	time.Sleep(m.duration)
+
+	if ctx.Err() != nil {
+		return errors.New("interrupted")
+	}
+
+	return nil
+}
+
+// TestMigrationBatchDeadline verifies that a deadline for the whole run is honored between and during
+// migrations, reporting the interrupted migration.
+func TestMigrationBatchDeadline(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		duration time.Duration
+		wantKey  string
+	}{
+		// the deadline expires while the second migration runs
+		{duration: 60 * time.Millisecond, wantKey: "01"},
+		// the deadline expires while the third migration runs
+		{duration: 40 * time.Millisecond, wantKey: "02"},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestMigrationBatchDeadline-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+			defer cancel()
+
+			migrations := make([]dmorph.Migration, 0, 5)
+
+			for i := range 5 {
+				migrations = append(migrations, slowMigration{key: fmt.Sprintf("%02d", i), duration: test.duration})
+			}
+
+			runErr := dmorph.Run(ctx,
+				db,
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrations(migrations...))
+
+			require.ErrorIs(t, runErr, context.DeadlineExceeded, "expected deadline to be exceeded")
+
+			assert.Contains(t, runErr.Error(), "migration "+test.wantKey, "expected the attempted migration")
+		})
+	}
+}
+
+// intrudingMigration registers a foreign migration in the migration table, simulating a concurrent process.
+type intrudingMigration struct {
+	key string
+}
+
+func (m intrudingMigration) Key() string {
+	return m.key
+}
+
+func (m intrudingMigration) Migrate(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO "+dmorph.MigrationTableName+" (id, mgroup) VALUES (?, ?)",
+		"99_foreign", dmorph.MigrationGroupName)
+
+	return err //nolint:wrapcheck
+}
+
+// TestMigrationRunAndVerify verifies that the applied migrations are checked to equal the configured ones after
+// the run.
+func TestMigrationRunAndVerify(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		migrations []dmorph.Migration
+		wantErr    error
+	}{
+		{ // exactly at the configured head
+			migrations: []dmorph.Migration{oneMigration{key: "02_b"}, oneMigration{key: "01_a"}},
+		},
+		{ // foreign migration registered during the run
+			migrations: []dmorph.Migration{oneMigration{key: "01_a"}, intrudingMigration{key: "02_b"}},
+			wantErr:    dmorph.ErrVerificationFailed,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestMigrationRunAndVerify-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			morpher, morpherErr := dmorph.NewMorpher(
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrations(test.migrations...))
+
+			require.NoError(t, morpherErr, "morpher could not be created")
+
+			verifyErr := morpher.RunAndVerify(t.Context(), db)
+
+			if test.wantErr == nil {
+				require.NoError(t, verifyErr, "expected verification to succeed")
+
+				return
+			}
+
+			require.ErrorIs(t, verifyErr, test.wantErr, "expected verification to fail")
+			assert.ErrorContains(t, verifyErr, "99_foreign", "foreign migration not reported")
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// dialectRegistry maps the schemes of data source names to the factories of their dialects.
+type dialectRegistry struct {
+	mutex     sync.RWMutex
+	factories map[string]func() Dialect
+}
+
+// dialects is the registry used by DialectForDSN, initially containing the included dialects.
+//
+//nolint:gochecknoglobals // the registry is shared by all users of the package, like database/sql drivers
+var dialects = dialectRegistry{
+	factories: map[string]func() Dialect{
+		"csvq":       func() Dialect { return DialectCSVQ() },
+		"db2":        func() Dialect { return DialectDB2() },
+		"mssql":      func() Dialect { return DialectMSSQL() },
+		"mysql":      func() Dialect { return DialectMySQL() },
+		"oracle":     func() Dialect { return DialectOracle() },
+		"postgres":   func() Dialect { return DialectPostgres() },
+		"postgresql": func() Dialect { return DialectPostgres() },
+		"sqlite":     func() Dialect { return DialectSQLite() },
+		"sqlite3":    func() Dialect { return DialectSQLite() },
+		"sqlserver":  func() Dialect { return DialectMSSQL() },
+	},
+}
+
+// RegisterDialect registers the factory of a custom dialect for the given scheme of data source names, so that
+// DialectForDSN resolves it. Schemes are case-insensitive. If the scheme is already registered, including the
+// schemes of the included dialects, ErrDialectRegistered is returned, see ReplaceDialect.
+func RegisterDialect(scheme string, factory func() Dialect) error {
+	return dialects.register(scheme, factory, false)
+}
+
+// ReplaceDialect registers the factory of a dialect for the given scheme of data source names like
+// RegisterDialect, explicitly replacing an already registered one, e.g. an included dialect.
+func ReplaceDialect(scheme string, factory func() Dialect) error {
+	return dialects.register(scheme, factory, true)
+}
+
+// DialectForDSN returns the dialect registered for the scheme of the given data source name, e.g. `postgres`
+// for `postgres://user@host/db` or `sqlite` for `sqlite:test.db`. If no dialect is registered for the scheme,
+// ErrDialectUnknown is returned.
+func DialectForDSN(dsn string) (Dialect, error) {
+	scheme, _, found := strings.Cut(dsn, ":")
+
+	if !found {
+		return nil, fmt.Errorf("%w: no scheme in data source name", ErrDialectUnknown)
+	}
+
+	dialects.mutex.RLock()
+	factory, registered := dialects.factories[strings.ToLower(scheme)]
+	dialects.mutex.RUnlock()
+
+	if !registered {
+		return nil, fmt.Errorf("%w: %s", ErrDialectUnknown, scheme)
+	}
+
+	return factory(), nil
+}
+
+// register registers the factory for the given scheme, replacing an existing one only if requested.
+func (r *dialectRegistry) register(scheme string, factory func() Dialect, replace bool) error {
+	if scheme == "" || strings.Contains(scheme, ":") || factory == nil {
+		return fmt.Errorf("%w: %q", ErrDialectSchemeInvalid, scheme)
+	}
+
+	scheme = strings.ToLower(scheme)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, registered := r.factories[scheme]; registered && !replace {
+		return fmt.Errorf("%w: %s", ErrDialectRegistered, scheme)
+	}
+
+	r.factories[scheme] = factory
+
+	return nil
+}
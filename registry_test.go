@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// TestDialectForDSN verifies the resolution of the included dialects by the scheme of data source names.
+func TestDialectForDSN(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		dsn     string
+		want    string
+		wantErr error
+	}{
+		{dsn: "postgres://user@localhost/db", want: "postgres"},
+		{dsn: "PostgreSQL://user@localhost/db", want: "postgres"},
+		{dsn: "sqlserver://user@localhost?database=db", want: "mssql"},
+		{dsn: "mysql://user@tcp(localhost)/db", want: "mysql"},
+		{dsn: "sqlite:test.db", want: "sqlite"},
+		{dsn: "oracle://user@localhost/db", want: "oracle"},
+		{dsn: "nosuch://localhost", wantErr: dmorph.ErrDialectUnknown},
+		{dsn: "test.db", wantErr: dmorph.ErrDialectUnknown},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestDialectForDSN-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			dialect, err := dmorph.DialectForDSN(test.dsn)
+
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr, "expected error")
+
+				return
+			}
+
+			require.NoError(t, err, "expected no error")
+
+			named, isNamed := dialect.(dmorph.NamedDialect)
+
+			require.True(t, isNamed, "expected a named dialect")
+			assert.Equal(t, test.want, named.Name(), "expected dialect")
+		})
+	}
+}
+
+// TestRegisterDialect verifies that custom dialects can be registered without overwriting existing ones.
+func TestRegisterDialect(t *testing.T) {
+	t.Parallel()
+
+	custom := func() dmorph.Dialect {
+		dialect := dmorph.DialectPostgres()
+		dialect.DialectName = "custom"
+
+		return dialect
+	}
+
+	require.NoError(t, dmorph.RegisterDialect("TestRegisterDialect", custom), "expected no error registering")
+
+	dialect, err := dmorph.DialectForDSN("testregisterdialect://localhost/db")
+
+	require.NoError(t, err, "expected registered dialect to be resolved")
+	assert.Equal(t, "custom", dialect.(dmorph.NamedDialect).Name(), "expected custom dialect")
+
+	assert.ErrorIs(t, dmorph.RegisterDialect("testregisterdialect", custom), dmorph.ErrDialectRegistered,
+		"expected registered scheme to be guarded")
+	assert.ErrorIs(t, dmorph.RegisterDialect("postgres", custom), dmorph.ErrDialectRegistered,
+		"expected included scheme to be guarded")
+	assert.ErrorIs(t, dmorph.RegisterDialect("", custom), dmorph.ErrDialectSchemeInvalid,
+		"expected empty scheme to be rejected")
+	assert.ErrorIs(t, dmorph.RegisterDialect("nil", nil), dmorph.ErrDialectSchemeInvalid,
+		"expected missing factory to be rejected")
+
+	require.NoError(t,
+		dmorph.ReplaceDialect("testregisterdialect", func() dmorph.Dialect { return dmorph.DialectMySQL() }),
+		"expected no error replacing")
+
+	dialect, err = dmorph.DialectForDSN("testregisterdialect://localhost/db")
+
+	require.NoError(t, err, "expected replaced dialect to be resolved")
+	assert.Equal(t, "mysql", dialect.(dmorph.NamedDialect).Name(), "expected replaced dialect")
+}
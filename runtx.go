@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// RunTx applies all pending migrations within the given transaction of the caller and registers them, without
+// committing it. This suits test frameworks wrapping each test in a transaction that is rolled back afterward,
+// undoing the migrations as well. The dialect has to implement the TxDialect interface and to be able to roll
+// back DDL statements, as declared by the DryRunner interface, otherwise ErrRunTxUnsupported is returned, even
+// if the migration table is assumed to exist. The same holds for the options requiring separate transactions or
+// connections, i.e. WithTransactionPerStep, WithValidateFirst, WithIntegrityCheck, WithAutoBaselineExisting and
+// migrations restricted to server versions. Transaction options of the migrations are ignored, and the first
+// failing migration ends the run, as the transaction cannot be continued, unless the migrations are enclosed in
+// savepoints, see WithSavepoints.
+func (m *Morpher) RunTx(ctx context.Context, tx *sql.Tx) error {
+	if tx == nil {
+		return ErrNilTx
+	}
+
+	if validErr := m.IsValid(); validErr != nil {
+		return validErr
+	}
+
+	txDialect, isTxDialect := m.Dialect.(TxDialect)
+
+	// the caller rolls back the transaction, which has to undo the DDL statements of the migrations as well
+	if dryRunner, isDryRunner := m.Dialect.(DryRunner); !isDryRunner || !dryRunner.DryRunSupported() {
+		return ErrRunTxUnsupported
+	}
+
+	if !isTxDialect || m.StepTx || m.DryRun || m.Integrity || m.AutoBase {
+		return ErrRunTxUnsupported
+	}
+
+	for _, mi := range m.Migrations {
+		if _, restricted := m.versionRanges[mi.Key()]; restricted {
+			return fmt.Errorf("%w: migration %s restricted to server versions", ErrRunTxUnsupported, mi.Key())
+		}
+	}
+
+	savepointer, isSavepointer := m.Dialect.(Savepointer)
+
+	if m.Savepoints != SavepointPolicyNone && (!isSavepointer || !savepointer.SavepointsSupported()) {
+		return ErrSavepointUnsupported
+	}
+
+	if m.Savepoints == SavepointPolicyNone {
+		savepointer = nil
+	}
+
+	log := m.logger()
+
+	if !m.ReadOnly {
+		for _, statement := range m.Setup {
+			log.DebugContext(ctx, "setup statement", slog.String("statement", statement))
+
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("could not execute setup statement %q: %w", statement, err)
+			}
+		}
+
+		if !m.AssumeTable {
+			if err := txDialect.EnsureMigrationTableExistsTx(ctx, tx, m.TableName); err != nil {
+				return fmt.Errorf("could not ensure migration table: %w", err)
+			}
+		}
+	}
+
+	appliedMigrations, appliedErr := txDialect.AppliedMigrationsTx(ctx, tx, m.TableName, m.GroupName)
+
+	if appliedErr != nil {
+		return fmt.Errorf("could not get applied migrations: %w", appliedErr)
+	}
+
+	m, lastMigration, lastErr := m.lastMigration(ctx, appliedMigrations)
+
+	if lastErr != nil {
+		return lastErr
+	}
+
+	pending := m.pendingMigrations(lastMigration)
+
+	if m.ReadOnly {
+		if len(pending) > 0 {
+			return fmt.Errorf("%w: %s", ErrMigrationsPending, strings.Join(migrationKeys(pending), ", "))
+		}
+
+		return nil
+	}
+
+	if err := m.checkRequirements(appliedMigrations, lastMigration); err != nil {
+		return err
+	}
+
+	if m.Preflight {
+		if err := m.preflight(ctx, pending); err != nil {
+			return err
+		}
+	}
+
+	for _, migration := range pending {
+		log.InfoContext(ctx, "applying migration",
+			slog.String("file", migration.Key()),
+			slog.String("origin", migrationOrigin(migration)),
+		)
+
+		startMigration := time.Now()
+
+		stopped, err := m.migrateTx(ctx, tx, savepointer, migration)
+
+		if err != nil {
+			return err
+		}
+
+		if stopped {
+			return nil
+		}
+
+		log.InfoContext(ctx, "migration applied",
+			slog.String("file", migration.Key()),
+			slog.String("origin", migrationOrigin(migration)),
+			slog.Duration("duration", time.Since(startMigration)),
+		)
+	}
+
+	log.InfoContext(ctx, "migrations done",
+		slog.Int("total", len(m.Migrations)),
+		slog.Int("alreadyApplied", len(m.Migrations)-len(pending)),
+	)
+
+	return nil
+}
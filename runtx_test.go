@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// TestRunTx verifies that the migrations are applied within the transaction of the caller and rolled back with
+// it.
+func TestRunTx(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(os.DirFS("testData")))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	tx, txErr := db.BeginTx(t.Context(), nil)
+
+	require.NoError(t, txErr, "transaction could not be started")
+
+	require.NoError(t, morpher.RunTx(t.Context(), tx), "migrations could not be run")
+	require.NoError(t, morpher.RunTx(t.Context(), tx), "migrations could not be run again")
+
+	var registered int
+
+	require.NoError(t, tx.QueryRowContext(t.Context(),
+		`SELECT COUNT(*) FROM "`+dmorph.MigrationTableName+`"`).Scan(&registered),
+		"could not count registered migrations")
+	assert.Equal(t, morpher.Count(), registered, "wrong number of registered migrations")
+
+	require.NoError(t, tx.Rollback(), "transaction could not be rolled back")
+
+	var tables int
+
+	require.NoError(t, db.QueryRowContext(t.Context(),
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&tables),
+		"could not count tables")
+	assert.Zero(t, tables, "migrations not rolled back")
+}
+
+// TestRunTxUnsupported verifies that dialects and options requiring separate transactions are rejected.
+func TestRunTxUnsupported(t *testing.T) {
+	t.Parallel()
+
+	nonRollback := dmorph.DialectSQLite()
+	nonRollback.RollbackDDL = false
+
+	tests := []struct {
+		options []dmorph.MorphOption
+		wantErr error
+	}{
+		{ // dialect cannot roll back DDL statements
+			options: []dmorph.MorphOption{dmorph.WithDialect(nonRollback)},
+			wantErr: dmorph.ErrRunTxUnsupported,
+		},
+		{ // dialect cannot roll back DDL statements, even if the migration table exists
+			options: []dmorph.MorphOption{dmorph.WithDialect(nonRollback), dmorph.WithAssumeTableExists(true)},
+			wantErr: dmorph.ErrRunTxUnsupported,
+		},
+		{ // transaction per step
+			options: []dmorph.MorphOption{
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithTransactionPerStep(true),
+			},
+			wantErr: dmorph.ErrRunTxUnsupported,
+		},
+		{ // validation in a separate transaction
+			options: []dmorph.MorphOption{
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithValidateFirst(true),
+			},
+			wantErr: dmorph.ErrRunTxUnsupported,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestRunTxUnsupported-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			morpher, morpherErr := dmorph.NewMorpher(
+				append(test.options, dmorph.WithMigrations(oneMigration{key: "01_test"}))...)
+
+			require.NoError(t, morpherErr, "morpher could not be created")
+
+			tx, txErr := openTempSQLite(t).BeginTx(t.Context(), nil)
+
+			require.NoError(t, txErr, "transaction could not be started")
+
+			defer func() { _ = tx.Rollback() }()
+
+			assert.ErrorIs(t, morpher.RunTx(t.Context(), tx), test.wantErr, "expected unsupported error")
+		})
+	}
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+	assert.ErrorIs(t, morpher.RunTx(t.Context(), nil), dmorph.ErrNilTx, "expected nil transaction error")
+}
+
+// TestRunTxSavepoints verifies that a failed migration is rolled back to its savepoint, keeping the migrations
+// applied before within the transaction.
+func TestRunTxSavepoints(t *testing.T) {
+	t.Parallel()
+
+	migrations := fstest.MapFS{
+		"01_base.sql":   {Data: []byte("CREATE TABLE t0 (id INTEGER)")},
+		"02_broken.sql": {Data: []byte("CREATE TABLE t1 (id INTEGER);\nINSERT INTO missing (id) VALUES (1)")},
+		"03_addon.sql":  {Data: []byte("CREATE TABLE t2 (id INTEGER)")},
+	}
+
+	tests := []struct {
+		policy  dmorph.SavepointPolicy
+		wantErr bool
+	}{
+		{ // failed migration returned after rolling it back
+			policy:  dmorph.SavepointPolicyAbort,
+			wantErr: true,
+		},
+		{ // stopped cleanly after rolling back the failed migration
+			policy:  dmorph.SavepointPolicyStop,
+			wantErr: false,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestRunTxSavepoints-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			morpher, morpherErr := dmorph.NewMorpher(
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrationsFromFS(migrations),
+				dmorph.WithSavepoints(test.policy))
+
+			require.NoError(t, morpherErr, "morpher could not be created")
+
+			tx, txErr := db.BeginTx(t.Context(), nil)
+
+			require.NoError(t, txErr, "transaction could not be started")
+
+			runErr := morpher.RunTx(t.Context(), tx)
+
+			if test.wantErr {
+				require.Error(t, runErr, "expected failed migration")
+			} else {
+				require.NoError(t, runErr, "expected clean stop")
+			}
+
+			require.NoError(t, tx.Commit(), "transaction could not be committed")
+
+			assert.Equal(t, []string{"01_base.sql"}, appliedSQLite(t, db), "wrong applied migrations")
+
+			var tables int
+
+			require.NoError(t, db.QueryRowContext(t.Context(),
+				"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('t0', 't1', 't2')").Scan(&tables),
+				"could not count tables")
+			assert.Equal(t, 1, tables, "failed migration not rolled back to its savepoint")
+		})
+	}
+}
+
+// TestRunTxSavepointsUnsupported verifies that savepoints are rejected for dialects not supporting them.
+func TestRunTxSavepointsUnsupported(t *testing.T) {
+	t.Parallel()
+
+	dialect := dmorph.DialectSQLite()
+	dialect.SavepointTemplate = ""
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dialect),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}),
+		dmorph.WithSavepoints(dmorph.SavepointPolicyAbort))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	tx, txErr := openTempSQLite(t).BeginTx(t.Context(), nil)
+
+	require.NoError(t, txErr, "transaction could not be started")
+
+	defer func() { _ = tx.Rollback() }()
+
+	assert.ErrorIs(t, morpher.RunTx(t.Context(), tx), dmorph.ErrSavepointUnsupported, "expected unsupported error")
+}
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// savepointName is the name of the savepoint enclosing each migration of RunTx.
+const savepointName = "dmorph_migration"
+
+// SavepointPolicy defines the handling of failed migrations of RunTx, see WithSavepoints.
+type SavepointPolicy int
+
+const (
+	// SavepointPolicyNone applies the migrations without savepoints. A failed migration ends the run, leaving the
+	// transaction to be rolled back by the caller. This is the default.
+	SavepointPolicyNone SavepointPolicy = iota
+
+	// SavepointPolicyAbort encloses each migration in a savepoint. A failed migration is rolled back to its
+	// savepoint and its error is returned, so that the caller may still commit or roll back the transaction.
+	SavepointPolicyAbort
+
+	// SavepointPolicyStop encloses each migration in a savepoint like SavepointPolicyAbort, but stops cleanly
+	// after rolling back a failed migration, only logging its error. Committing the transaction then keeps the
+	// migrations applied before.
+	SavepointPolicyStop
+)
+
+// WithSavepoints sets the handling of failed migrations of RunTx, a middle ground between a transaction per
+// migration and a single transaction for all of them. Policies other than SavepointPolicyNone require the
+// dialect to implement the Savepointer interface and to support savepoints, otherwise RunTx returns
+// ErrSavepointUnsupported.
+func WithSavepoints(policy SavepointPolicy) MorphOption {
+	return func(m *Morpher) error {
+		m.Savepoints = policy
+
+		return nil
+	}
+}
+
+// migrateTx applies and registers the given migration within the given transaction. If a Savepointer is given,
+// the migration is enclosed in a savepoint and rolled back to it on failure. It returns if the run is to be
+// stopped cleanly, according to the SavepointPolicy.
+func (m *Morpher) migrateTx(
+	ctx context.Context,
+	tx *sql.Tx,
+	savepointer Savepointer,
+	migration Migration) (bool, error) {
+
+	if savepointer == nil {
+		return false, m.migrateInTx(ctx, tx, migration)
+	}
+
+	if err := savepointer.Savepoint(ctx, tx, savepointName); err != nil {
+		return false, fmt.Errorf("could not create savepoint: %w", err)
+	}
+
+	migrateErr := m.migrateInTx(ctx, tx, migration)
+
+	if migrateErr != nil {
+		if rollbackErr := savepointer.RollbackSavepoint(ctx, tx, savepointName); rollbackErr != nil {
+			return false, errors.Join(migrateErr, fmt.Errorf("could not roll back to savepoint: %w", rollbackErr))
+		}
+	}
+
+	if releaseErr := savepointer.ReleaseSavepoint(ctx, tx, savepointName); releaseErr != nil {
+		return false, errors.Join(migrateErr, fmt.Errorf("could not release savepoint: %w", releaseErr))
+	}
+
+	if migrateErr != nil && m.Savepoints == SavepointPolicyStop {
+		m.logger().WarnContext(ctx, "migration failed, stopping",
+			slog.String("file", migration.Key()),
+			slog.String("origin", migrationOrigin(migration)),
+			slog.Any("error", migrateErr),
+		)
+
+		return true, nil
+	}
+
+	return false, migrateErr
+}
+
+// migrateInTx applies and registers the given migration within the given transaction.
+func (m *Morpher) migrateInTx(ctx context.Context, tx *sql.Tx, migration Migration) error {
+	if err := m.annotateTx(ctx, tx, migration.Key()); err != nil {
+		return err
+	}
+
+	if err := migration.Migrate(ctx, tx); err != nil {
+		return fmt.Errorf("could not apply migration %s: %w", migration.Key(), err)
+	}
+
+	return m.registerMigration(ctx, tx, migration.Key())
+}
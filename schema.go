@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// DumpSchema returns the DDL of all tables and indexes of the database in a normalized order, e.g. to compare the
+// schema resulting from the migrations with a golden file. The dialect has to implement the SchemaDumper
+// interface, otherwise ErrSchemaDumpUnsupported is returned.
+func DumpSchema(ctx context.Context, db *sql.DB, dialect Dialect) (string, error) {
+	if db == nil {
+		return "", ErrNilDB
+	}
+
+	dumper, isDumper := dialect.(SchemaDumper)
+
+	if !isDumper {
+		return "", ErrSchemaDumpUnsupported
+	}
+
+	return dumper.DumpSchema(ctx, db) //nolint:wrapcheck // errors are wrapped by the dialects
+}
+
+// DumpSchema returns the DDL statements returned by the SchemaTemplate, each on its own line and terminated by
+// `;`. Whitespace is normalized, so that the dump does not depend on the formatting of the migrations. If the
+// SchemaTemplate is not set, ErrSchemaDumpUnsupported is returned.
+func (b NamedParamsDialect) DumpSchema(ctx context.Context, db *sql.DB) (string, error) {
+	if b.SchemaTemplate == "" {
+		return "", ErrSchemaDumpUnsupported
+	}
+
+	statements, err := queryStrings(ctx, db, b.SchemaTemplate)
+
+	if err != nil {
+		return "", wrapIfError("could not dump schema", err)
+	}
+
+	result := strings.Builder{}
+
+	for _, statement := range statements {
+		result.WriteString(strings.TrimSuffix(strings.Join(strings.Fields(statement), " "), ";"))
+		result.WriteString(";\n")
+	}
+
+	return result.String(), nil
+}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// TestDumpSchema verifies that the schema resulting from the migrations is dumped in a normalized form.
+func TestDumpSchema(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(os.DirFS("testData"))), "migrations could not be run")
+
+	_, err := db.ExecContext(t.Context(), "CREATE INDEX idx_tab1 ON tab1 (id)")
+	require.NoError(t, err, "could not create index")
+
+	schema, schemaErr := dmorph.DumpSchema(t.Context(), db, dmorph.DialectSQLite())
+
+	require.NoError(t, schemaErr, "schema could not be dumped")
+	assert.Equal(t, `CREATE TABLE "migrations" ( id VARCHAR(255) NOT NULL, mgroup VARCHAR(255) NOT NULL, `+
+		`create_ts TIMESTAMP DEFAULT current_timestamp, PRIMARY KEY (id, mgroup) );
+CREATE TABLE tab0 ( id string PRIMARY KEY );
+CREATE TABLE tab1 ( id string PRIMARY KEY );
+CREATE INDEX idx_tab1 ON tab1 (id);
+`, schema, "wrong schema")
+
+	numbered, numberedErr := dmorph.DumpSchema(t.Context(), db, dmorph.DialectSQLiteNumbered())
+
+	require.NoError(t, numberedErr, "schema could not be dumped")
+	assert.Equal(t, schema, numbered, "dialects dump different schemas")
+
+	_, schemaErr = dmorph.DumpSchema(t.Context(), db, dmorph.DialectCSVQ())
+
+	require.ErrorIs(t, schemaErr, dmorph.ErrSchemaDumpUnsupported, "expected unsupported schema dump")
+
+	_, schemaErr = dmorph.DumpSchema(t.Context(), nil, dmorph.DialectSQLite())
+
+	assert.ErrorIs(t, schemaErr, dmorph.ErrNilDB, "expected nil database error")
+}
@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// baselineMigration is a migration replacing all migrations up to and including its key. It is registered
+// using its key, so that databases migrated before the squash and fresh ones share the same last migration.
+type baselineMigration struct {
+	key     string
+	sql     string
+	morpher *Morpher
+}
+
+// Key returns the key of the last migration replaced by the baseline.
+func (b baselineMigration) Key() string {
+	return b.key
+}
+
+// Migrate executes the baseline on the given transaction.
+func (b baselineMigration) Migrate(ctx context.Context, tx *sql.Tx) error {
+	return applyStepsStream(ctx, tx, strings.NewReader(b.sql), b.key, b.morpher.stepsConfig())
+}
+
+// open returns the SQL of the baseline.
+func (b baselineMigration) open() (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(b.sql)), nil
+}
+
+// WithBaseline squashes all migrations up to and including throughKey into a baseline executing baselineSQL.
+// Fresh databases run the baseline instead of the replaced migrations and register it as throughKey. Databases
+// that already have all the replaced migrations applied treat the baseline as satisfied. Databases that have
+// only a part of them applied are rejected with ErrMigrationsUnrelated.
+func WithBaseline(throughKey string, baselineSQL string) MorphOption {
+	return func(m *Morpher) error {
+		m.Baseline = baselineMigration{key: throughKey, sql: baselineSQL, morpher: m}
+
+		return nil
+	}
+}
+
+// Squash replaces all configured migrations up to and including throughKey by a baseline executing
+// baselineSQL, see WithBaseline. The given database serves as reference: it has to be consistent with the
+// configured migrations and migrated at least up to throughKey, otherwise ErrMigrationsPending is returned.
+func (m *Morpher) Squash(ctx context.Context, db *sql.DB, throughKey string, baselineSQL string) error {
+	if db == nil {
+		return ErrNilDB
+	}
+
+	if validErr := m.IsValid(); validErr != nil {
+		return validErr
+	}
+
+	if !slices.ContainsFunc(m.Migrations, func(mi Migration) bool { return mi.Key() == throughKey }) {
+		return fmt.Errorf("%w: %s", ErrBaselineUnknown, throughKey)
+	}
+
+	appliedMigrations, appliedMigrationsErr := m.Dialect.AppliedMigrations(ctx, db, m.TableName, m.GroupName)
+
+	if appliedMigrationsErr != nil {
+		return fmt.Errorf("could not get applied migrations: %w", appliedMigrationsErr)
+	}
+
+	if len(appliedMigrations) == 0 ||
+		m.KeyProp.MigrationKeyOrder(appliedMigrations[len(appliedMigrations)-1], throughKey) < 0 {
+
+		return fmt.Errorf("%w: database not migrated up to %s", ErrMigrationsPending, throughKey)
+	}
+
+	slices.SortFunc(m.Migrations, m.KeyProp.MigrationOrder)
+
+	if err := m.checkAppliedMigrations(ctx, appliedMigrations); err != nil {
+		return err
+	}
+
+	if err := WithBaseline(throughKey, baselineSQL)(m); err != nil {
+		return err
+	}
+
+	m.squashBaseline()
+
+	return nil
+}
+
+// squashBaseline replaces the migrations up to and including the key of the baseline by the baseline itself.
+func (m *Morpher) squashBaseline() {
+	if m.Baseline == nil {
+		return
+	}
+
+	result := make([]Migration, 0, len(m.Migrations)+1)
+	result = append(result, m.Baseline)
+
+	for _, mi := range m.Migrations {
+		if m.KeyProp.MigrationKeyOrder(mi.Key(), m.Baseline.Key()) > 0 {
+			result = append(result, mi)
+		}
+	}
+
+	m.Migrations = result
+}
+
+// collapseBaseline replaces the applied migrations replaced by the baseline by the key of the baseline. This
+// only happens, if the database has all of them applied, i.e. the last of them is the key of the baseline.
+func (m *Morpher) collapseBaseline(appliedMigrations []string) []string {
+	if m.Baseline == nil {
+		return appliedMigrations
+	}
+
+	covered := 0
+
+	for covered < len(appliedMigrations) &&
+		m.KeyProp.MigrationKeyOrder(appliedMigrations[covered], m.Baseline.Key()) <= 0 {
+
+		covered++
+	}
+
+	if covered == 0 || appliedMigrations[covered-1] != m.Baseline.Key() {
+		return appliedMigrations
+	}
+
+	return appliedMigrations[covered-1:]
+}
@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// testBaselineSQL is the baseline used for the squash tests.
+const testBaselineSQL = "CREATE TABLE t1 (id INTEGER)\n;\nCREATE TABLE t2 (id INTEGER)\n"
+
+// appliedSQLite returns the migrations applied to the given SQLite database in the default group.
+func appliedSQLite(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	applied, appliedErr := dmorph.DialectSQLite().AppliedMigrations(t.Context(),
+		db, dmorph.MigrationTableName, dmorph.MigrationGroupName)
+
+	require.NoError(t, appliedErr, "applied migrations could not be read")
+
+	return applied
+}
+
+// TestSquashFreshDatabase verifies that a fresh database runs the baseline instead of the replaced migrations.
+func TestSquashFreshDatabase(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithBaseline("02_second", testBaselineSQL),
+		dmorph.WithMigrations(oneMigration{key: "01_first"}, oneMigration{key: "03_third"}))
+
+	require.NoError(t, runErr, "migrations could not be run")
+	assert.Equal(t, []string{"02_second", "03_third"}, appliedSQLite(t, db), "wrong migrations applied")
+
+	var tables int
+
+	require.NoError(t,
+		db.QueryRowContext(t.Context(),
+			"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('t1', 't2')").Scan(&tables),
+		"tables could not be counted")
+	assert.Equal(t, 2, tables, "baseline not applied")
+}
+
+// TestSquashExistingDatabase verifies that a database having all replaced migrations applied treats the
+// baseline as satisfied.
+func TestSquashExistingDatabase(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "01_first"}, oneMigration{key: "02_second"},
+			oneMigration{key: "03_third"}))
+
+	require.NoError(t, runErr, "preparation migrations could not be run")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithBaseline("02_second", testBaselineSQL),
+		dmorph.WithMigrations(oneMigration{key: "03_third"}, oneMigration{key: "04_fourth"}))
+
+	require.NoError(t, runErr, "squashed migrations could not be run")
+	assert.Equal(t, []string{"01_first", "02_second", "03_third", "04_fourth"}, appliedSQLite(t, db),
+		"wrong migrations applied")
+}
+
+// TestSquashPartialDatabase verifies that a database having only a part of the replaced migrations applied
+// is rejected.
+func TestSquashPartialDatabase(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	runErr := dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "01_first"}))
+
+	require.NoError(t, runErr, "preparation migrations could not be run")
+
+	runErr = dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithBaseline("02_second", testBaselineSQL),
+		dmorph.WithMigrations(oneMigration{key: "03_third"}))
+
+	require.ErrorIs(t, runErr, dmorph.ErrMigrationsUnrelated, "partially migrated database not rejected")
+}
+
+// TestMorpherSquash verifies squashing the migrations using a reference database.
+func TestMorpherSquash(t *testing.T) {
+	t.Parallel()
+
+	reference := openTempSQLite(t)
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "01_first"}, oneMigration{key: "02_second"},
+			oneMigration{key: "03_third"}))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	runErr := dmorph.Run(t.Context(),
+		reference,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrations(oneMigration{key: "01_first"}))
+
+	require.NoError(t, runErr, "preparation migrations could not be run")
+
+	require.ErrorIs(t, morpher.Squash(t.Context(), reference, "02_second", testBaselineSQL),
+		dmorph.ErrMigrationsPending, "unmigrated reference database not rejected")
+	require.ErrorIs(t, morpher.Squash(t.Context(), reference, "05_unknown", testBaselineSQL),
+		dmorph.ErrBaselineUnknown, "unknown baseline key not rejected")
+
+	require.NoError(t, morpher.Run(t.Context(), reference), "reference database could not be migrated")
+	require.NoError(t, morpher.Squash(t.Context(), reference, "02_second", testBaselineSQL),
+		"migrations could not be squashed")
+	assert.Equal(t, 2, morpher.Count(), "migrations not squashed")
+
+	fresh := openTempSQLite(t)
+
+	require.NoError(t, morpher.Run(t.Context(), fresh), "fresh database could not be migrated")
+	require.NoError(t, morpher.Run(t.Context(), reference), "reference database could not be migrated")
+	assert.Equal(t, []string{"02_second", "03_third"}, appliedSQLite(t, fresh), "wrong migrations applied")
+}
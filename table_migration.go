@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"testing/fstest"
+)
+
+// WithMigrationsFromTable generates a FileMigration for each row returned by the given query, e.g. to apply
+// migrations stored centrally in a control-plane database to multiple tenant databases. The query has to return
+// the key and the SQL of each migration, in this order. The rows are read once, when the option is applied, and
+// the migrations are applied like migration files, including their directives, ordered by their keys.
+func WithMigrationsFromTable(ctx context.Context, db *sql.DB, query string) MorphOption {
+	return func(morpher *Morpher) error {
+		migrations, err := migrationsFromTable(ctx, db, query, morpher)
+
+		morpher.Migrations = append(morpher.Migrations, migrations...)
+
+		return err
+	}
+}
+
+// MigrationsFromTable returns a FileMigration for each row returned by the given query, read the same way as
+// by WithMigrationsFromTable, without the need of a Morpher. When applied outside a Morpher, they do not log.
+func MigrationsFromTable(ctx context.Context, db *sql.DB, query string) ([]Migration, error) {
+	return migrationsFromTable(ctx, db, query, nil)
+}
+
+// migrationsFromTable reads the migrations returned by the given query for the given, possibly nil, Morpher.
+// The SQL of the migrations is kept in memory, so that the migrations do not depend on the source database
+// after reading them. The origin of each migration is its key prefixed by `table:`.
+func migrationsFromTable(ctx context.Context, db *sql.DB, query string, morpher *Morpher) ([]Migration, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+
+	if err != nil {
+		return nil, wrapIfError("could not query migrations", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	files := fstest.MapFS{}
+
+	var result []Migration
+
+	for rows.Next() {
+		var key, content string
+
+		if err := rows.Scan(&key, &content); err != nil {
+			return nil, wrapIfError("could not scan migration", err)
+		}
+
+		// the keys are used as the names of the in-memory files
+		if !fs.ValidPath(key) || key == "." {
+			return nil, &KeyFormatError{Key: key}
+		}
+
+		files[key] = &fstest.MapFile{Data: []byte(content), Mode: 0o444}
+		result = append(result, migrationFromFileFS(files, morpher, key, "table:"+key))
+	}
+
+	return result, wrapIfError("could not read migrations", rows.Err())
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// TestMigrationsFromTable verifies that migrations stored in a table of one database are applied to another one.
+func TestMigrationsFromTable(t *testing.T) {
+	t.Parallel()
+
+	source := openTempSQLite(t)
+	target := openTempSQLite(t)
+
+	for _, statement := range []string{
+		"CREATE TABLE registry (mkey TEXT PRIMARY KEY, msql TEXT)",
+		`INSERT INTO registry (mkey, msql) VALUES
+			('02_data', 'INSERT INTO t0 (id) VALUES (1);
+INSERT INTO t0 (id) VALUES (2)'),
+			('01_base', 'CREATE TABLE t0 (id INTEGER)')`,
+	} {
+		_, err := source.ExecContext(t.Context(), statement)
+		require.NoError(t, err, "could not prepare source table")
+	}
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		target,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromTable(t.Context(), source, "SELECT mkey, msql FROM registry")),
+		"migrations from table could not be run")
+
+	assert.Equal(t, []string{"01_base", "02_data"}, appliedSQLite(t, target), "wrong applied migrations")
+
+	var rows int
+
+	require.NoError(t, target.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM t0").Scan(&rows),
+		"could not count rows")
+	assert.Equal(t, 2, rows, "wrong number of rows")
+
+	migrations, migrationsErr := dmorph.MigrationsFromTable(t.Context(), source,
+		"SELECT mkey, msql FROM registry ORDER BY mkey")
+
+	require.NoError(t, migrationsErr, "could not read migrations")
+	require.Len(t, migrations, 2, "wrong number of migrations")
+
+	file, isFile := migrations[0].(dmorph.FileMigration)
+
+	require.True(t, isFile, "expected file migration")
+	assert.Equal(t, "table:01_base", file.Origin, "wrong origin")
+}
+
+// TestMigrationsFromTableErrors verifies that invalid sources are reported.
+func TestMigrationsFromTableErrors(t *testing.T) {
+	t.Parallel()
+
+	source := openTempSQLite(t)
+
+	_, err := source.ExecContext(t.Context(),
+		"CREATE TABLE registry (mkey TEXT, msql TEXT); INSERT INTO registry VALUES ('../escape', 'SELECT 1')")
+	require.NoError(t, err, "could not prepare source table")
+
+	_, err = dmorph.MigrationsFromTable(t.Context(), nil, "SELECT mkey, msql FROM registry")
+	require.ErrorIs(t, err, dmorph.ErrNilDB, "expected nil database error")
+
+	_, err = dmorph.MigrationsFromTable(t.Context(), source, "SELECT mkey, msql FROM missing")
+	require.Error(t, err, "expected query error")
+
+	_, err = dmorph.MigrationsFromTable(t.Context(), source, "SELECT mkey, msql FROM registry")
+	assert.ErrorIs(t, err, dmorph.ErrMigrationKeyFormat, "expected key format error")
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// TrialRun executes each pending migration in a transaction of its own that is always rolled back, without
+// registering it, e.g. to smoke test the migrations against a snapshot of the production database. Unlike
+// Pending, it detects errors reported by the database, like constraint violations, at the cost of executing the
+// migrations. As each migration is rolled back before the next one is executed, migrations depending on the
+// changes of earlier pending ones fail in the trial run, though they would succeed in Run. All pending migrations
+// are tried, the errors of the failing ones are joined, each wrapping ErrMigrationValidation. The dialect has to
+// implement the DryRunner interface and support rolling back DDL statements, otherwise ErrDryRunUnsupported is
+// returned. The migration table is never created.
+func (m *Morpher) TrialRun(ctx context.Context, db *sql.DB) error {
+	if db == nil {
+		return ErrNilDB
+	}
+
+	if validErr := m.IsValid(); validErr != nil {
+		return validErr
+	}
+
+	if runner, isRunner := m.Dialect.(DryRunner); !isRunner || !runner.DryRunSupported() {
+		return ErrDryRunUnsupported
+	}
+
+	appliedMigrations, appliedErr := m.readAppliedMigrations(ctx, db)
+
+	if appliedErr != nil {
+		return appliedErr
+	}
+
+	m, versionErr := m.forServerVersion(ctx, db, appliedMigrations)
+
+	if versionErr != nil {
+		return versionErr
+	}
+
+	m, lastMigration, lastErr := m.lastMigration(ctx, appliedMigrations)
+
+	if lastErr != nil {
+		return lastErr
+	}
+
+	var errs []error
+
+	for _, mig := range m.pendingMigrations(lastMigration) {
+		if err := m.tryMigration(ctx, db, mig); err != nil {
+			m.logger().WarnContext(ctx, "migration failed in trial run",
+				slog.String("file", mig.Key()),
+				slog.Any("error", err),
+			)
+
+			errs = append(errs, fmt.Errorf("%w: %s: %w", ErrMigrationValidation, mig.Key(), err))
+
+			continue
+		}
+
+		m.logger().InfoContext(ctx, "migration succeeded in trial run", slog.String("file", mig.Key()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// tryMigration executes the given migration in a transaction that is always rolled back.
+func (m *Morpher) tryMigration(ctx context.Context, db *sql.DB, mig Migration) error {
+	opts, optsErr := m.txOptions(mig)
+
+	if optsErr != nil {
+		return optsErr
+	}
+
+	tx, err := db.BeginTx(ctx, opts)
+
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	if err := mig.Migrate(ctx, tx); err != nil {
+		return err //nolint:wrapcheck // wrapped by TrialRun
+	}
+
+	return wrapIfError("could not roll back trial run", tx.Rollback())
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// TestTrialRun verifies that the pending migrations are executed in isolation and rolled back, reporting the
+// failing ones, without registering any.
+func TestTrialRun(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	migrations := fstest.MapFS{
+		"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER PRIMARY KEY);\nINSERT INTO t0 (id) VALUES (1)")},
+	}
+
+	require.NoError(t, dmorph.Run(t.Context(),
+		db,
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations)),
+		"base migration could not be run")
+
+	// violates the primary key
+	migrations["02_duplicate.sql"] = &fstest.MapFile{Data: []byte("INSERT INTO t0 (id) VALUES (1)")}
+	migrations["03_table.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE t1 (id INTEGER)")}
+	// depends on the rolled back table of the previous migration
+	migrations["04_dependent.sql"] = &fstest.MapFile{Data: []byte("INSERT INTO t1 (id) VALUES (1)")}
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	trialErr := morpher.TrialRun(t.Context(), db)
+
+	require.ErrorIs(t, trialErr, dmorph.ErrMigrationValidation, "expected validation error")
+	assert.ErrorContains(t, trialErr, "02_duplicate.sql", "constraint violation not reported")
+	assert.ErrorContains(t, trialErr, "04_dependent.sql", "dependent migration not reported")
+	assert.NotContains(t, trialErr.Error(), "03_table.sql", "succeeding migration reported")
+
+	assert.Equal(t, []string{"01_base.sql"}, appliedSQLite(t, db), "trial run registered migrations")
+
+	var tables int
+
+	require.NoError(t, db.QueryRowContext(t.Context(),
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 't1'").Scan(&tables),
+		"could not count tables")
+	assert.Zero(t, tables, "trial run not rolled back")
+}
+
+// TestTrialRunFresh verifies that a trial run on a fresh database does not create the migration table.
+func TestTrialRunFresh(t *testing.T) {
+	t.Parallel()
+
+	db := openTempSQLite(t)
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{"01_base.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")}}))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+	require.NoError(t, morpher.TrialRun(t.Context(), db), "trial run failed")
+
+	var tables int
+
+	require.NoError(t, db.QueryRowContext(t.Context(),
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&tables),
+		"could not count tables")
+	assert.Zero(t, tables, "trial run created tables")
+}
+
+// TestTrialRunUnsupported verifies that dialects not able to roll back DDL statements are rejected.
+func TestTrialRunUnsupported(t *testing.T) {
+	t.Parallel()
+
+	dialect := dmorph.DialectSQLite()
+	dialect.RollbackDDL = false
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dialect),
+		dmorph.WithMigrations(oneMigration{key: "01_test"}))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+	assert.ErrorIs(t, morpher.TrialRun(t.Context(), openTempSQLite(t)), dmorph.ErrDryRunUnsupported,
+		"expected unsupported error")
+	assert.ErrorIs(t, morpher.TrialRun(t.Context(), nil), dmorph.ErrNilDB, "expected nil database error")
+}
@@ -25,6 +25,17 @@ func wrapIfError(text string, err error) error {
 	return fmt.Errorf("%s: %w", text, err)
 }
 
+// keySet returns the given keys as set, for lookups in constant time.
+func keySet(keys []string) map[string]struct{} {
+	result := make(map[string]struct{}, len(keys))
+
+	for _, key := range keys {
+		result[key] = struct{}{}
+	}
+
+	return result
+}
+
 var semVerPrefixRex = regexp.MustCompile(`^v[0-9]+[._][0-9]+[._][0-9]+`)
 
 func semVerPrefixSortPredicate(a, b string) int {
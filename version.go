@@ -0,0 +1,249 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// VersionPolicy defines the handling of migrations not supporting the version of the database server, see
+// VersionedMigration.
+type VersionPolicy int
+
+const (
+	// VersionPolicySkip skips migrations not supporting the server version, without registering them. Migrations
+	// requiring a skipped migration, see DependentMigration, are skipped as well. This is the default.
+	VersionPolicySkip VersionPolicy = iota
+
+	// VersionPolicyError fails the run with ErrVersionMismatch, before any migration is applied.
+	VersionPolicyError
+)
+
+// WithVersionPolicy sets the handling of migrations not supporting the version of the database server. Skipped
+// migrations are excluded from the run like migrations excluded by tags, so later migrations not depending on
+// them are still applied. As skipped migrations are not registered, a database whose server is upgraded into
+// the range of a skipped migration is reported as unrelated to the migrations, instead of applying it out of
+// order. Already applied migrations are never skipped, e.g. if the server was upgraded beyond their range.
+func WithVersionPolicy(policy VersionPolicy) MorphOption {
+	return func(m *Morpher) error {
+		m.VerPolicy = policy
+
+		return nil
+	}
+}
+
+// versionRex matches the version number in the version string of a database server, e.g. `14.5` in
+// `14.5 (Debian 14.5-1)` or `11.5.8.0` in `DB2 v11.5.8.0`.
+var versionRex = regexp.MustCompile(`\d+(?:\.\d+)*`)
+
+// parseVersion returns the numeric components of the first version number in the given string.
+func parseVersion(version string) ([]int, error) {
+	match := versionRex.FindString(version)
+
+	if match == "" {
+		return nil, fmt.Errorf("%w: %q", ErrVersionInvalid, version)
+	}
+
+	parts := strings.Split(match, ".")
+	result := make([]int, 0, len(parts))
+
+	for _, part := range parts {
+		number, err := strconv.Atoi(part)
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrVersionInvalid, version)
+		}
+
+		result = append(result, number)
+	}
+
+	return result, nil
+}
+
+// compareVersionBound compares the server version to the bound, considering only as many components of the
+// server version as the bound has. So the server version 15.3 matches both the minimum and the maximum 15.
+func compareVersionBound(server []int, bound []int) int {
+	for i, b := range bound {
+		s := 0
+
+		if i < len(server) {
+			s = server[i]
+		}
+
+		if s != b {
+			return s - b
+		}
+	}
+
+	return 0
+}
+
+// versionBounds returns the parsed version bounds of the given migration. A nil bound does not restrict the
+// range.
+func versionBounds(mi Migration) ([]int, []int, error) {
+	versioned, isVersioned := mi.(VersionedMigration)
+
+	if !isVersioned {
+		return nil, nil, nil
+	}
+
+	minVersion, maxVersion, err := versioned.VersionRange()
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get version range of migration %s: %w", mi.Key(), err)
+	}
+
+	var bounds [2][]int
+
+	for i, bound := range []string{minVersion, maxVersion} {
+		if bound == "" {
+			continue
+		}
+
+		if bounds[i], err = parseVersion(bound); err != nil {
+			return nil, nil, fmt.Errorf("invalid version range of migration %s: %w", mi.Key(), err)
+		}
+	}
+
+	return bounds[0], bounds[1], nil
+}
+
+// versionRange is the range of server versions supported by a migration. A nil bound does not restrict the range.
+type versionRange struct{ lower, upper []int }
+
+// readVersionRanges reads the version ranges of the migrations once, so that malformed directives are reported
+// when loading the migrations instead of when running them, and runs do not have to read them again.
+func (m *Morpher) readVersionRanges() error {
+	m.versionRanges = nil
+
+	for _, mi := range m.Migrations {
+		lower, upper, err := versionBounds(mi)
+
+		if err != nil {
+			return err
+		}
+
+		if lower == nil && upper == nil {
+			continue
+		}
+
+		if m.versionRanges == nil {
+			m.versionRanges = make(map[string]versionRange)
+		}
+
+		m.versionRanges[mi.Key()] = versionRange{lower: lower, upper: upper}
+	}
+
+	return nil
+}
+
+// forServerVersion returns the Morpher to run on the given database. If migrations restrict the supported server
+// versions, the server version is read and a copy of the Morpher is returned, whose pending migrations are
+// filtered according to the VersionPolicy. The given applied migrations are kept regardless of the server version,
+// so that they are still checked for consistency. Otherwise, the Morpher itself is returned.
+func (m *Morpher) forServerVersion(ctx context.Context, db *sql.DB, appliedMigrations []string) (*Morpher, error) {
+	if len(m.versionRanges) == 0 {
+		return m, nil
+	}
+
+	reporter, isReporter := m.Dialect.(VersionReporter)
+
+	if !isReporter {
+		return nil, ErrVersionUnsupported
+	}
+
+	serverVersion, versionErr := reporter.ServerVersion(ctx, db)
+
+	if versionErr != nil {
+		return nil, fmt.Errorf("could not get server version: %w", versionErr)
+	}
+
+	server, parseErr := parseVersion(serverVersion)
+
+	if parseErr != nil {
+		return nil, fmt.Errorf("could not parse server version: %w", parseErr)
+	}
+
+	applied := keySet(appliedMigrations)
+
+	var skipped []string
+
+	for _, mi := range m.Migrations {
+		if _, isApplied := applied[mi.Key()]; isApplied {
+			continue
+		}
+
+		r, restricted := m.versionRanges[mi.Key()]
+
+		if !restricted ||
+			(r.lower == nil || compareVersionBound(server, r.lower) >= 0) &&
+				(r.upper == nil || compareVersionBound(server, r.upper) <= 0) {
+
+			continue
+		}
+
+		if m.VerPolicy == VersionPolicyError {
+			return nil, fmt.Errorf("%w: %s on %s", ErrVersionMismatch, mi.Key(), serverVersion)
+		}
+
+		skipped = append(skipped, mi.Key())
+	}
+
+	skipped, err := m.skipDependents(skipped)
+
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := *m
+	filtered.Migrations = slices.DeleteFunc(slices.Clone(m.Migrations), func(mi Migration) bool {
+		if _, isApplied := applied[mi.Key()]; isApplied || !slices.Contains(skipped, mi.Key()) {
+			return false
+		}
+
+		m.logger().InfoContext(ctx, "migration skipped for server version",
+			slog.String("file", mi.Key()),
+			slog.String("version", serverVersion))
+
+		return true
+	})
+
+	return &filtered, nil
+}
+
+// skipDependents extends the given keys of skipped migrations by the keys of all migrations requiring them,
+// directly or indirectly.
+func (m *Morpher) skipDependents(skipped []string) ([]string, error) {
+	for changed := len(skipped) > 0; changed; {
+		changed = false
+
+		for _, mi := range m.Migrations {
+			dependent, isDependent := mi.(DependentMigration)
+
+			if !isDependent || slices.Contains(skipped, mi.Key()) {
+				continue
+			}
+
+			requires, err := dependent.Requires()
+
+			if err != nil {
+				return nil, fmt.Errorf("could not get requirements of migration %s: %w", mi.Key(), err)
+			}
+
+			if slices.ContainsFunc(requires, func(key string) bool { return slices.Contains(skipped, key) }) {
+				skipped = append(skipped, mi.Key())
+				changed = true
+			}
+		}
+	}
+
+	return skipped, nil
+}
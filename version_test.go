@@ -0,0 +1,210 @@
+// SPDX-FileCopyrightText: 2026 The DMorph contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package dmorph_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AlphaOne1/dmorph"
+)
+
+// testVersionMigrations are the migrations used for the server version tests. SQLite reports versions 3.x, so
+// the second migration is not supported and the third one depends on it.
+var testVersionMigrations = fstest.MapFS{
+	"01_base.sql":      {Data: []byte("-- dmorph:max-version 3\nCREATE TABLE t0 (id INTEGER)")},
+	"02_future.sql":    {Data: []byte("-- dmorph:min-version 99.1\nCREATE TABLE t1 (id INTEGER)")},
+	"03_dependent.sql": {Data: []byte("-- dmorph:requires 02_future.sql\nINSERT INTO t1 (id) VALUES (1)")},
+	"04_data.sql":      {Data: []byte("-- dmorph:min-version 3.0\nINSERT INTO t0 (id) VALUES (1)")},
+}
+
+// TestWithVersionPolicy verifies that migrations not supporting the server version are skipped or rejected.
+func TestWithVersionPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		options []dmorph.MorphOption
+		want    []string
+		wantErr error
+	}{
+		{ // skipped by default, including dependent migrations
+			want: []string{"01_base.sql", "04_data.sql"},
+		},
+		{ // skipped explicitly
+			options: []dmorph.MorphOption{dmorph.WithVersionPolicy(dmorph.VersionPolicySkip)},
+			want:    []string{"01_base.sql", "04_data.sql"},
+		},
+		{ // rejected
+			options: []dmorph.MorphOption{dmorph.WithVersionPolicy(dmorph.VersionPolicyError)},
+			wantErr: dmorph.ErrVersionMismatch,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestWithVersionPolicy-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			morpher, morpherErr := dmorph.NewMorpher(append([]dmorph.MorphOption{
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrationsFromFS(testVersionMigrations),
+			}, test.options...)...)
+
+			require.NoError(t, morpherErr, "morpher could not be created")
+
+			runErr := morpher.Run(t.Context(), db)
+
+			if test.wantErr != nil {
+				require.ErrorIs(t, runErr, test.wantErr, "expected version error")
+				assert.Empty(t, appliedSQLite(t, db), "no migration expected to be applied")
+
+				return
+			}
+
+			require.NoError(t, runErr, "migrations could not be run")
+			assert.Equal(t, test.want, appliedSQLite(t, db), "wrong migrations applied")
+
+			require.NoError(t, morpher.RunAndVerify(t.Context(), db), "skipped migrations not excluded")
+
+			pending, pendingErr := morpher.Pending(t.Context(), db)
+
+			require.NoError(t, pendingErr, "pending migrations could not be read")
+			assert.Empty(t, pending, "skipped migrations reported pending")
+		})
+	}
+}
+
+// TestWithVersionPolicyInvalid verifies that invalid version ranges and dialects without server version are
+// rejected.
+func TestWithVersionPolicyInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(fstest.MapFS{
+			"01_base.sql": {Data: []byte("-- dmorph:min-version latest\nCREATE TABLE t0 (id INTEGER)")},
+		}))
+
+	require.ErrorIs(t, morpherErr, dmorph.ErrVersionInvalid, "expected invalid version error")
+
+	dialect := dmorph.DialectSQLite()
+	dialect.VersionTemplate = ""
+
+	runErr := dmorph.Run(t.Context(),
+		openTempSQLite(t),
+		dmorph.WithDialect(dialect),
+		dmorph.WithMigrationsFromFS(testVersionMigrations))
+
+	assert.ErrorIs(t, runErr, dmorph.ErrVersionUnsupported, "expected unsupported server version")
+}
+
+// TestWithVersionPolicyReadOnce verifies that the version ranges are read when creating the Morpher, so that
+// later runs do not read the migrations again.
+func TestWithVersionPolicyReadOnce(t *testing.T) {
+	t.Parallel()
+
+	// all migrations support the server version, so that no requirements of skipped ones are read
+	migrations := fstest.MapFS{
+		"01_base.sql": {Data: []byte("-- dmorph:max-version 3\nCREATE TABLE t0 (id INTEGER)")},
+		"02_data.sql": {Data: []byte("-- dmorph:min-version 3.0\nINSERT INTO t0 (id) VALUES (1)")},
+	}
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(migrations))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	db := openTempSQLite(t)
+
+	require.NoError(t, morpher.Run(t.Context(), db), "migrations could not be run")
+
+	// the migrations are not needed anymore, as all of them are applied
+	clear(migrations)
+
+	require.NoError(t, morpher.Run(t.Context(), db), "version ranges read again")
+	assert.Equal(t, []string{"01_base.sql", "02_data.sql"}, appliedSQLite(t, db), "wrong migrations applied")
+}
+
+// TestWithVersionPolicyGenerateSQL verifies that the generated SQL skips migrations not supporting the server
+// version, like Run.
+func TestWithVersionPolicyGenerateSQL(t *testing.T) {
+	t.Parallel()
+
+	morpher, morpherErr := dmorph.NewMorpher(
+		dmorph.WithDialect(dmorph.DialectSQLite()),
+		dmorph.WithMigrationsFromFS(testVersionMigrations))
+
+	require.NoError(t, morpherErr, "morpher could not be created")
+
+	var script strings.Builder
+
+	require.NoError(t, morpher.GenerateSQL(t.Context(), openTempSQLite(t), &script), "could not generate SQL")
+	assert.NotContains(t, script.String(), "02_future.sql", "unsupported migration generated")
+	assert.Contains(t, script.String(), "04_data.sql", "supported migration not generated")
+}
+
+// TestWithVersionPolicyApplied verifies that applied migrations are kept, even if they do not support the server
+// version anymore, and that runs skipping all migrations succeed.
+func TestWithVersionPolicyApplied(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		applied    fstest.MapFS
+		migrations fstest.MapFS
+		options    []dmorph.MorphOption
+		want       []string
+	}{
+		{ // one migration applied, all of them skipped
+			applied: fstest.MapFS{"01.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")}},
+			migrations: fstest.MapFS{
+				"01.sql": {Data: []byte("-- dmorph:min-version 99\nCREATE TABLE t0 (id INTEGER)")},
+				"02.sql": {Data: []byte("-- dmorph:min-version 99\nCREATE TABLE t1 (id INTEGER)")},
+			},
+			want: []string{"01.sql"},
+		},
+		{ // fast path, none applied, all of them skipped
+			migrations: fstest.MapFS{
+				"01.sql": {Data: []byte("-- dmorph:min-version 99\nCREATE TABLE t0 (id INTEGER)")},
+			},
+			options: []dmorph.MorphOption{dmorph.WithFastPath(true)},
+		},
+		{ // applied migration beyond its maximum version
+			applied: fstest.MapFS{"01.sql": {Data: []byte("CREATE TABLE t0 (id INTEGER)")}},
+			migrations: fstest.MapFS{
+				"01.sql": {Data: []byte("-- dmorph:max-version 2\nCREATE TABLE t0 (id INTEGER)")},
+				"02.sql": {Data: []byte("CREATE TABLE t1 (id INTEGER)")},
+			},
+			want: []string{"01.sql", "02.sql"},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestWithVersionPolicyApplied-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			db := openTempSQLite(t)
+
+			if test.applied != nil {
+				require.NoError(t, dmorph.Run(t.Context(),
+					db,
+					dmorph.WithDialect(dmorph.DialectSQLite()),
+					dmorph.WithMigrationsFromFS(test.applied)), "applied migrations could not be run")
+			}
+
+			require.NoError(t, dmorph.Run(t.Context(), db, append([]dmorph.MorphOption{
+				dmorph.WithDialect(dmorph.DialectSQLite()),
+				dmorph.WithMigrationsFromFS(test.migrations),
+			}, test.options...)...), "migrations could not be run")
+
+			assert.Equal(t, test.want, appliedSQLite(t, db), "wrong migrations applied")
+		})
+	}
+}